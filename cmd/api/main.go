@@ -4,8 +4,11 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
+	"go-inventory-ws/internal/events"
 	"go-inventory-ws/internal/handler"
 	"go-inventory-ws/internal/middleware"
 	"go-inventory-ws/internal/model"
@@ -32,17 +35,41 @@ func main() {
 	// 2. Setup Database
 	db := database.ConnectDB()
 	// Auto Migrate (Hati-hati di production, sebaiknya pakai tools migrasi terpisah)
-	if err := db.AutoMigrate(&model.Product{}, &model.Transaction{}, &model.User{}, &model.Privilege{}, &model.Role{}, &model.Shift{}); err != nil {
+	if err := db.AutoMigrate(&model.Product{}, &model.Transaction{}, &model.TransactionDocument{}, &model.User{}, &model.Privilege{}, &model.Role{}, &model.Shift{}, &model.HubEvent{}, &model.OutboxEvent{}, &model.Location{}, &model.StockBalance{}, &model.StockTransfer{}, &model.StockTransferLine{}, &model.Lot{}, &model.TransactionLot{}, &model.TransferLineLot{}, &model.SerialNumber{}, &model.TransactionSerial{}, &model.TransferLineSerial{}, &model.UnitOfMeasure{}, &model.ProductUnit{}, &model.ReasonCode{}, &model.Stocktake{}, &model.StocktakeLine{}, &model.Reservation{}); err != nil {
 		log.Printf("❌ AutoMigrate failed: %v", err)
 	} else {
 		log.Println("✅ AutoMigrate completed successfully (including shifts table)")
 	}
 
 	seedPrivilegesRolesAndAdmin(db)
+	seedDefaultLocation(db)
+	if err := repository.NewUnitRepo(db).SeedDefaults(model.DefaultUnits); err != nil {
+		log.Printf("Warning: Failed to seed units of measure: %v", err)
+	}
+	if err := repository.NewReasonRepo(db).SeedDefaults(model.DefaultReasonCodes); err != nil {
+		log.Printf("Warning: Failed to seed reason codes: %v", err)
+	}
+	if err := repository.NewTransactionRepo(db).BackfillDirections(); err != nil {
+		log.Printf("Warning: Failed to backfill transaction directions: %v", err)
+	}
 
-	// 4. Setup WebSocket Hub
-	wsHub := ws.NewHub()
+	// 4. Setup WebSocket Hub (events are persisted for replay on reconnect)
+	hubEventRepo := repository.NewHubEventRepo(db)
+	wsHub := ws.NewHub(hubEventRepo)
 	go wsHub.Run()
+	go wsHub.RunRetention(hubEventRetention())
+
+	// Multi-instance fan-out: WS_BACKPLANE=postgres (LISTEN/NOTIFY).
+	// WS_BACKPLANE_DSN overrides the DSN for the LISTEN connection, which needs a
+	// session (not transaction-mode pooled) connection.
+	if os.Getenv("WS_BACKPLANE") == "postgres" {
+		backplaneDSN := os.Getenv("WS_BACKPLANE_DSN")
+		if backplaneDSN == "" {
+			backplaneDSN = database.DSN()
+		}
+		wsHub.UseBackplane(ws.NewPostgresBackplane(backplaneDSN, db, hubEventRepo))
+		log.Println("✅ WebSocket hub using Postgres LISTEN/NOTIFY backplane")
+	}
 
 	// 5. Dependency Injection (Wiring Layers)
 	productRepo := repository.NewProductRepo(db)
@@ -51,10 +78,35 @@ func main() {
 	privilegeRepo := repository.NewPrivilegeRepo(db)
 	roleRepo := repository.NewRoleRepo(db)
 	shiftRepo := repository.NewShiftRepo(db)
-
-	invService := service.NewInventoryService(productRepo, txRepo, db, wsHub)
+	outboxRepo := repository.NewOutboxRepo(db)
+	locationRepo := repository.NewLocationRepo(db)
+	stockRepo := repository.NewStockRepo(db)
+	transferRepo := repository.NewTransferRepo(db)
+	lotRepo := repository.NewLotRepo(db)
+	serialRepo := repository.NewSerialRepo(db)
+	unitRepo := repository.NewUnitRepo(db)
+	reasonRepo := repository.NewReasonRepo(db)
+	stocktakeRepo := repository.NewStocktakeRepo(db)
+	reservationRepo := repository.NewReservationRepo(db)
+
+	// Relays events committed to the outbox table to the hub
+	outbox := service.NewOutbox(outboxRepo, db, wsHub)
+	go outbox.Run()
+
+	locationService := service.NewLocationService(locationRepo)
+	unitService := service.NewUnitService(unitRepo, productRepo)
+	reasonService := service.NewReasonService(reasonRepo)
+	invService := service.NewInventoryService(productRepo, txRepo, stockRepo, lotRepo, serialRepo, reservationRepo, locationService, unitService, reasonService, db, outbox)
+	transferService := service.NewTransferService(transferRepo, productRepo, stockRepo, lotRepo, serialRepo, reservationRepo, locationService, reasonService, db, outbox)
+	stocktakeService := service.NewStocktakeService(stocktakeRepo, productRepo, stockRepo, locationService, unitService, reasonService, db, outbox)
+	reservationService := service.NewReservationService(reservationRepo, productRepo, stockRepo, locationService, unitService, db, outbox)
+	go reservationService.Run()
+	lotService := service.NewLotService(lotRepo, productRepo)
+	serialService := service.NewSerialService(serialRepo, productRepo)
 	dashService := service.NewDashboardService(txRepo)
-	authService := service.NewAuthService(userRepo, wsHub)
+	presenceService := service.NewPresenceService(userRepo, wsHub)
+	go presenceService.Run()
+	authService := service.NewAuthService(userRepo, wsHub, presenceService)
 	userService := service.NewUserService(userRepo, privilegeRepo, roleRepo)
 	shiftService := service.NewShiftService(shiftRepo, userRepo, wsHub)
 
@@ -64,6 +116,16 @@ func main() {
 	userHandler := handler.NewUserHandler(userService)
 	roleHandler := handler.NewRoleHandler(roleRepo)
 	shiftHandler := handler.NewShiftHandler(shiftService)
+	presenceHandler := handler.NewPresenceHandler(presenceService)
+	locationHandler := handler.NewLocationHandler(locationService)
+	transferHandler := handler.NewTransferHandler(transferService)
+	stocktakeHandler := handler.NewStocktakeHandler(stocktakeService)
+	reservationHandler := handler.NewReservationHandler(reservationService)
+	lotHandler := handler.NewLotHandler(lotService)
+	serialHandler := handler.NewSerialHandler(serialService)
+	unitHandler := handler.NewUnitHandler(unitService)
+	reasonHandler := handler.NewReasonHandler(reasonService)
+	eventHandler := handler.NewEventHandler(wsHub)
 
 	// 6. Setup Fiber
 	app := fiber.New(fiber.Config{
@@ -86,6 +148,11 @@ func main() {
 	auth.Post("/validate-token", authHandler.ValidateToken)
 	auth.Post("/heartbeat", middleware.RequireAuth(userRepo), authHandler.Heartbeat) // Heartbeat uses Auth but available to all authenticated
 
+	// Server-Sent Events fallback of /ws (same events, topics and resume).
+	// Registered before the protected group: its RequireAuth only reads the header,
+	// while EventSource can only pass the token as ?token=
+	api.Get("/events", middleware.RequireStreamAuth(userRepo), eventHandler.Stream)
+
 	// ============ PROTECTED ROUTES ============
 	// All routes below require authentication
 	protected := api.Group("", middleware.RequireAuth(userRepo))
@@ -96,16 +163,78 @@ func main() {
 
 	// Product Routes (with privilege checks)
 	protected.Get("/products", invHandler.GetProducts)
+	protected.Get("/products/low-stock", invHandler.GetLowStockReport)
 	protected.Post("/products", middleware.RequirePrivilege("product:create"), invHandler.CreateProduct)
 	protected.Put("/products/:id", middleware.RequirePrivilege("product:update"), invHandler.UpdateProduct)
+	protected.Delete("/products/:id", middleware.RequirePrivilege("product:delete"), invHandler.DeleteProduct)
+	protected.Post("/products/:id/archive", middleware.RequirePrivilege("product:delete"), invHandler.ArchiveProduct)
+	protected.Post("/products/:id/restore", middleware.RequirePrivilege("product:delete"), invHandler.RestoreProduct)
+	protected.Get("/products/:id/transactions", middleware.RequirePrivilege("transaction:view"), invHandler.GetProductLedger)
+	protected.Get("/products/:id/stock", invHandler.GetProductStock)
+	protected.Get("/products/:id/lots", lotHandler.GetProductLots)
+	protected.Get("/products/:id/serials", serialHandler.GetProductSerials)
+	protected.Get("/products/:id/units", unitHandler.GetProductUnits)
+	protected.Put("/products/:id/units/:code", middleware.RequirePrivilege("product:update"), unitHandler.SetProductUnit)
+	protected.Delete("/products/:id/units/:code", middleware.RequirePrivilege("product:update"), unitHandler.RemoveProductUnit)
+	protected.Put("/products/:id/locations/:location_id", middleware.RequirePrivilege("product:update"), invHandler.SetLocationReorderPoint)
+
+	// Location Routes (stores, warehouses)
+	protected.Get("/locations", locationHandler.GetLocations)
+	protected.Post("/locations", middleware.RequirePrivilege("location:manage"), locationHandler.CreateLocation)
+	protected.Put("/locations/:id", middleware.RequirePrivilege("location:manage"), locationHandler.UpdateLocation)
+
+	// Unit of Measure Routes (catalog)
+	protected.Get("/units", unitHandler.GetUnits)
+	protected.Post("/units", middleware.RequirePrivilege("unit:manage"), unitHandler.CreateUnit)
+	protected.Put("/units/:code", middleware.RequirePrivilege("unit:manage"), unitHandler.UpdateUnit)
+
+	// Reason Code Routes (catalog of adjustment and return reasons)
+	protected.Get("/reason-codes", reasonHandler.GetReasonCodes)
+	protected.Post("/reason-codes", middleware.RequirePrivilege("reason:manage"), reasonHandler.CreateReasonCode)
+	protected.Put("/reason-codes/:code", middleware.RequirePrivilege("reason:manage"), reasonHandler.UpdateReasonCode)
+
+	// Lot Routes (lot-tracked products)
+	protected.Get("/lots/expiring", lotHandler.GetExpiringLots)
+
+	// Serial Number Routes (serialized products); the history includes transactions
+	protected.Get("/serials/:serial", middleware.RequirePrivilege("transaction:view"), serialHandler.LookupSerial)
+
+	// Stock Transfer Routes (draft -> in transit -> (partially) received)
+	protected.Get("/transfers", middleware.RequirePrivilege("transfer:view"), transferHandler.GetTransfers)
+	protected.Get("/transfers/:id", middleware.RequirePrivilege("transfer:view"), transferHandler.GetTransfer)
+	protected.Post("/transfers", middleware.RequirePrivilege("transfer:create"), transferHandler.CreateTransfer)
+	protected.Post("/transfers/:id/dispatch", middleware.RequirePrivilege("transfer:create"), transferHandler.DispatchTransfer)
+	protected.Post("/transfers/:id/cancel", middleware.RequirePrivilege("transfer:create"), transferHandler.CancelTransfer)
+	protected.Post("/transfers/:id/receive", middleware.RequirePrivilege("transfer:receive"), transferHandler.ReceiveTransfer)
+
+	// Stocktake Routes (open -> counted concurrently -> approved / cancelled)
+	protected.Get("/stocktakes", middleware.RequirePrivilege("stocktake:view"), stocktakeHandler.GetStocktakes)
+	protected.Get("/stocktakes/:id", middleware.RequirePrivilege("stocktake:view"), stocktakeHandler.GetStocktake)
+	protected.Post("/stocktakes", middleware.RequirePrivilege("stocktake:count"), stocktakeHandler.CreateStocktake)
+	protected.Post("/stocktakes/:id/counts", middleware.RequirePrivilege("stocktake:count"), stocktakeHandler.RecordCounts)
+	protected.Post("/stocktakes/:id/approve", middleware.RequirePrivilege("stocktake:approve"), stocktakeHandler.ApproveStocktake)
+	protected.Post("/stocktakes/:id/cancel", middleware.RequirePrivilege("stocktake:approve"), stocktakeHandler.CancelStocktake)
+
+	// Reservation Routes (stock held for orders until picked, released or expired)
+	protected.Get("/reservations", middleware.RequirePrivilege("reservation:view"), reservationHandler.GetReservations)
+	protected.Get("/reservations/:id", middleware.RequirePrivilege("reservation:view"), reservationHandler.GetReservation)
+	protected.Post("/reservations", middleware.RequirePrivilege("reservation:manage"), reservationHandler.CreateReservation)
+	protected.Post("/reservations/:id/release", middleware.RequirePrivilege("reservation:manage"), reservationHandler.ReleaseReservation)
 
 	// Transaction Routes (with privilege checks)
 	protected.Get("/transactions", middleware.RequirePrivilege("transaction:view"), invHandler.GetTransactions)
 	protected.Get("/transactions/:id", middleware.RequirePrivilege("transaction:view"), invHandler.GetTransaction)
 	protected.Post("/transactions", middleware.RequirePrivilege("transaction:create"), invHandler.CreateTransaction)
+	protected.Post("/transactions/:id/void", middleware.RequirePrivilege("transaction:void"), invHandler.VoidTransaction)
+
+	// Transaction Documents (several products recorded atomically, e.g. one checkout)
+	protected.Get("/transaction-documents", middleware.RequirePrivilege("transaction:view"), invHandler.GetDocuments)
+	protected.Get("/transaction-documents/:id", middleware.RequirePrivilege("transaction:view"), invHandler.GetDocument)
+	protected.Post("/transaction-documents", middleware.RequirePrivilege("transaction:create"), invHandler.CreateDocument)
 
 	// Financial Routes
 	protected.Get("/finance/stats", middleware.RequirePrivilege("transaction:view"), invHandler.GetFinancialStats)
+	protected.Get("/finance/shrinkage", middleware.RequirePrivilege("transaction:view"), invHandler.GetShrinkageReport)
 
 	// User Management Routes (with privilege checks)
 	protected.Get("/users", userHandler.GetUsers)
@@ -115,6 +244,9 @@ func main() {
 	protected.Delete("/users/:id", middleware.RequirePrivilege("user:delete"), userHandler.DeleteUser)
 	protected.Put("/users/:id/privileges", middleware.RequirePrivilege("user:update_privilege"), userHandler.UpdateUserPrivileges)
 
+	// Presence Routes (online / away / offline of every active user)
+	protected.Get("/presence", presenceHandler.GetPresence)
+
 	// Role Routes
 	protected.Get("/roles", roleHandler.GetRoles)
 
@@ -127,6 +259,11 @@ func main() {
 		return c.JSON(privileges)
 	})
 
+	// Event schema (JSON Schema of every WebSocket event envelope, for the frontend)
+	protected.Get("/events/schema", func(c *fiber.Ctx) error {
+		return c.JSON(events.Schema())
+	})
+
 	// Shift Routes (MASTER_ADMIN only for CUD, authenticated users can view their own)
 	// NOTE: Order matters! More specific routes must come before parameterized routes
 	protected.Get("/shifts", shiftHandler.GetShifts)
@@ -137,31 +274,37 @@ func main() {
 	protected.Delete("/shifts/:id", middleware.RequirePrivilege("shift:delete"), shiftHandler.DeleteShift)
 
 	// WebSocket Route
-	// Connect with ?user_id=<uuid> for targeted shift notifications
+	// Requires the same JWT as the REST API: "Authorization: Bearer <token>",
+	// "Sec-WebSocket-Protocol: bearer, <token>" or ?token=<token>
 	app.Use("/ws", func(c *fiber.Ctx) error {
 		if websocket.IsWebSocketUpgrade(c) {
 			return c.Next()
 		}
 		return c.SendStatus(fiber.StatusUpgradeRequired)
-	})
+	}, middleware.RequireStreamAuth(userRepo))
 	app.Get("/ws", websocket.New(func(c *websocket.Conn) {
-		// Check if user_id is provided for targeted messaging
-		userID := c.Query("user_id")
-		if userID != "" {
-			// Register with user ID for targeted shift notifications
-			wsHub.RegisterWithUser(c, userID)
-		} else {
-			// Anonymous connection (general broadcasts only)
-			wsHub.Register <- c
+		// Identity comes from the validated token, never from the client
+		userID, _ := c.Locals("user_id").(string)
+		tokenVersion, _ := c.Locals("token_version").(string)
+		privileges, _ := c.Locals("user_privileges").([]string)
+
+		client := ws.NewClient(wsHub, c, userID, tokenVersion, privileges)
+		// Optional initial subscriptions: ?topics=products,product:<id>,finance,presence,shifts
+		// Without them the client receives every event until it sends a subscribe frame
+		if topics := ws.ParseTopics(c.Query("topics")); len(topics) > 0 {
+			client.Subscribe(topics...)
 		}
-		defer func() { wsHub.Unregister <- c }()
 
-		for {
-			// Keep alive loop
-			if _, _, err := c.ReadMessage(); err != nil {
-				break
-			}
+		// Resume after a reconnect: ?last_seq=<seq> (or a {"action":"resume"} frame later)
+		if lastSeq, err := strconv.ParseInt(c.Query("last_seq"), 10, 64); err == nil {
+			client.ResumeFrom(lastSeq)
 		}
+
+		// Blocks until the connection closes (ping/pong keepalive handled by the client pumps)
+		client.Serve()
+	}, websocket.Config{
+		// Echo the "bearer" subprotocol so browsers accept the handshake
+		Subprotocols: []string{"bearer"},
 	}))
 
 	// 8. Graceful Shutdown
@@ -188,6 +331,15 @@ func main() {
 	log.Println("Server exited")
 }
 
+// hubEventRetention reads WS_EVENT_RETENTION_HOURS (default 24h)
+func hubEventRetention() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("WS_EVENT_RETENTION_HOURS"))
+	if err != nil || hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
 // seedPrivilegesRolesAndAdmin creates default privileges, roles, and admin user if they don't exist
 func seedPrivilegesRolesAndAdmin(db *gorm.DB) {
 	privilegeRepo := repository.NewPrivilegeRepo(db)
@@ -195,7 +347,8 @@ func seedPrivilegesRolesAndAdmin(db *gorm.DB) {
 	roleRepo := repository.NewRoleRepo(db)
 
 	// 1. Seed privileges first
-	if err := privilegeRepo.SeedDefaults(); err != nil {
+	newPrivileges, err := privilegeRepo.SeedDefaults()
+	if err != nil {
 		log.Printf("Warning: Failed to seed privileges: %v", err)
 	}
 
@@ -212,20 +365,19 @@ func seedPrivilegesRolesAndAdmin(db *gorm.DB) {
 	if err == nil && len(masterRole.Privileges) == 0 {
 		db.Model(&masterRole).Association("Privileges").Replace(allPrivileges)
 		log.Println("✅ MASTER_ADMIN role assigned all privileges")
+	} else if err == nil && len(newPrivileges) > 0 {
+		// Privileges added by a later release are granted once, when first seeded,
+		// so privileges removed by hand stay removed
+		grantNewPrivileges(db, masterRole, newPrivileges)
 	}
 
 	// ADMIN gets limited privileges (exclude user management)
 	adminRole, err := roleRepo.FindByCode(model.RoleAdmin)
 	if err == nil && len(adminRole.Privileges) == 0 {
-		adminPrivileges := []model.Privilege{}
-		for _, p := range allPrivileges {
-			// Exclude user creation, update, delete, and privilege update
-			if p.Code != "user:create" && p.Code != "user:update" && p.Code != "user:delete" && p.Code != "user:update_privilege" {
-				adminPrivileges = append(adminPrivileges, p)
-			}
-		}
-		db.Model(&adminRole).Association("Privileges").Replace(adminPrivileges)
+		db.Model(&adminRole).Association("Privileges").Replace(adminPrivilegesOf(allPrivileges))
 		log.Println("✅ ADMIN role assigned limited privileges")
+	} else if err == nil && len(newPrivileges) > 0 {
+		grantNewPrivileges(db, adminRole, adminPrivilegesOf(newPrivileges))
 	}
 
 	// 4. Create default admin user with MASTER_ADMIN role
@@ -269,3 +421,47 @@ func seedPrivilegesRolesAndAdmin(db *gorm.DB) {
 		}
 	}
 }
+
+// seedDefaultLocation creates the default location and assigns it the stock and
+// transactions recorded before locations existed
+// grantNewPrivileges adds privileges to a role and to the users holding it, who
+// carry their own privilege list
+func grantNewPrivileges(db *gorm.DB, role *model.Role, privileges []model.Privilege) {
+	if len(privileges) == 0 {
+		return
+	}
+	db.Model(role).Association("Privileges").Append(privileges)
+
+	var users []model.User
+	db.Where("role_id = ?", role.ID).Find(&users)
+	for i := range users {
+		db.Model(&users[i]).Association("Privileges").Append(privileges)
+	}
+	log.Printf("✅ %s granted %d new privileges", role.Code, len(privileges))
+}
+
+// adminPrivilegesOf filters out the user management privileges ADMIN doesn't get
+func adminPrivilegesOf(privileges []model.Privilege) []model.Privilege {
+	adminPrivileges := []model.Privilege{}
+	for _, p := range privileges {
+		// Exclude user creation, update, delete, and privilege update
+		if p.Code != "user:create" && p.Code != "user:update" && p.Code != "user:delete" && p.Code != "user:update_privilege" {
+			adminPrivileges = append(adminPrivileges, p)
+		}
+	}
+	return adminPrivileges
+}
+
+func seedDefaultLocation(db *gorm.DB) {
+	locationRepo := repository.NewLocationRepo(db)
+	stockRepo := repository.NewStockRepo(db)
+
+	location, err := locationRepo.SeedDefault()
+	if err != nil {
+		log.Printf("Warning: Failed to seed default location: %v", err)
+		return
+	}
+	if err := stockRepo.BackfillDefaultLocation(location.ID); err != nil {
+		log.Printf("Warning: Failed to backfill default location: %v", err)
+	}
+}
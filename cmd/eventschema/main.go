@@ -0,0 +1,21 @@
+// Command eventschema prints the JSON schema of every WebSocket event envelope,
+// for the frontend to generate its types from:
+//
+//	go run ./cmd/eventschema > events.schema.json
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"go-inventory-ws/internal/events"
+)
+
+func main() {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(events.Schema()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is the payload of an envelope. Every event struct in this package implements it.
+type Event interface {
+	EventType() string
+	EventVersion() int
+}
+
+// Actor identifies the user who caused an event
+type Actor struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// Envelope is the common wrapper of every event sent to clients
+type Envelope struct {
+	ID         uuid.UUID `json:"id"`
+	Seq        int64     `json:"seq,omitempty"` // Set by the hub (see WithSeq), used to resume after reconnect
+	Type       string    `json:"type"`
+	Version    int       `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Actor      *Actor    `json:"actor,omitempty"`
+	Data       Event     `json:"data"`
+}
+
+// New wraps an event in an envelope. actor may be nil for system events.
+func New(data Event, actor *Actor) *Envelope {
+	return &Envelope{
+		ID:         uuid.New(),
+		Type:       data.EventType(),
+		Version:    data.EventVersion(),
+		OccurredAt: time.Now(),
+		Actor:      actor,
+		Data:       data,
+	}
+}
+
+// encodedEnvelope is an Envelope whose data is kept as raw JSON
+type encodedEnvelope struct {
+	Envelope
+	Data json.RawMessage `json:"data"`
+}
+
+// WithSeq returns an encoded envelope with its Seq set. The hub numbers events
+// when it sends them, after they were encoded (and possibly stored in the outbox).
+func WithSeq(payload []byte, seq int64) ([]byte, error) {
+	var env encodedEnvelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return nil, err
+	}
+	env.Seq = seq
+	return json.Marshal(env)
+}
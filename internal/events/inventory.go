@@ -0,0 +1,200 @@
+package events
+
+import (
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+)
+
+// Inventory event types
+const (
+	TypeProductCreated     = "product_created"
+	TypeProductUpdated     = "product_updated"
+	TypeProductArchived    = "product_archived"
+	TypeProductRestored    = "product_restored"
+	TypeProductDeleted     = "product_deleted"
+	TypeTransactionCreated = "transaction_created"
+	TypeDocumentCreated    = "transaction_document_created"
+	TypeTransactionVoided  = "transaction_voided"
+	TypeFinancialUpdate    = "financial_update"
+	TypeLowStockAlert      = "low_stock_alert"
+)
+
+// ProductSummary is the product snapshot carried by product events
+type ProductSummary struct {
+	ID    uuid.UUID       `json:"id"`
+	SKU   string          `json:"sku"`
+	Name  string          `json:"name"`
+	Stock decimal.Decimal `json:"stock"`
+	Price int64           `json:"price"`
+}
+
+// ProductCreated is published when a product is created
+type ProductCreated struct {
+	Product ProductSummary `json:"product"`
+	Message string         `json:"message"`
+}
+
+func (ProductCreated) EventType() string { return TypeProductCreated }
+func (ProductCreated) EventVersion() int { return 1 }
+
+// ProductUpdated is published when a product is edited
+type ProductUpdated struct {
+	Product  ProductSummary  `json:"product"`
+	OldStock decimal.Decimal `json:"old_stock"`
+	NewStock decimal.Decimal `json:"new_stock"`
+	Message  string          `json:"message"`
+}
+
+func (ProductUpdated) EventType() string { return TypeProductUpdated }
+func (ProductUpdated) EventVersion() int { return 1 }
+
+// ProductArchived is published when a product is hidden from the product list
+type ProductArchived struct {
+	Product ProductSummary `json:"product"`
+	Message string         `json:"message"`
+}
+
+func (ProductArchived) EventType() string { return TypeProductArchived }
+func (ProductArchived) EventVersion() int { return 1 }
+
+// ProductRestored is published when an archived or deleted product is brought back
+type ProductRestored struct {
+	Product ProductSummary `json:"product"`
+	Message string         `json:"message"`
+}
+
+func (ProductRestored) EventType() string { return TypeProductRestored }
+func (ProductRestored) EventVersion() int { return 1 }
+
+// ProductDeleted is published when a product is (soft) deleted
+type ProductDeleted struct {
+	Product ProductSummary `json:"product"`
+	Message string         `json:"message"`
+}
+
+func (ProductDeleted) EventType() string { return TypeProductDeleted }
+func (ProductDeleted) EventVersion() int { return 1 }
+
+// TransactionCreated is published when stock moves IN or OUT.
+// It carries no amounts, see FinancialUpdate.
+// v2: type can also be ADJUSTMENT or RETURN, direction tells which way stock moved.
+type TransactionCreated struct {
+	TransactionID uuid.UUID       `json:"transaction_id"`
+	Type          string          `json:"type"`                  // IN, OUT, ADJUSTMENT, RETURN
+	Direction     string          `json:"direction"`             // IN, OUT
+	ReasonCode    string          `json:"reason_code,omitempty"` // ADJUSTMENT, RETURN
+	Quantity      decimal.Decimal `json:"quantity"`              // In the product's base unit
+	Unit          string          `json:"unit"`                  // Unit the quantity was entered in
+	UnitQuantity  decimal.Decimal `json:"unit_quantity"`
+	ProductID     uuid.UUID       `json:"product_id"`
+	ProductName   string          `json:"product_name"`
+	ProductSKU    string          `json:"product_sku"`
+	NewStock      decimal.Decimal `json:"new_stock"` // Product total across locations
+	LocationID    uuid.UUID       `json:"location_id"`
+	LocationName  string          `json:"location_name"`
+	LocationStock decimal.Decimal `json:"location_stock"`    // Balance at the location after the transaction
+	Lots          []LotQuantity   `json:"lots,omitempty"`    // Lot-tracked products only
+	Serials       []string        `json:"serials,omitempty"` // Serialized products only
+	Message       string          `json:"message"`
+}
+
+func (TransactionCreated) EventType() string { return TypeTransactionCreated }
+func (TransactionCreated) EventVersion() int { return 2 }
+
+// LotQuantity is the part of a stock movement that went into or out of one lot
+type LotQuantity struct {
+	LotNumber  string          `json:"lot_number"`
+	ExpiryDate string          `json:"expiry_date,omitempty"` // YYYY-MM-DD
+	Quantity   decimal.Decimal `json:"quantity"`
+}
+
+// TransactionDocumentCreated is published once for a multi-line document instead of
+// a TransactionCreated per line. Like TransactionCreated it carries no amounts.
+type TransactionDocumentCreated struct {
+	DocumentID   uuid.UUID      `json:"document_id"`
+	Number       string         `json:"number"`
+	Type         string         `json:"type"` // IN, OUT
+	LocationID   uuid.UUID      `json:"location_id"`
+	LocationName string         `json:"location_name"`
+	Lines        []DocumentLine `json:"lines"`
+	Message      string         `json:"message"`
+}
+
+func (TransactionDocumentCreated) EventType() string { return TypeDocumentCreated }
+func (TransactionDocumentCreated) EventVersion() int { return 1 }
+
+// DocumentLine is one product of a TransactionDocumentCreated, same fields as TransactionCreated
+type DocumentLine struct {
+	TransactionID uuid.UUID       `json:"transaction_id"`
+	ProductID     uuid.UUID       `json:"product_id"`
+	ProductName   string          `json:"product_name"`
+	ProductSKU    string          `json:"product_sku"`
+	Quantity      decimal.Decimal `json:"quantity"` // In the product's base unit
+	Unit          string          `json:"unit"`
+	UnitQuantity  decimal.Decimal `json:"unit_quantity"`
+	NewStock      decimal.Decimal `json:"new_stock"`
+	LocationStock decimal.Decimal `json:"location_stock"`
+	Lots          []LotQuantity   `json:"lots,omitempty"`
+	Serials       []string        `json:"serials,omitempty"`
+}
+
+// TransactionVoided is published when a transaction is voided. Type, Direction,
+// Quantity, Lots and Serials are those of the reversal that restored the stock. No amounts.
+type TransactionVoided struct {
+	TransactionID uuid.UUID       `json:"transaction_id"` // The voided transaction
+	ReversalID    uuid.UUID       `json:"reversal_id"`
+	Type          string          `json:"type"`      // IN, OUT, ADJUSTMENT, RETURN
+	Direction     string          `json:"direction"` // IN, OUT
+	Quantity      decimal.Decimal `json:"quantity"`
+	ProductID     uuid.UUID       `json:"product_id"`
+	ProductName   string          `json:"product_name"`
+	ProductSKU    string          `json:"product_sku"`
+	NewStock      decimal.Decimal `json:"new_stock"`
+	LocationID    uuid.UUID       `json:"location_id"`
+	LocationName  string          `json:"location_name"`
+	LocationStock decimal.Decimal `json:"location_stock"`
+	Lots          []LotQuantity   `json:"lots,omitempty"`
+	Serials       []string        `json:"serials,omitempty"`
+	Reason        string          `json:"reason"`
+	Message       string          `json:"message"`
+}
+
+func (TransactionVoided) EventType() string { return TypeTransactionVoided }
+func (TransactionVoided) EventVersion() int { return 1 }
+
+// FinancialUpdate tells finance screens to refresh. Only sent to users with transaction:view.
+// When a transaction is voided it is sent again with Voided set.
+// For a document it is sent once with DocumentID set and the document total;
+// TransactionID and ProductID are then the nil UUID.
+type FinancialUpdate struct {
+	TransactionID uuid.UUID  `json:"transaction_id"`
+	DocumentID    *uuid.UUID `json:"document_id,omitempty"`
+	Type          string     `json:"type"` // IN, OUT
+	TotalAmount   int64      `json:"total_amount"`
+	PaymentMethod string     `json:"payment_method"`
+	ProductID     uuid.UUID  `json:"product_id"`
+	Voided        bool       `json:"voided,omitempty"` // The transaction no longer counts
+	Message       string     `json:"message"`
+}
+
+func (FinancialUpdate) EventType() string { return TypeFinancialUpdate }
+func (FinancialUpdate) EventVersion() int { return 1 }
+
+// LowStockAlert is published when an OUT transaction brings a product's stock
+// from above its reorder point to at or below it. With a location, the alert is
+// about the balance at that location (only for locations with their own reorder point).
+type LowStockAlert struct {
+	Product           ProductSummary   `json:"product"`
+	LocationID        *uuid.UUID       `json:"location_id,omitempty"`
+	LocationName      string           `json:"location_name,omitempty"`
+	LocationStock     *decimal.Decimal `json:"location_stock,omitempty"`
+	ReorderPoint      decimal.Decimal  `json:"reorder_point"`
+	ReorderQty        decimal.Decimal  `json:"reorder_qty"`
+	SuggestedOrderQty decimal.Decimal  `json:"suggested_order_qty"`
+	Critical          bool             `json:"critical"` // Stock is also below MinStock
+	Message           string           `json:"message"`
+}
+
+func (LowStockAlert) EventType() string { return TypeLowStockAlert }
+func (LowStockAlert) EventVersion() int { return 1 }
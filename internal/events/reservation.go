@@ -0,0 +1,47 @@
+package events
+
+import (
+	"time"
+
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+)
+
+// Reservation event types
+const (
+	TypeReservationCreated  = "reservation_created"
+	TypeReservationReleased = "reservation_released"
+)
+
+// ReservationSummary is the reservation snapshot carried by reservation events
+type ReservationSummary struct {
+	ID           uuid.UUID       `json:"id"`
+	ProductID    uuid.UUID       `json:"product_id"`
+	LocationID   uuid.UUID       `json:"location_id"`
+	Quantity     decimal.Decimal `json:"quantity"`
+	FulfilledQty decimal.Decimal `json:"fulfilled_qty"`
+	Reference    string          `json:"reference,omitempty"`
+	Status       string          `json:"status"`
+	ExpiresAt    time.Time       `json:"expires_at"`
+}
+
+// ReservationCreated is published when stock is put on hold for an order
+type ReservationCreated struct {
+	Reservation ReservationSummary `json:"reservation"`
+	Available   decimal.Decimal    `json:"available"` // Left to sell at the location afterwards
+	Message     string             `json:"message"`
+}
+
+func (ReservationCreated) EventType() string { return TypeReservationCreated }
+func (ReservationCreated) EventVersion() int { return 1 }
+
+// ReservationReleased is published when a reservation stops holding stock without being
+// picked: released by hand (status RELEASED) or by the expiry job (EXPIRED, no actor)
+type ReservationReleased struct {
+	Reservation ReservationSummary `json:"reservation"`
+	Message     string             `json:"message"`
+}
+
+func (ReservationReleased) EventType() string { return TypeReservationReleased }
+func (ReservationReleased) EventVersion() int { return 1 }
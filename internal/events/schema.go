@@ -0,0 +1,195 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+)
+
+// Registry lists every event type published to clients. Add new events here
+// so they show up in the generated JSON schema.
+var Registry = []Event{
+	ProductCreated{},
+	ProductUpdated{},
+	ProductArchived{},
+	ProductRestored{},
+	ProductDeleted{},
+	TransactionCreated{},
+	TransactionDocumentCreated{},
+	TransactionVoided{},
+	FinancialUpdate{},
+	LowStockAlert{},
+	StockTransferCreated{},
+	StockTransferDispatched{},
+	StockTransferReceived{},
+	StockTransferCancelled{},
+	StocktakeCreated{},
+	StocktakeCountUpdated{},
+	StocktakeApproved{},
+	StocktakeCancelled{},
+	ReservationCreated{},
+	ReservationReleased{},
+	UserStatusUpdate{},
+	ShiftCreated{},
+	ShiftUpdated{},
+	ShiftReassignedFrom{},
+	ShiftReassignedTo{},
+	ShiftCancelled{},
+}
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns a JSON Schema document describing the envelope of every
+// registered event, for the frontend to generate types from
+func Schema() map[string]interface{} {
+	g := &schemaGenerator{defs: map[string]interface{}{}}
+
+	variants := make([]interface{}, 0, len(Registry))
+	for _, event := range Registry {
+		name := event.EventType()
+		g.defs[name] = g.envelope(event)
+		variants = append(variants, ref(name))
+	}
+
+	return map[string]interface{}{
+		"$schema": schemaDialect,
+		"title":   "Event envelope",
+		"oneOf":   variants,
+		"$defs":   g.defs,
+	}
+}
+
+// envelope builds the schema of an Envelope whose data is the given event
+func (g *schemaGenerator) envelope(event Event) map[string]interface{} {
+	schema := g.structSchema(reflect.TypeOf(Envelope{}))
+	props := schema["properties"].(map[string]interface{})
+	props["type"] = map[string]interface{}{"const": event.EventType()}
+	props["version"] = map[string]interface{}{"const": event.EventVersion()}
+	props["data"] = g.schemaFor(reflect.TypeOf(event))
+	return schema
+}
+
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	uuidType    = reflect.TypeOf(uuid.UUID{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+	decimalType = reflect.TypeOf(decimal.Decimal(0))
+)
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/$defs/" + name}
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case uuidType:
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	case rawJSONType:
+		return map[string]interface{}{}
+	case decimalType:
+		// Exact number literal with at most 3 decimal places
+		return map[string]interface{}{"type": "number", "multipleOf": 0.001}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schemaFor(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		// Named structs go to $defs, which also keeps recursive types finite
+		name := t.Name()
+		if name == "" {
+			return g.structSchema(t)
+		}
+		if _, exists := g.defs[name]; !exists {
+			g.defs[name] = map[string]interface{}{} // Placeholder while recursing
+			g.defs[name] = g.structSchema(t)
+		}
+		return ref(name)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema describes the JSON object encoding/json produces for a struct
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	required := []string{}
+	g.addFields(t, props, &required)
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (g *schemaGenerator) addFields(t reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a json name are flattened, like encoding/json does
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(embedded, props, required)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if field.Type.Kind() == reflect.Interface {
+			props[name] = map[string]interface{}{}
+		} else {
+			props[name] = g.schemaFor(field.Type)
+		}
+
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
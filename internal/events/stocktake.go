@@ -0,0 +1,89 @@
+package events
+
+import (
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+)
+
+// Stocktake event types
+const (
+	TypeStocktakeCreated      = "stocktake_created"
+	TypeStocktakeCountUpdated = "stocktake_count_updated"
+	TypeStocktakeApproved     = "stocktake_approved"
+	TypeStocktakeCancelled    = "stocktake_cancelled"
+)
+
+// StocktakeSummary is the stocktake snapshot carried by stocktake events
+type StocktakeSummary struct {
+	ID           uuid.UUID `json:"id"`
+	Number       string    `json:"number"`
+	Status       string    `json:"status"`
+	LocationID   uuid.UUID `json:"location_id"`
+	LocationName string    `json:"location_name"`
+	Counted      int       `json:"counted"` // Lines counted so far
+	Total        int       `json:"total"`
+}
+
+// StocktakeCreated is published when a stocktake session opens
+type StocktakeCreated struct {
+	Stocktake StocktakeSummary `json:"stocktake"`
+	Message   string           `json:"message"`
+}
+
+func (StocktakeCreated) EventType() string { return TypeStocktakeCreated }
+func (StocktakeCreated) EventVersion() int { return 1 }
+
+// StocktakeCountUpdated is published on every batch of counts, for live progress
+type StocktakeCountUpdated struct {
+	Stocktake StocktakeSummary     `json:"stocktake"`
+	Counts    []StocktakeLineCount `json:"counts"` // Lines counted in this batch
+	Message   string               `json:"message"`
+}
+
+func (StocktakeCountUpdated) EventType() string { return TypeStocktakeCountUpdated }
+func (StocktakeCountUpdated) EventVersion() int { return 1 }
+
+// StocktakeLineCount is the count of one product. Variance is counted minus expected.
+type StocktakeLineCount struct {
+	ProductID   uuid.UUID       `json:"product_id"`
+	ExpectedQty decimal.Decimal `json:"expected_qty"`
+	CountedQty  decimal.Decimal `json:"counted_qty"`
+	Variance    decimal.Decimal `json:"variance"`
+}
+
+// StocktakeApproved is published when the variances are posted. Adjustments lists
+// the lines that moved stock, with the transaction recording each of them. The
+// variance is applied to the balance at approval time, so LocationStock is the
+// counted quantity plus the movements recorded while counting, not the count itself.
+type StocktakeApproved struct {
+	Stocktake   StocktakeSummary      `json:"stocktake"`
+	Adjustments []StocktakeAdjustment `json:"adjustments"`
+	Message     string                `json:"message"`
+}
+
+func (StocktakeApproved) EventType() string { return TypeStocktakeApproved }
+func (StocktakeApproved) EventVersion() int { return 1 }
+
+// StocktakeAdjustment is the ADJUSTMENT transaction posted for one line
+type StocktakeAdjustment struct {
+	TransactionID uuid.UUID       `json:"transaction_id"`
+	ProductID     uuid.UUID       `json:"product_id"`
+	ProductName   string          `json:"product_name"`
+	ProductSKU    string          `json:"product_sku"`
+	Direction     string          `json:"direction"` // IN, OUT
+	Quantity      decimal.Decimal `json:"quantity"`  // Counted minus expected, as a positive quantity
+	ExpectedQty   decimal.Decimal `json:"expected_qty"`
+	CountedQty    decimal.Decimal `json:"counted_qty"`
+	NewStock      decimal.Decimal `json:"new_stock"`
+	LocationStock decimal.Decimal `json:"location_stock"` // Resulting balance at the location
+}
+
+// StocktakeCancelled is published when an open stocktake is closed without posting
+type StocktakeCancelled struct {
+	Stocktake StocktakeSummary `json:"stocktake"`
+	Message   string           `json:"message"`
+}
+
+func (StocktakeCancelled) EventType() string { return TypeStocktakeCancelled }
+func (StocktakeCancelled) EventVersion() int { return 1 }
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+)
+
+// Stock transfer event types
+const (
+	TypeStockTransferCreated    = "stock_transfer_created"
+	TypeStockTransferDispatched = "stock_transfer_dispatched"
+	TypeStockTransferReceived   = "stock_transfer_received"
+	TypeStockTransferCancelled  = "stock_transfer_cancelled"
+)
+
+// TransferSummary is the transfer snapshot carried by transfer events
+type TransferSummary struct {
+	ID               uuid.UUID             `json:"id"`
+	Number           string                `json:"number"`
+	Status           string                `json:"status"`
+	FromLocationID   uuid.UUID             `json:"from_location_id"`
+	FromLocationName string                `json:"from_location_name"`
+	ToLocationID     uuid.UUID             `json:"to_location_id"`
+	ToLocationName   string                `json:"to_location_name"`
+	HasDiscrepancy   bool                  `json:"has_discrepancy"`
+	Lines            []TransferLineSummary `json:"lines"`
+}
+
+type TransferLineSummary struct {
+	ProductID      uuid.UUID       `json:"product_id"`
+	Quantity       decimal.Decimal `json:"quantity"`
+	ReceivedQty    decimal.Decimal `json:"received_qty"`
+	DiscrepancyQty decimal.Decimal `json:"discrepancy_qty"`
+}
+
+// StockTransferCreated is published when a draft transfer is created
+type StockTransferCreated struct {
+	Transfer TransferSummary `json:"transfer"`
+	Message  string          `json:"message"`
+}
+
+func (StockTransferCreated) EventType() string { return TypeStockTransferCreated }
+func (StockTransferCreated) EventVersion() int { return 1 }
+
+// StockTransferDispatched is published when the goods leave the source location
+type StockTransferDispatched struct {
+	Transfer TransferSummary `json:"transfer"`
+	Message  string          `json:"message"`
+}
+
+func (StockTransferDispatched) EventType() string { return TypeStockTransferDispatched }
+func (StockTransferDispatched) EventVersion() int { return 1 }
+
+// StockTransferReceived is published on every receipt. Transfer.Status tells whether
+// the transfer is complete (RECEIVED) or still partially received.
+type StockTransferReceived struct {
+	Transfer TransferSummary       `json:"transfer"`
+	Received []TransferLineSummary `json:"received"` // Quantities of this receipt only
+	Message  string                `json:"message"`
+}
+
+func (StockTransferReceived) EventType() string { return TypeStockTransferReceived }
+func (StockTransferReceived) EventVersion() int { return 1 }
+
+// StockTransferCancelled is published when a draft transfer is cancelled
+type StockTransferCancelled struct {
+	Transfer TransferSummary `json:"transfer"`
+	Message  string          `json:"message"`
+}
+
+func (StockTransferCancelled) EventType() string { return TypeStockTransferCancelled }
+func (StockTransferCancelled) EventVersion() int { return 1 }
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"time"
+
+	"go-inventory-ws/internal/model"
+)
+
+// User and shift event types
+const (
+	TypeUserStatusUpdate    = "user_status_update"
+	TypeShiftCreated        = "shift_created"
+	TypeShiftUpdated        = "shift_updated"
+	TypeShiftReassignedFrom = "shift_reassigned_from"
+	TypeShiftReassignedTo   = "shift_reassigned_to"
+	TypeShiftCancelled      = "shift_cancelled"
+)
+
+// UserStatusUpdate reports a change of a user's presence (only sent on transitions)
+type UserStatusUpdate struct {
+	UserID     string     `json:"user_id"`
+	Status     string     `json:"status"` // online, away, offline
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+func (UserStatusUpdate) EventType() string { return TypeUserStatusUpdate }
+func (UserStatusUpdate) EventVersion() int { return 1 }
+
+// ShiftCreated is sent to the user a new shift was assigned to
+type ShiftCreated struct {
+	Shift   model.ShiftResponse `json:"shift"`
+	Message string              `json:"message"`
+}
+
+func (ShiftCreated) EventType() string { return TypeShiftCreated }
+func (ShiftCreated) EventVersion() int { return 1 }
+
+// ShiftUpdated is sent to the assigned user when their shift changes
+type ShiftUpdated struct {
+	Shift   model.ShiftResponse `json:"shift"`
+	Message string              `json:"message"`
+}
+
+func (ShiftUpdated) EventType() string { return TypeShiftUpdated }
+func (ShiftUpdated) EventVersion() int { return 1 }
+
+// ShiftReassignedFrom is sent to the previous assignee of a reassigned shift
+type ShiftReassignedFrom struct {
+	NewAssignee model.UserResponse `json:"new_assignee"`
+	Message     string             `json:"message"`
+}
+
+func (ShiftReassignedFrom) EventType() string { return TypeShiftReassignedFrom }
+func (ShiftReassignedFrom) EventVersion() int { return 1 }
+
+// ShiftReassignedTo is sent to the new assignee of a reassigned shift
+type ShiftReassignedTo struct {
+	PreviousAssignee model.UserResponse  `json:"previous_assignee"`
+	Shift            model.ShiftResponse `json:"shift"`
+	Message          string              `json:"message"`
+}
+
+func (ShiftReassignedTo) EventType() string { return TypeShiftReassignedTo }
+func (ShiftReassignedTo) EventVersion() int { return 1 }
+
+// ShiftCancelled is sent to the assigned user when their shift is deleted
+type ShiftCancelled struct {
+	Shift   model.ShiftResponse `json:"shift"`
+	Message string              `json:"message"`
+}
+
+func (ShiftCancelled) EventType() string { return TypeShiftCancelled }
+func (ShiftCancelled) EventVersion() int { return 1 }
@@ -17,15 +17,19 @@ func NewDashboardHandler(s service.DashboardService) *DashboardHandler {
 }
 
 // GetStockMovement returns stock movement data for charts
-// Query params: days (default 7)
+// Query params: days (default 7), location_id (default all locations)
 func (h *DashboardHandler) GetStockMovement(c *fiber.Ctx) error {
 	daysStr := c.Query("days", "7")
 	days, err := strconv.Atoi(daysStr)
 	if err != nil || days <= 0 {
 		days = 7
 	}
+	locationID, err := queryUUID(c, "location_id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location_id"})
+	}
 
-	data, err := h.service.GetStockMovement(days)
+	data, err := h.service.GetStockMovement(days, locationID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch stock movement"})
 	}
@@ -37,8 +41,14 @@ func (h *DashboardHandler) GetStockMovement(c *fiber.Ctx) error {
 }
 
 // GetDashboardStats returns overview statistics
+// Query params: location_id (default all locations)
 func (h *DashboardHandler) GetDashboardStats(c *fiber.Ctx) error {
-	stats, err := h.service.GetDashboardStats()
+	locationID, err := queryUUID(c, "location_id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location_id"})
+	}
+
+	stats, err := h.service.GetDashboardStats(locationID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch dashboard stats"})
 	}
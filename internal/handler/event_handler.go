@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"strconv"
+
+	"go-inventory-ws/internal/ws"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EventHandler struct {
+	wsHub *ws.Hub
+}
+
+func NewEventHandler(hub *ws.Hub) *EventHandler {
+	return &EventHandler{wsHub: hub}
+}
+
+// Stream is the Server-Sent Events fallback for clients that cannot open a WebSocket
+// GET /api/v1/events
+// Query params: topics (comma separated, default all), token (EventSource cannot send headers)
+// Resumes after the Last-Event-ID header (or ?last_event_id=) like the WebSocket ?last_seq=
+func (h *EventHandler) Stream(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	tokenVersion, _ := c.Locals("token_version").(string)
+	privileges, _ := c.Locals("user_privileges").([]string)
+
+	client := ws.NewStreamClient(h.wsHub, userID, tokenVersion, privileges)
+	if topics := ws.ParseTopics(c.Query("topics")); len(topics) > 0 {
+		client.Subscribe(topics...)
+	}
+
+	lastEventID := c.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	if lastSeq, err := strconv.ParseInt(lastEventID, 10, 64); err == nil {
+		client.ResumeFrom(lastSeq)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+
+	// Runs after the handler returns, for as long as the client stays connected
+	c.Context().SetBodyStreamWriter(client.ServeSSE)
+	return nil
+}
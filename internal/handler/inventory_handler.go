@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
 	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
 	"go-inventory-ws/internal/service"
+	"go-inventory-ws/pkg/decimal"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -49,9 +53,20 @@ func parseUUID(id string) (uuid.UUID, error) {
 	return uuid.Parse(id)
 }
 
+// hasPrivilege checks the privileges set by RequireAuth, for checks that depend on the request body
+func hasPrivilege(c *fiber.Ctx, privilege string) bool {
+	privileges, _ := c.Locals("user_privileges").([]string)
+	for _, p := range privileges {
+		if p == privilege {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *InventoryHandler) CreateProduct(c *fiber.Ctx) error {
-	var product model.Product
-	if err := c.BodyParser(&product); err != nil {
+	var req service.CreateProductRequest
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
 	}
 
@@ -59,11 +74,11 @@ func (h *InventoryHandler) CreateProduct(c *fiber.Ctx) error {
 	userName := getUserName(c)
 	userEmail := getUserEmail(c)
 
-	if err := h.service.CreateProduct(&product, userID, userName, userEmail); err != nil {
+	if err := h.service.CreateProduct(&req, userID, userName, userEmail); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.Status(201).JSON(fiber.Map{"message": "Product created", "data": product})
+	return c.Status(201).JSON(fiber.Map{"message": "Product created", "data": req.Product})
 }
 
 func (h *InventoryHandler) CreateTransaction(c *fiber.Ctx) error {
@@ -72,6 +87,11 @@ func (h *InventoryHandler) CreateTransaction(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
 	}
 
+	// Taking stock out of expired lots is an override
+	if tx.AllowExpired && !hasPrivilege(c, "lot:override_expired") {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: requires 'lot:override_expired' privilege"})
+	}
+
 	userID := getUserID(c)
 	userName := getUserName(c)
 	userEmail := getUserEmail(c)
@@ -80,14 +100,81 @@ func (h *InventoryHandler) CreateTransaction(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.Status(201).JSON(fiber.Map{"message": "Transaction recorded"})
+	return c.Status(201).JSON(fiber.Map{"message": "Transaction recorded", "data": tx})
+}
+
+// CreateDocument records several products in one go, all lines or none
+// POST /api/v1/transaction-documents
+func (h *InventoryHandler) CreateDocument(c *fiber.Ctx) error {
+	var doc model.TransactionDocument
+	if err := c.BodyParser(&doc); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	for _, line := range doc.Lines {
+		if line.AllowExpired && !hasPrivilege(c, "lot:override_expired") {
+			return c.Status(403).JSON(fiber.Map{"error": "Forbidden: requires 'lot:override_expired' privilege"})
+		}
+	}
+
+	if err := h.service.RecordDocument(&doc, getUserID(c), getUserName(c), getUserEmail(c)); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{"message": "Transaction document recorded", "data": doc})
+}
+
+// GetDocuments lists transaction documents, newest first (without their lines)
+// GET /api/v1/transaction-documents
+func (h *InventoryHandler) GetDocuments(c *fiber.Ctx) error {
+	filter := repository.DocumentFilter{
+		Pagination: repository.Pagination{
+			Page:  c.QueryInt("page", 1),
+			Limit: c.QueryInt("limit", repository.DefaultPageSize),
+		},
+		Type: model.TransactionType(c.Query("type")),
+	}
+	if filter.Type != "" && filter.Type != model.TxIn && filter.Type != model.TxOut {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid type, must be IN or OUT"})
+	}
+	var err error
+	if filter.LocationID, err = queryUUID(c, "location_id"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location_id"})
+	}
+	filter.Normalize()
+
+	documents, total, err := h.service.ListDocuments(filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch transaction documents"})
+	}
+	return c.JSON(fiber.Map{
+		"data":  documents,
+		"total": total,
+		"page":  filter.Page,
+		"limit": filter.Limit,
+	})
+}
+
+// GetDocument returns a document with its lines
+// GET /api/v1/transaction-documents/:id
+func (h *InventoryHandler) GetDocument(c *fiber.Ctx) error {
+	docID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid document ID"})
+	}
+
+	doc, err := h.service.GetDocumentByID(docID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Transaction document not found"})
+	}
+	return c.JSON(doc)
 }
 
 func (h *InventoryHandler) UpdateProduct(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	var product model.Product
-	if err := c.BodyParser(&product); err != nil {
+	var req service.UpdateProductRequest
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
 	}
 
@@ -101,7 +188,7 @@ func (h *InventoryHandler) UpdateProduct(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
 	}
 
-	updated, err := h.service.UpdateProduct(productID, &product, userID, userName, userEmail)
+	updated, err := h.service.UpdateProduct(productID, &req, userID, userName, userEmail)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -109,20 +196,254 @@ func (h *InventoryHandler) UpdateProduct(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Product updated", "data": updated})
 }
 
+// ArchiveProduct hides a product from the list without touching its history
+func (h *InventoryHandler) ArchiveProduct(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+
+	archived, err := h.service.ArchiveProduct(productID, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return productError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"message": "Product archived", "data": archived})
+}
+
+func (h *InventoryHandler) RestoreProduct(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+
+	restored, err := h.service.RestoreProduct(productID, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return productError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"message": "Product restored", "data": restored})
+}
+
+func (h *InventoryHandler) DeleteProduct(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+
+	if err := h.service.DeleteProduct(productID, getUserID(c), getUserName(c), getUserEmail(c)); err != nil {
+		return productError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"message": "Product deleted"})
+}
+
+// GetProductStock returns a product's balance per location, with reserved and available stock
+// GET /api/v1/products/:id/stock
+func (h *InventoryHandler) GetProductStock(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+
+	product, balances, err := h.service.GetProductStock(productID)
+	if err != nil {
+		return productError(c, err)
+	}
+	// available = on-hand - reserved, in-transit stock can't be sold yet
+	reserved, available := decimal.Zero, decimal.Zero
+	for _, balance := range balances {
+		reserved += balance.Reserved
+		available += balance.Available
+	}
+	return c.JSON(fiber.Map{
+		"product_id": product.ID,
+		"stock":      product.Stock,
+		"reserved":   reserved,
+		"available":  available,
+		"data":       balances,
+	})
+}
+
+// SetLocationReorderPoint overrides a product's reorder point at one location
+// PUT /api/v1/products/:id/locations/:location_id {"reorder_point": 5} (null clears the override)
+func (h *InventoryHandler) SetLocationReorderPoint(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+	locationID, err := parseUUID(c.Params("location_id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location ID"})
+	}
+
+	var req struct {
+		ReorderPoint *decimal.Decimal `json:"reorder_point"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	if err := h.service.SetLocationReorderPoint(productID, locationID, req.ReorderPoint); err != nil {
+		return productError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Reorder point updated"})
+}
+
+// productError maps product service errors to a status code
+func productError(c *fiber.Ctx, err error) error {
+	switch err {
+	case service.ErrProductNotFound, service.ErrLocationNotFound:
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case service.ErrProductHasStock, service.ErrProductArchived, service.ErrLocationInactive:
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// GetLowStockReport lists products at or below their reorder point
+// GET /api/v1/products/low-stock?location_id=
+func (h *InventoryHandler) GetLowStockReport(c *fiber.Ctx) error {
+	locationID, err := queryUUID(c, "location_id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location_id"})
+	}
+
+	items, err := h.service.GetLowStockReport(locationID)
+	if err != nil {
+		return productError(c, err)
+	}
+	return c.JSON(fiber.Map{"data": items, "total": len(items)})
+}
+
+// GetProducts lists products page by page
+// GET /api/v1/products?page=&limit=&search=&unit=&stock_below=&min_price=&max_price=&created_by=&include_archived=&sort_by=&order=asc|desc
 func (h *InventoryHandler) GetProducts(c *fiber.Ctx) error {
-	products, err := h.service.GetAllProducts()
+	filter := repository.ProductFilter{
+		Pagination: repository.Pagination{
+			Page:  c.QueryInt("page", 1),
+			Limit: c.QueryInt("limit", repository.DefaultPageSize),
+		},
+		Search:          strings.TrimSpace(c.Query("search")),
+		Unit:            c.Query("unit"),
+		CreatedBy:       c.Query("created_by"),
+		IncludeArchived: c.QueryBool("include_archived"),
+		SortBy:          c.Query("sort_by", "created_at"),
+		SortDesc:        c.Query("order", "desc") == "desc",
+	}
+	if !repository.ProductSortColumns[filter.SortBy] {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid sort_by, use name, sku, stock, price, created_at or updated_at"})
+	}
+
+	var err error
+	if filter.StockBelow, err = queryDecimal(c, "stock_below"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid stock_below"})
+	}
+	if filter.MinPrice, err = queryInt64(c, "min_price"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid min_price"})
+	}
+	if filter.MaxPrice, err = queryInt64(c, "max_price"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid max_price"})
+	}
+	filter.Normalize()
+
+	products, total, err := h.service.ListProducts(filter)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Internal Server Error"})
 	}
-	return c.JSON(products)
+	return c.JSON(fiber.Map{
+		"data":  products,
+		"total": total,
+		"page":  filter.Page,
+		"limit": filter.Limit,
+	})
 }
 
+// GetTransactions lists transactions newest first with keyset pagination
+// GET /api/v1/transactions?from=YYYY-MM-DD&to=YYYY-MM-DD&product_id=&location_id=&type=IN|OUT&payment_method=&user_id=&cursor=&limit=
 func (h *InventoryHandler) GetTransactions(c *fiber.Ctx) error {
-	transactions, err := h.service.GetAllTransactions()
+	filter := repository.TransactionFilter{
+		Type:          model.TransactionType(c.Query("type")),
+		PaymentMethod: c.Query("payment_method"),
+		ReasonCode:    c.Query("reason_code"),
+		UserID:        c.Query("user_id"),
+		ExcludeVoided: c.QueryBool("exclude_voided"),
+		Limit:         c.QueryInt("limit", repository.DefaultPageSize),
+	}
+	switch filter.Type {
+	case "", model.TxIn, model.TxOut, model.TxAdjustment, model.TxTransfer, model.TxReturn:
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid type, use IN, OUT, ADJUSTMENT, TRANSFER or RETURN"})
+	}
+
+	var err error
+	if filter.From, err = queryDate(c, "from"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid from format, use YYYY-MM-DD"})
+	}
+	if filter.To, err = queryDate(c, "to"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid to format, use YYYY-MM-DD"})
+	}
+	if filter.To != nil {
+		// "to" is inclusive: everything before the next day
+		end := filter.To.AddDate(0, 0, 1)
+		filter.To = &end
+	}
+	if raw := c.Query("product_id"); raw != "" {
+		productID, err := parseUUID(raw)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid product_id"})
+		}
+		filter.ProductID = &productID
+	}
+	if filter.LocationID, err = queryUUID(c, "location_id"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location_id"})
+	}
+	if filter.After, err = queryCursor(c); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	transactions, next, err := h.service.ListTransactions(filter)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Internal Server Error"})
 	}
-	return c.JSON(transactions)
+	return c.JSON(fiber.Map{
+		"data":        transactions,
+		"next_cursor": encodeCursor(next),
+	})
+}
+
+// GetProductLedger lists a product's stock movements with the running stock balance,
+// overall or at one location
+// GET /api/v1/products/:id/transactions?location_id=&cursor=&limit=
+func (h *InventoryHandler) GetProductLedger(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+	locationID, err := queryUUID(c, "location_id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location_id"})
+	}
+	after, err := queryCursor(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	product, entries, next, err := h.service.GetProductLedger(productID, locationID, after, c.QueryInt("limit", repository.DefaultPageSize))
+	if err != nil {
+		return productError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"product": fiber.Map{
+			"id":    product.ID,
+			"sku":   product.SKU,
+			"name":  product.Name,
+			"stock": product.Stock,
+		},
+		"data":        entries,
+		"next_cursor": encodeCursor(next),
+	})
 }
 
 func (h *InventoryHandler) GetTransaction(c *fiber.Ctx) error {
@@ -139,6 +460,36 @@ func (h *InventoryHandler) GetTransaction(c *fiber.Ctx) error {
 	return c.JSON(tx)
 }
 
+// VoidTransaction reverses a mistaken transaction, a reason is required
+// POST /api/v1/transactions/:id/void
+func (h *InventoryHandler) VoidTransaction(c *fiber.Ctx) error {
+	txID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid transaction ID"})
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	reversal, err := h.service.VoidTransaction(txID, req.Reason, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		switch err {
+		case service.ErrTransactionNotFound, service.ErrProductNotFound, service.ErrLocationNotFound:
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		case service.ErrTransactionVoided, service.ErrReversalNotVoidable, service.ErrTransferNotVoidable, service.ErrLocationInactive:
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		default:
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	return c.Status(201).JSON(fiber.Map{"message": "Transaction voided", "data": reversal})
+}
+
 func (h *InventoryHandler) GetFinancialStats(c *fiber.Ctx) error {
 	rangeParam := c.Query("range", "7d") // Default 7 days
 	now := time.Now()
@@ -168,3 +519,107 @@ func (h *InventoryHandler) GetFinancialStats(c *fiber.Ctx) error {
 
 	return c.JSON(stats)
 }
+
+// GetShrinkageReport sums stock adjustments per reason code and per product
+// GET /api/v1/finance/shrinkage?from=YYYY-MM-DD&to=YYYY-MM-DD&location_id= (default: last 30 days)
+func (h *InventoryHandler) GetShrinkageReport(c *fiber.Ctx) error {
+	from, err := queryDate(c, "from")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid from format, use YYYY-MM-DD"})
+	}
+	to, err := queryDate(c, "to")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid to format, use YYYY-MM-DD"})
+	}
+	locationID, err := queryUUID(c, "location_id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location_id"})
+	}
+
+	endDate := time.Now()
+	if to != nil {
+		// "to" is inclusive: everything before the next day
+		endDate = to.AddDate(0, 0, 1)
+	}
+	startDate := endDate.AddDate(0, 0, -30)
+	if from != nil {
+		startDate = *from
+	}
+	if !startDate.Before(endDate) {
+		return c.Status(400).JSON(fiber.Map{"error": "from must be before to"})
+	}
+
+	report, err := h.service.GetShrinkageReport(startDate, endDate, locationID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to build shrinkage report"})
+	}
+	return c.JSON(report)
+}
+
+// queryDecimal parses an optional decimal query parameter (nil when absent)
+func queryDecimal(c *fiber.Ctx, key string) (*decimal.Decimal, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := decimal.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// queryInt64 parses an optional int64 query parameter (nil when absent)
+func queryInt64(c *fiber.Ctx, key string) (*int64, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// queryDate parses an optional YYYY-MM-DD query parameter (nil when absent)
+func queryDate(c *fiber.Ctx, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func queryCursor(c *fiber.Ctx) (*repository.Cursor, error) {
+	raw := c.Query("cursor")
+	if raw == "" {
+		return nil, nil
+	}
+	return repository.DecodeCursor(raw)
+}
+
+// encodeCursor returns nil (JSON null) on the last page
+func encodeCursor(cursor *repository.Cursor) interface{} {
+	if cursor == nil {
+		return nil
+	}
+	return cursor.Encode()
+}
+
+// queryUUID parses an optional UUID query parameter (nil when absent)
+func queryUUID(c *fiber.Ctx, key string) (*uuid.UUID, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := parseUUID(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LocationHandler struct {
+	locationService service.LocationService
+}
+
+func NewLocationHandler(locationService service.LocationService) *LocationHandler {
+	return &LocationHandler{locationService: locationService}
+}
+
+// GetLocations lists stock locations
+// GET /api/v1/locations?include_inactive=true
+func (h *LocationHandler) GetLocations(c *fiber.Ctx) error {
+	locations, err := h.locationService.GetLocations(c.QueryBool("include_inactive"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch locations"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  locations,
+		"total": len(locations),
+	})
+}
+
+// CreateLocation handles location creation
+// POST /api/v1/locations
+func (h *LocationHandler) CreateLocation(c *fiber.Ctx) error {
+	var location model.Location
+	if err := c.BodyParser(&location); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	if err := h.locationService.CreateLocation(&location, getUserID(c)); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{"message": "Location created", "data": location})
+}
+
+// UpdateLocation handles location update (including deactivation)
+// PUT /api/v1/locations/:id
+func (h *LocationHandler) UpdateLocation(c *fiber.Ctx) error {
+	locationID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location ID"})
+	}
+
+	var req service.UpdateLocationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	location, err := h.locationService.UpdateLocation(locationID, &req, getUserID(c))
+	if err != nil {
+		if err == service.ErrLocationNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "Location updated", "data": location})
+}
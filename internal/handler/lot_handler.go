@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"go-inventory-ws/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LotHandler struct {
+	lotService service.LotService
+}
+
+func NewLotHandler(lotService service.LotService) *LotHandler {
+	return &LotHandler{lotService: lotService}
+}
+
+// GetExpiringLots lists lots with stock expiring soon (expired ones first)
+// GET /api/v1/lots/expiring?days=30&location_id=
+func (h *LotHandler) GetExpiringLots(c *fiber.Ctx) error {
+	days := c.QueryInt("days", 30)
+	locationID, err := queryUUID(c, "location_id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location ID"})
+	}
+
+	lots, err := h.lotService.GetExpiringLots(days, locationID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch expiring lots"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  lots,
+		"total": len(lots),
+		"days":  days,
+	})
+}
+
+// GetProductLots lists the lots of a product at every location
+// GET /api/v1/products/:id/lots?include_empty=true
+func (h *LotHandler) GetProductLots(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+
+	lots, err := h.lotService.GetProductLots(productID, c.QueryBool("include_empty"))
+	if err != nil {
+		if err == service.ErrProductNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch lots"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  lots,
+		"total": len(lots),
+	})
+}
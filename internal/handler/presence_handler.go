@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"go-inventory-ws/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PresenceHandler struct {
+	presenceService service.PresenceService
+}
+
+func NewPresenceHandler(presenceService service.PresenceService) *PresenceHandler {
+	return &PresenceHandler{presenceService: presenceService}
+}
+
+// GetPresence returns the current presence status of every active user
+// GET /api/v1/presence
+func (h *PresenceHandler) GetPresence(c *fiber.Ctx) error {
+	entries, err := h.presenceService.GetPresence()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch presence"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  entries,
+		"total": len(entries),
+	})
+}
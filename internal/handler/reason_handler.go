@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReasonHandler struct {
+	reasonService service.ReasonService
+}
+
+func NewReasonHandler(reasonService service.ReasonService) *ReasonHandler {
+	return &ReasonHandler{reasonService: reasonService}
+}
+
+// GetReasonCodes lists the reason code catalog
+// GET /api/v1/reason-codes?type=ADJUSTMENT&include_inactive=true
+func (h *ReasonHandler) GetReasonCodes(c *fiber.Ctx) error {
+	txType := model.TransactionType(c.Query("type"))
+	if txType != "" && txType != model.TxAdjustment && txType != model.TxReturn {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid type, use ADJUSTMENT or RETURN"})
+	}
+
+	reasons, err := h.reasonService.GetReasonCodes(txType, c.QueryBool("include_inactive"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch reason codes"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  reasons,
+		"total": len(reasons),
+	})
+}
+
+// CreateReasonCode adds a reason code to the catalog
+// POST /api/v1/reason-codes
+func (h *ReasonHandler) CreateReasonCode(c *fiber.Ctx) error {
+	var reason model.ReasonCode
+	if err := c.BodyParser(&reason); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	if err := h.reasonService.CreateReasonCode(&reason, getUserID(c)); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{"message": "Reason code created", "data": reason})
+}
+
+// UpdateReasonCode renames, redirects or (de)activates a reason code
+// PUT /api/v1/reason-codes/:code
+func (h *ReasonHandler) UpdateReasonCode(c *fiber.Ctx) error {
+	var req service.UpdateReasonCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	reason, err := h.reasonService.UpdateReasonCode(c.Params("code"), &req, getUserID(c))
+	if err != nil {
+		if err == service.ErrReasonNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "Reason code updated", "data": reason})
+}
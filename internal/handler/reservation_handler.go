@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReservationHandler struct {
+	reservationService service.ReservationService
+}
+
+func NewReservationHandler(reservationService service.ReservationService) *ReservationHandler {
+	return &ReservationHandler{reservationService: reservationService}
+}
+
+// GetReservations lists reservations, newest first
+// GET /api/v1/reservations?status=&product_id=&location_id=&reference=&page=&limit=
+func (h *ReservationHandler) GetReservations(c *fiber.Ctx) error {
+	filter := repository.ReservationFilter{
+		Pagination: repository.Pagination{
+			Page:  c.QueryInt("page", 1),
+			Limit: c.QueryInt("limit", repository.DefaultPageSize),
+		},
+		Status:    model.ReservationStatus(c.Query("status")),
+		Reference: c.Query("reference"),
+	}
+	var err error
+	if filter.ProductID, err = queryUUID(c, "product_id"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product_id"})
+	}
+	if filter.LocationID, err = queryUUID(c, "location_id"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location_id"})
+	}
+	filter.Normalize()
+
+	reservations, total, err := h.reservationService.GetReservations(filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch reservations"})
+	}
+	return c.JSON(fiber.Map{
+		"data":  reservations,
+		"total": total,
+		"page":  filter.Page,
+		"limit": filter.Limit,
+	})
+}
+
+// GetReservation returns one reservation
+// GET /api/v1/reservations/:id
+func (h *ReservationHandler) GetReservation(c *fiber.Ctx) error {
+	reservationID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid reservation ID"})
+	}
+
+	reservation, err := h.reservationService.GetReservation(reservationID)
+	if err != nil {
+		return reservationError(c, err)
+	}
+	return c.JSON(reservation)
+}
+
+// CreateReservation holds stock for an order
+// POST /api/v1/reservations {"product_id":"...","quantity":2,"reference":"SO-1001","expires_at":"2024-02-01T10:00:00Z"}
+func (h *ReservationHandler) CreateReservation(c *fiber.Ctx) error {
+	var req service.CreateReservationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	reservation, err := h.reservationService.CreateReservation(&req, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return reservationError(c, err)
+	}
+	return c.Status(201).JSON(fiber.Map{"message": "Stock reserved", "data": reservation})
+}
+
+// ReleaseReservation gives the held stock back
+// POST /api/v1/reservations/:id/release
+func (h *ReservationHandler) ReleaseReservation(c *fiber.Ctx) error {
+	reservationID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid reservation ID"})
+	}
+
+	reservation, err := h.reservationService.ReleaseReservation(reservationID, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return reservationError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Reservation released", "data": reservation})
+}
+
+// reservationError maps reservation service errors to a status code
+func reservationError(c *fiber.Ctx, err error) error {
+	switch err {
+	case service.ErrReservationNotFound, service.ErrProductNotFound, service.ErrLocationNotFound:
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case service.ErrReservationNotActive, service.ErrProductArchived, service.ErrLocationInactive:
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SerialHandler struct {
+	serialService service.SerialService
+}
+
+func NewSerialHandler(serialService service.SerialService) *SerialHandler {
+	return &SerialHandler{serialService: serialService}
+}
+
+// GetProductSerials lists the units of a serialized product
+// GET /api/v1/products/:id/serials?status=IN_STOCK&location_id=
+func (h *SerialHandler) GetProductSerials(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+	locationID, err := queryUUID(c, "location_id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location ID"})
+	}
+
+	status := model.SerialStatus(c.Query("status"))
+	switch status {
+	case "", model.SerialInStock, model.SerialInTransit, model.SerialOut, model.SerialMissing:
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid status"})
+	}
+
+	serials, err := h.serialService.GetProductSerials(productID, status, locationID)
+	if err != nil {
+		if err == service.ErrProductNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch serial numbers"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  serials,
+		"total": len(serials),
+	})
+}
+
+// LookupSerial returns the unit(s) with a serial number and their transaction history
+// GET /api/v1/serials/:serial
+func (h *SerialHandler) LookupSerial(c *fiber.Ctx) error {
+	history, err := h.serialService.LookupSerial(c.Params("serial"))
+	if err != nil {
+		if err == service.ErrSerialNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up serial number"})
+	}
+
+	return c.JSON(fiber.Map{"data": history})
+}
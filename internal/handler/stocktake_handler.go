@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type StocktakeHandler struct {
+	stocktakeService service.StocktakeService
+}
+
+func NewStocktakeHandler(stocktakeService service.StocktakeService) *StocktakeHandler {
+	return &StocktakeHandler{stocktakeService: stocktakeService}
+}
+
+// GetStocktakes lists stocktakes with their counting progress, newest first
+// GET /api/v1/stocktakes?status=&location_id=&page=&limit=
+func (h *StocktakeHandler) GetStocktakes(c *fiber.Ctx) error {
+	filter := repository.StocktakeFilter{
+		Pagination: repository.Pagination{
+			Page:  c.QueryInt("page", 1),
+			Limit: c.QueryInt("limit", repository.DefaultPageSize),
+		},
+		Status: model.StocktakeStatus(c.Query("status")),
+	}
+	var err error
+	if filter.LocationID, err = queryUUID(c, "location_id"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location_id"})
+	}
+	filter.Normalize()
+
+	stocktakes, total, err := h.stocktakeService.GetStocktakes(filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch stocktakes"})
+	}
+	return c.JSON(fiber.Map{
+		"data":  stocktakes,
+		"total": total,
+		"page":  filter.Page,
+		"limit": filter.Limit,
+	})
+}
+
+// GetStocktake returns a stocktake with its lines and their variances
+// GET /api/v1/stocktakes/:id
+func (h *StocktakeHandler) GetStocktake(c *fiber.Ctx) error {
+	stocktakeID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid stocktake ID"})
+	}
+
+	stocktake, err := h.stocktakeService.GetStocktake(stocktakeID)
+	if err != nil {
+		return stocktakeError(c, err)
+	}
+	return c.JSON(stocktake)
+}
+
+// CreateStocktake opens a stocktake and snapshots the expected quantities
+// POST /api/v1/stocktakes {"location_id":"...","product_ids":["..."]}
+func (h *StocktakeHandler) CreateStocktake(c *fiber.Ctx) error {
+	var req service.CreateStocktakeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	stocktake, err := h.stocktakeService.CreateStocktake(&req, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return stocktakeError(c, err)
+	}
+	return c.Status(201).JSON(fiber.Map{"message": "Stocktake created", "data": stocktake})
+}
+
+// RecordCounts saves counted quantities
+// POST /api/v1/stocktakes/:id/counts {"counts":[{"product_id":"...","quantity":12,"mode":"add"}]}
+func (h *StocktakeHandler) RecordCounts(c *fiber.Ctx) error {
+	stocktakeID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid stocktake ID"})
+	}
+
+	var req service.RecordCountsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	lines, err := h.stocktakeService.RecordCounts(stocktakeID, &req, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return stocktakeError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Counts recorded", "data": lines})
+}
+
+// ApproveStocktake posts the variances as adjustments
+// POST /api/v1/stocktakes/:id/approve
+func (h *StocktakeHandler) ApproveStocktake(c *fiber.Ctx) error {
+	stocktakeID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid stocktake ID"})
+	}
+
+	stocktake, err := h.stocktakeService.ApproveStocktake(stocktakeID, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return stocktakeError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Stocktake approved", "data": stocktake})
+}
+
+// CancelStocktake closes an open stocktake without posting
+// POST /api/v1/stocktakes/:id/cancel
+func (h *StocktakeHandler) CancelStocktake(c *fiber.Ctx) error {
+	stocktakeID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid stocktake ID"})
+	}
+
+	stocktake, err := h.stocktakeService.CancelStocktake(stocktakeID, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return stocktakeError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Stocktake cancelled", "data": stocktake})
+}
+
+// stocktakeError maps stocktake service errors to a status code
+func stocktakeError(c *fiber.Ctx, err error) error {
+	switch err {
+	case service.ErrStocktakeNotFound, service.ErrProductNotFound, service.ErrLocationNotFound:
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case service.ErrStocktakeNotOpen, service.ErrStocktakeIncomplete, service.ErrProductArchived, service.ErrLocationInactive:
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TransferHandler struct {
+	transferService service.TransferService
+}
+
+func NewTransferHandler(transferService service.TransferService) *TransferHandler {
+	return &TransferHandler{transferService: transferService}
+}
+
+// GetTransfers lists stock transfers, newest first
+// GET /api/v1/transfers?status=&location_id=&page=&limit=
+func (h *TransferHandler) GetTransfers(c *fiber.Ctx) error {
+	filter := repository.TransferFilter{
+		Pagination: repository.Pagination{
+			Page:  c.QueryInt("page", 1),
+			Limit: c.QueryInt("limit", repository.DefaultPageSize),
+		},
+		Status: model.TransferStatus(c.Query("status")),
+	}
+	var err error
+	if filter.LocationID, err = queryUUID(c, "location_id"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid location_id"})
+	}
+	filter.Normalize()
+
+	transfers, total, err := h.transferService.GetTransfers(filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch transfers"})
+	}
+	return c.JSON(fiber.Map{
+		"data":  transfers,
+		"total": total,
+		"page":  filter.Page,
+		"limit": filter.Limit,
+	})
+}
+
+// GetTransfer returns a transfer with its lines
+// GET /api/v1/transfers/:id
+func (h *TransferHandler) GetTransfer(c *fiber.Ctx) error {
+	transferID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid transfer ID"})
+	}
+
+	transfer, err := h.transferService.GetTransfer(transferID)
+	if err != nil {
+		return transferError(c, err)
+	}
+	return c.JSON(transfer)
+}
+
+// CreateTransfer creates a draft transfer
+// POST /api/v1/transfers
+func (h *TransferHandler) CreateTransfer(c *fiber.Ctx) error {
+	var req service.CreateTransferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	transfer, err := h.transferService.CreateTransfer(&req, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return transferError(c, err)
+	}
+	return c.Status(201).JSON(fiber.Map{"message": "Transfer created", "data": transfer})
+}
+
+// DispatchTransfer ships a draft transfer
+// POST /api/v1/transfers/:id/dispatch
+func (h *TransferHandler) DispatchTransfer(c *fiber.Ctx) error {
+	transferID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid transfer ID"})
+	}
+
+	transfer, err := h.transferService.DispatchTransfer(transferID, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return transferError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Transfer dispatched", "data": transfer})
+}
+
+// ReceiveTransfer records a (partial) receipt
+// POST /api/v1/transfers/:id/receive {"lines":[{"line_id":"...","quantity":5}],"close":false}
+func (h *TransferHandler) ReceiveTransfer(c *fiber.Ctx) error {
+	transferID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid transfer ID"})
+	}
+
+	var req service.ReceiveTransferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	transfer, err := h.transferService.ReceiveTransfer(transferID, &req, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return transferError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Transfer received", "data": transfer})
+}
+
+// CancelTransfer cancels a draft transfer
+// POST /api/v1/transfers/:id/cancel
+func (h *TransferHandler) CancelTransfer(c *fiber.Ctx) error {
+	transferID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid transfer ID"})
+	}
+
+	transfer, err := h.transferService.CancelTransfer(transferID, getUserID(c), getUserName(c), getUserEmail(c))
+	if err != nil {
+		return transferError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Transfer cancelled", "data": transfer})
+}
+
+// transferError maps transfer service errors to a status code
+func transferError(c *fiber.Ctx, err error) error {
+	switch err {
+	case service.ErrTransferNotFound, service.ErrProductNotFound, service.ErrLocationNotFound:
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case service.ErrTransferInvalidState, service.ErrProductArchived, service.ErrLocationInactive:
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UnitHandler struct {
+	unitService service.UnitService
+}
+
+func NewUnitHandler(unitService service.UnitService) *UnitHandler {
+	return &UnitHandler{unitService: unitService}
+}
+
+// GetUnits lists the unit catalog
+// GET /api/v1/units?include_inactive=true
+func (h *UnitHandler) GetUnits(c *fiber.Ctx) error {
+	units, err := h.unitService.GetUnits(c.QueryBool("include_inactive"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch units"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  units,
+		"total": len(units),
+	})
+}
+
+// CreateUnit adds a unit to the catalog
+// POST /api/v1/units
+func (h *UnitHandler) CreateUnit(c *fiber.Ctx) error {
+	var unit model.UnitOfMeasure
+	if err := c.BodyParser(&unit); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	if err := h.unitService.CreateUnit(&unit, getUserID(c)); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{"message": "Unit created", "data": unit})
+}
+
+// UpdateUnit renames or (de)activates a unit
+// PUT /api/v1/units/:code
+func (h *UnitHandler) UpdateUnit(c *fiber.Ctx) error {
+	var req service.UpdateUnitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	unit, err := h.unitService.UpdateUnit(c.Params("code"), &req, getUserID(c))
+	if err != nil {
+		if err == service.ErrUnitNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "Unit updated", "data": unit})
+}
+
+// GetProductUnits returns a product's base unit and its alternative units
+// GET /api/v1/products/:id/units
+func (h *UnitHandler) GetProductUnits(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+
+	product, units, err := h.unitService.GetProductUnits(productID)
+	if err != nil {
+		if err == service.ErrProductNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch product units"})
+	}
+
+	return c.JSON(fiber.Map{
+		"product_id": product.ID,
+		"base_unit":  product.Unit,
+		"stock":      product.Stock,
+		"data":       units,
+	})
+}
+
+// SetProductUnit adds or changes the conversion of an alternative unit
+// PUT /api/v1/products/:id/units/:code  {"factor": 24}
+func (h *UnitHandler) SetProductUnit(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+
+	var req struct {
+		Factor int `json:"factor"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
+	}
+
+	unit, err := h.unitService.SetProductUnit(productID, c.Params("code"), req.Factor, getUserID(c))
+	if err != nil {
+		if err == service.ErrProductNotFound || err == service.ErrUnitNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "Product unit saved", "data": unit})
+}
+
+// RemoveProductUnit removes an alternative unit from a product
+// DELETE /api/v1/products/:id/units/:code
+func (h *UnitHandler) RemoveProductUnit(c *fiber.Ctx) error {
+	productID, err := parseUUID(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid product ID"})
+	}
+
+	if err := h.unitService.RemoveProductUnit(productID, c.Params("code")); err != nil {
+		if err == service.ErrUnitNotConfigured {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to remove product unit"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Product unit removed"})
+}
@@ -24,32 +24,69 @@ func RequireAuth(userRepo repository.UserRepository) fiber.Handler {
 			return c.Status(401).JSON(fiber.Map{"error": "Invalid authorization format. Use: Bearer <token>"})
 		}
 
-		tokenString := parts[1]
+		return authenticate(c, userRepo, parts[1])
+	}
+}
 
-		// Validate token
-		claims, err := jwt.ValidateToken(tokenString)
-		if err != nil {
-			return c.Status(401).JSON(fiber.Map{"error": "Invalid or expired token"})
+// RequireStreamAuth is the auth middleware for the event streams (/ws and SSE).
+// Browsers cannot set headers on a WebSocket handshake or an EventSource, so besides
+// the usual "Authorization: Bearer <token>" header the token is also accepted as a
+// subprotocol pair ("Sec-WebSocket-Protocol: bearer, <token>") or as ?token=<token>.
+func RequireStreamAuth(userRepo repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString := extractStreamToken(c)
+		if tokenString == "" {
+			return c.Status(401).JSON(fiber.Map{"error": "Missing authorization token"})
 		}
 
-		// Check strict session against DB
-		user, err := userRepo.FindByID(claims.UserID)
-		if err != nil {
-			return c.Status(401).JSON(fiber.Map{"error": "User not found"})
-		}
+		return authenticate(c, userRepo, tokenString)
+	}
+}
+
+// extractStreamToken looks for the token in header, subprotocol and query (in that order)
+func extractStreamToken(c *fiber.Ctx) string {
+	if parts := strings.Split(c.Get("Authorization"), " "); len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+		return parts[1]
+	}
 
-		if user.TokenVersion != claims.TokenVersion {
-			return c.Status(401).JSON(fiber.Map{"error": "Session expired (logged in on another device)"})
+	// Sec-WebSocket-Protocol: bearer, <token>
+	protocols := strings.Split(c.Get("Sec-WebSocket-Protocol"), ",")
+	for i := 0; i+1 < len(protocols); i++ {
+		if strings.ToLower(strings.TrimSpace(protocols[i])) == "bearer" {
+			return strings.TrimSpace(protocols[i+1])
 		}
+	}
+
+	return c.Query("token")
+}
+
+// authenticate validates the token, enforces the single-session TokenVersion check
+// and sets user info in context for downstream handlers
+func authenticate(c *fiber.Ctx, userRepo repository.UserRepository, tokenString string) error {
+	// Validate token
+	claims, err := jwt.ValidateToken(tokenString)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid or expired token"})
+	}
 
-		// Set user info in context for downstream handlers
-		c.Locals("user_id", claims.UserID.String())
-		c.Locals("user_email", claims.Email)
-		c.Locals("user_name", claims.Name)
-		c.Locals("user_privileges", claims.Privileges)
+	// Check strict session against DB
+	user, err := userRepo.FindByID(claims.UserID)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "User not found"})
+	}
 
-		return c.Next()
+	if user.TokenVersion != claims.TokenVersion {
+		return c.Status(401).JSON(fiber.Map{"error": "Session expired (logged in on another device)"})
 	}
+
+	// Set user info in context for downstream handlers
+	c.Locals("user_id", claims.UserID.String())
+	c.Locals("user_email", claims.Email)
+	c.Locals("user_name", claims.Name)
+	c.Locals("user_privileges", claims.Privileges)
+	c.Locals("token_version", claims.TokenVersion)
+
+	return c.Next()
 }
 
 // RequirePrivilege checks if the authenticated user has the required privilege
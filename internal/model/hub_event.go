@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// HubEvent is a persisted WebSocket event. Seq is the hub-wide sequence number
+// clients send back on reconnect to replay what they missed.
+type HubEvent struct {
+	Seq               int64     `gorm:"primaryKey;autoIncrement" json:"seq"`
+	Topics            string    `gorm:"type:text" json:"topics"`                              // Comma separated
+	RequiredPrivilege string    `gorm:"type:varchar(50)" json:"required_privilege,omitempty"` // Empty = everyone
+	UserIDs           string    `gorm:"type:text" json:"user_ids,omitempty"`                  // Comma separated, empty = broadcast
+	Payload           string    `gorm:"type:text;not null" json:"payload"`                    // Raw JSON message (without seq)
+	CreatedAt         time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (HubEvent) TableName() string {
+	return "hub_events"
+}
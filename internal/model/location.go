@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+)
+
+type LocationType string
+
+const (
+	LocationStore     LocationType = "STORE"
+	LocationWarehouse LocationType = "WAREHOUSE"
+)
+
+// DefaultLocationCode is the location seeded at startup. Existing stock and
+// transactions recorded without a location belong to it.
+const DefaultLocationCode = "MAIN"
+
+// Location is a place holding stock (store front, warehouse)
+type Location struct {
+	BaseModel
+	Code      string       `gorm:"type:varchar(30);uniqueIndex;not null" json:"code" validate:"required"`
+	Name      string       `gorm:"type:varchar(255);not null" json:"name" validate:"required"`
+	Type      LocationType `gorm:"type:varchar(20);not null" json:"type" validate:"required,oneof=STORE WAREHOUSE"`
+	Address   string       `gorm:"type:text" json:"address"`
+	IsDefault bool         `gorm:"default:false" json:"is_default"`
+	IsActive  bool         `gorm:"default:true" json:"is_active"`
+}
+
+// TableName specifies the table name for GORM
+func (Location) TableName() string {
+	return "locations"
+}
+
+// StockBalance is the on-hand quantity of a product at one location.
+// Product.Stock is the sum of its balances, in-transit quantities included.
+type StockBalance struct {
+	ProductID  uuid.UUID       `gorm:"type:uuid;primaryKey" json:"product_id"`
+	LocationID uuid.UUID       `gorm:"type:uuid;primaryKey;index" json:"location_id"`
+	Location   *Location       `gorm:"foreignKey:LocationID" json:"location,omitempty"`
+	Quantity   decimal.Decimal `gorm:"not null;default:0" json:"quantity"`
+	InTransit  decimal.Decimal `gorm:"not null;default:0" json:"in_transit"` // Dispatched by a transfer, not received yet
+
+	// Held by active reservations and what is left to sell (Quantity - Reserved),
+	// filled in by GET /products/:id/stock
+	Reserved  decimal.Decimal `gorm:"-" json:"reserved"`
+	Available decimal.Decimal `gorm:"-" json:"available"`
+
+	// Optional per-location reorder point, falls back to Product.ReorderPoint
+	ReorderPoint *decimal.Decimal `json:"reorder_point,omitempty" validate:"omitempty,gte=0"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (StockBalance) TableName() string {
+	return "stock_balances"
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"time"
+
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Lot is a batch of a lot-tracked product at one location. The quantities of a
+// product's lots at a location add up to its StockBalance.Quantity there.
+type Lot struct {
+	BaseModel
+	ProductID  uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_lots_product_location_number" json:"product_id"`
+	Product    *Product        `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	LocationID uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_lots_product_location_number" json:"location_id"`
+	Location   *Location       `gorm:"foreignKey:LocationID" json:"location,omitempty"`
+	LotNumber  string          `gorm:"type:varchar(50);not null;uniqueIndex:idx_lots_product_location_number" json:"lot_number"`
+	ExpiryDate *time.Time      `gorm:"type:date;index" json:"expiry_date,omitempty"` // Empty = does not expire
+	Quantity   decimal.Decimal `gorm:"not null;default:0" json:"quantity"`
+}
+
+// TableName specifies the table name for GORM
+func (Lot) TableName() string {
+	return "lots"
+}
+
+// IsExpired reports whether the lot is past its expiry date (the expiry day itself is still fine)
+func (l *Lot) IsExpired(now time.Time) bool {
+	if l.ExpiryDate == nil {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	expiry := time.Date(l.ExpiryDate.Year(), l.ExpiryDate.Month(), l.ExpiryDate.Day(), 0, 0, 0, 0, time.UTC)
+	return expiry.Before(today)
+}
+
+// TransactionLot records which lots a transaction moved (one row per lot)
+type TransactionLot struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primary_key;" json:"id"`
+	TransactionID uuid.UUID       `gorm:"type:uuid;not null;index" json:"transaction_id"`
+	LotID         uuid.UUID       `gorm:"type:uuid;not null;index" json:"lot_id"`
+	Lot           *Lot            `gorm:"foreignKey:LotID" json:"lot,omitempty"`
+	Quantity      decimal.Decimal `gorm:"not null" json:"quantity"`
+}
+
+// TableName specifies the table name for GORM
+func (TransactionLot) TableName() string {
+	return "transaction_lots"
+}
+
+func (l *TransactionLot) BeforeCreate(tx *gorm.DB) (err error) {
+	l.ID = uuid.New()
+	return
+}
+
+// TransferLineLot records the lots a transfer line took from the source location,
+// so the destination receives the same lot numbers and expiry dates
+type TransferLineLot struct {
+	ID             uuid.UUID       `gorm:"type:uuid;primary_key;" json:"id"`
+	TransferLineID uuid.UUID       `gorm:"type:uuid;not null;index" json:"transfer_line_id"`
+	LotNumber      string          `gorm:"type:varchar(50);not null" json:"lot_number"`
+	ExpiryDate     *time.Time      `gorm:"type:date" json:"expiry_date,omitempty"`
+	Quantity       decimal.Decimal `gorm:"not null" json:"quantity"`
+	ReceivedQty    decimal.Decimal `gorm:"not null;default:0" json:"received_qty"`
+}
+
+// TableName specifies the table name for GORM
+func (TransferLineLot) TableName() string {
+	return "transfer_line_lots"
+}
+
+func (l *TransferLineLot) BeforeCreate(tx *gorm.DB) (err error) {
+	l.ID = uuid.New()
+	return
+}
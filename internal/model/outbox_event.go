@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// OutboxEvent is a WebSocket event written in the same DB transaction as the change
+// it describes. The outbox dispatcher relays it to the hub only once committed.
+type OutboxEvent struct {
+	ID                int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Topics            string     `gorm:"type:text" json:"topics"`                              // Comma separated
+	RequiredPrivilege string     `gorm:"type:varchar(50)" json:"required_privilege,omitempty"` // Empty = everyone
+	UserIDs           string     `gorm:"type:text" json:"user_ids,omitempty"`                  // Comma separated, empty = broadcast
+	Payload           string     `gorm:"type:text;not null" json:"payload"`                    // Raw JSON message
+	CreatedAt         time.Time  `json:"created_at"`
+	DispatchedAt      *time.Time `gorm:"index" json:"dispatched_at,omitempty"` // Nil = pending
+}
+
+// TableName specifies the table name for GORM
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -23,6 +23,26 @@ var DefaultPrivileges = []Privilege{
 	// Transaction management
 	{Code: "transaction:view", Name: "View Transaction"},
 	{Code: "transaction:create", Name: "Create Transaction"},
+	{Code: "transaction:void", Name: "Void Transaction"},
+	// Locations (stores, warehouses)
+	{Code: "location:manage", Name: "Manage Locations"},
+	// Unit of measure catalog
+	{Code: "unit:manage", Name: "Manage Units"},
+	// Reason codes of adjustments and returns
+	{Code: "reason:manage", Name: "Manage Reason Codes"},
+	// Stock transfers between locations
+	{Code: "transfer:view", Name: "View Transfer"},
+	{Code: "transfer:create", Name: "Create Transfer"},
+	{Code: "transfer:receive", Name: "Receive Transfer"},
+	// Stocktakes (physical counts)
+	{Code: "stocktake:view", Name: "View Stocktake"},
+	{Code: "stocktake:count", Name: "Count Stocktake"},
+	{Code: "stocktake:approve", Name: "Approve Stocktake"},
+	// Reservations (stock held for orders)
+	{Code: "reservation:view", Name: "View Reservation"},
+	{Code: "reservation:manage", Name: "Manage Reservations"},
+	// Lots
+	{Code: "lot:override_expired", Name: "Override Expired Lot"},
 	// Dashboard
 	{Code: "dashboard:view", Name: "View Dashboard"},
 	// Shift management (MASTER_ADMIN only)
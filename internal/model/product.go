@@ -1,12 +1,38 @@
 package model
 
+import (
+	"time"
+
+	"go-inventory-ws/pkg/decimal"
+)
+
 type Product struct {
 	BaseModel
-	SKU   string `gorm:"type:varchar(50);uniqueIndex;not null" json:"sku" validate:"required"`
-	Name  string `gorm:"type:varchar(255);not null" json:"name" validate:"required"`
-	Stock int    `gorm:"default:0" json:"stock"`
-	Unit  string `gorm:"type:varchar(20)" json:"unit"`
-	Price int64  `gorm:"default:0" json:"price" validate:"required,gt=0"`
+	SKU   string          `gorm:"type:varchar(50);uniqueIndex;not null" json:"sku" validate:"required"`
+	Name  string          `gorm:"type:varchar(255);not null" json:"name" validate:"required"`
+	Stock decimal.Decimal `gorm:"default:0" json:"stock"` // Derived: total of Balances (on hand + in transit), kept in sync by the inventory service
+	Unit  string          `gorm:"type:varchar(20)" json:"unit"`
+	Price int64           `gorm:"default:0" json:"price" validate:"required,gt=0"`
+
+	// Lot-tracked products (perishables) record a lot on every IN and pick lots
+	// first-expired-first-out on OUT. Can only be changed while the product has no stock.
+	TrackLots bool `gorm:"default:false" json:"track_lots"`
+
+	// Serialized products (electronics) register a serial number per unit on IN and
+	// require the serials of the units taken out on OUT. Same switching rule as TrackLots.
+	Serialized bool `gorm:"default:false" json:"serialized"`
+
+	// Reorder levels. A product is low on stock once Stock <= ReorderPoint.
+	// ReorderQty is the usual quantity to order, MinStock/MaxStock are optional
+	// safety stock and capacity (when set: MinStock <= ReorderPoint <= MaxStock).
+	ReorderPoint decimal.Decimal  `gorm:"not null" json:"reorder_point" validate:"gte=0"`
+	ReorderQty   decimal.Decimal  `gorm:"not null;default:0" json:"reorder_qty" validate:"gte=0"`
+	MinStock     *decimal.Decimal `json:"min_stock,omitempty" validate:"omitempty,gte=0"`
+	MaxStock     *decimal.Decimal `json:"max_stock,omitempty" validate:"omitempty,gt=0"`
+
+	// Archived products are hidden from GET /products but keep their transaction history
+	ArchivedAt *time.Time `gorm:"index" json:"archived_at,omitempty"`
+	ArchivedBy string     `gorm:"type:varchar(255)" json:"archived_by,omitempty"`
 
 	// User tracking
 	CreatedByUserID *string `gorm:"type:varchar(255)" json:"created_by_user_id,omitempty"`
@@ -15,5 +41,28 @@ type Product struct {
 	UpdatedByUser   *User   `gorm:"foreignKey:UpdatedByUserID;references:ID" json:"updated_by_user,omitempty"`
 
 	// Relasi
-	Transactions []Transaction `json:"transactions,omitempty"`
+	Transactions []Transaction  `json:"transactions,omitempty"`
+	Balances     []StockBalance `gorm:"foreignKey:ProductID" json:"balances,omitempty"`
+}
+
+// IsArchived reports whether the product has been archived
+func (p *Product) IsArchived() bool {
+	return p.ArchivedAt != nil
+}
+
+// IsLowStock reports whether stock is at or below the reorder point
+func (p *Product) IsLowStock() bool {
+	return p.Stock <= p.ReorderPoint
+}
+
+// SuggestedOrderQty is how much to order to get back to MaxStock
+// (or ReorderQty when no maximum is configured)
+func (p *Product) SuggestedOrderQty() decimal.Decimal {
+	if p.MaxStock != nil {
+		if qty := *p.MaxStock - p.Stock; qty > 0 {
+			return qty
+		}
+		return 0
+	}
+	return p.ReorderQty
 }
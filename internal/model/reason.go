@@ -0,0 +1,41 @@
+package model
+
+// DefaultReasonCodes are seeded into the catalog at startup
+var DefaultReasonCodes = []ReasonCode{
+	{Code: "DAMAGED", Name: "Damaged", Type: TxAdjustment, Direction: TxOut},
+	{Code: "EXPIRED", Name: "Expired", Type: TxAdjustment, Direction: TxOut},
+	{Code: "THEFT", Name: "Theft", Type: TxAdjustment, Direction: TxOut},
+	{Code: "LOST", Name: "Lost", Type: TxAdjustment, Direction: TxOut},
+	{Code: "TRANSIT_LOSS", Name: "Lost in transit", Type: TxAdjustment, Direction: TxOut},
+	{Code: "FOUND", Name: "Found stock", Type: TxAdjustment, Direction: TxIn},
+	{Code: "COUNT_CORRECTION", Name: "Count correction", Type: TxAdjustment},
+	{Code: "CUSTOMER_RETURN", Name: "Customer return", Type: TxReturn, Direction: TxIn},
+	{Code: "SUPPLIER_RETURN", Name: "Return to supplier", Type: TxReturn, Direction: TxOut},
+}
+
+// StocktakeReasonCode is the reason of the adjustments posted by an approved stocktake
+const StocktakeReasonCode = "COUNT_CORRECTION"
+
+// TransitLossReasonCode is the reason of the adjustments recording transfer discrepancies
+const TransitLossReasonCode = "TRANSIT_LOSS"
+
+// ReasonCode explains an ADJUSTMENT or RETURN transaction (damage, theft, found
+// stock...). Codes are stored upper case.
+type ReasonCode struct {
+	BaseModel
+	Code      string          `gorm:"type:varchar(30);uniqueIndex;not null" json:"code" validate:"required,max=30"`
+	Name      string          `gorm:"type:varchar(100);not null" json:"name" validate:"required"`
+	Type      TransactionType `gorm:"type:varchar(10);not null" json:"type" validate:"required,oneof=ADJUSTMENT RETURN"`
+	Direction TransactionType `gorm:"type:varchar(10)" json:"direction" validate:"omitempty,oneof=IN OUT"` // Empty = both
+	IsActive  bool            `gorm:"default:true" json:"is_active"`
+}
+
+// TableName specifies the table name for GORM
+func (ReasonCode) TableName() string {
+	return "reason_codes"
+}
+
+// Allows reports whether the reason can explain a transaction of this type and direction
+func (r *ReasonCode) Allows(txType, direction TransactionType) bool {
+	return r.Type == txType && (r.Direction == "" || r.Direction == direction)
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+)
+
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "ACTIVE"    // Holding stock
+	ReservationFulfilled ReservationStatus = "FULFILLED" // Picked: OUT transactions used up the quantity
+	ReservationReleased  ReservationStatus = "RELEASED"  // Released by hand (order cancelled)
+	ReservationExpired   ReservationStatus = "EXPIRED"   // Released by the expiry job
+)
+
+// Reservation holds stock at a location for an order that has not been picked yet.
+// Available stock is on-hand minus the outstanding quantity of active, unexpired
+// reservations; OUT transactions referencing the reservation draw from the hold.
+type Reservation struct {
+	BaseModel
+	ProductID    uuid.UUID         `gorm:"type:uuid;not null;index:idx_reservations_balance" json:"product_id" validate:"uuid_required"`
+	Product      *Product          `gorm:"foreignKey:ProductID" json:"product,omitempty" validate:"-"`
+	LocationID   uuid.UUID         `gorm:"type:uuid;not null;index:idx_reservations_balance" json:"location_id"`
+	Location     *Location         `gorm:"foreignKey:LocationID" json:"location,omitempty" validate:"-"`
+	Quantity     decimal.Decimal   `gorm:"not null" json:"quantity" validate:"required,gt=0"` // In the product's base unit
+	FulfilledQty decimal.Decimal   `gorm:"not null;default:0" json:"fulfilled_qty"`           // Taken out by OUT transactions so far
+	Reference    string            `gorm:"type:varchar(100);index" json:"reference"`          // Order number
+	Note         string            `gorm:"type:text" json:"note"`
+	Status       ReservationStatus `gorm:"type:varchar(20);not null;index:idx_reservations_balance" json:"status"`
+	ExpiresAt    time.Time         `gorm:"not null;index" json:"expires_at"`
+	ReleasedAt   *time.Time        `json:"released_at,omitempty"` // Fulfilled, released or expired
+	ReleasedBy   string            `gorm:"type:varchar(100)" json:"released_by,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (Reservation) TableName() string {
+	return "reservations"
+}
+
+// Outstanding is the quantity still held
+func (r *Reservation) Outstanding() decimal.Decimal {
+	return r.Quantity - r.FulfilledQty
+}
+
+// IsHolding reports whether the reservation still holds stock at the given time
+func (r *Reservation) IsHolding(now time.Time) bool {
+	return r.Status == ReservationActive && r.ExpiresAt.After(now)
+}
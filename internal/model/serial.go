@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SerialStatus string
+
+const (
+	SerialInStock   SerialStatus = "IN_STOCK"   // On hand at LocationID
+	SerialInTransit SerialStatus = "IN_TRANSIT" // Dispatched to LocationID, not received yet
+	SerialOut       SerialStatus = "OUT"        // Left the inventory through an OUT transaction (sold)
+	SerialMissing   SerialStatus = "MISSING"    // Shipped but never received (transfer discrepancy)
+)
+
+// SerialNumber is one unit of a serialized product. A serial number is unique per product.
+type SerialNumber struct {
+	BaseModel
+	ProductID  uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_serials_product_serial" json:"product_id"`
+	Product    *Product     `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	Serial     string       `gorm:"type:varchar(100);not null;uniqueIndex:idx_serials_product_serial;index" json:"serial"`
+	Status     SerialStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	LocationID *uuid.UUID   `gorm:"type:uuid;index" json:"location_id"` // Current (or last) location
+	Location   *Location    `gorm:"foreignKey:LocationID" json:"location,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (SerialNumber) TableName() string {
+	return "serial_numbers"
+}
+
+// TransactionSerial records which units a transaction moved (one row per unit)
+type TransactionSerial struct {
+	ID            uuid.UUID     `gorm:"type:uuid;primary_key;" json:"id"`
+	TransactionID uuid.UUID     `gorm:"type:uuid;not null;index" json:"transaction_id"`
+	SerialID      uuid.UUID     `gorm:"type:uuid;not null;index" json:"serial_id"`
+	Serial        *SerialNumber `gorm:"foreignKey:SerialID" json:"serial,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (TransactionSerial) TableName() string {
+	return "transaction_serials"
+}
+
+func (s *TransactionSerial) BeforeCreate(tx *gorm.DB) (err error) {
+	s.ID = uuid.New()
+	return
+}
+
+// TransferLineSerial is one unit shipped on a transfer line of a serialized product
+type TransferLineSerial struct {
+	ID             uuid.UUID     `gorm:"type:uuid;primary_key;" json:"id"`
+	TransferLineID uuid.UUID     `gorm:"type:uuid;not null;index" json:"transfer_line_id"`
+	SerialID       uuid.UUID     `gorm:"type:uuid;not null;index" json:"serial_id"`
+	Serial         *SerialNumber `gorm:"foreignKey:SerialID" json:"serial,omitempty"`
+	ReceivedAt     *time.Time    `json:"received_at,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (TransferLineSerial) TableName() string {
+	return "transfer_line_serials"
+}
+
+func (s *TransferLineSerial) BeforeCreate(tx *gorm.DB) (err error) {
+	s.ID = uuid.New()
+	return
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"time"
+
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TransferStatus string
+
+const (
+	TransferDraft             TransferStatus = "DRAFT"              // Editable, no stock moved yet
+	TransferInTransit         TransferStatus = "IN_TRANSIT"         // Left the source location
+	TransferPartiallyReceived TransferStatus = "PARTIALLY_RECEIVED" // Some lines/quantities received
+	TransferReceived          TransferStatus = "RECEIVED"           // Closed, discrepancies recorded on the lines
+	TransferCancelled         TransferStatus = "CANCELLED"          // Cancelled while still a draft
+)
+
+// StockTransfer moves quantities of products from one location to another.
+// Dispatch takes the stock out of the source location and holds it as in-transit
+// at the destination until it is received.
+type StockTransfer struct {
+	BaseModel
+	Number         string         `gorm:"type:varchar(30);uniqueIndex;not null" json:"number"`
+	FromLocationID uuid.UUID      `gorm:"type:uuid;not null;index" json:"from_location_id"`
+	FromLocation   *Location      `gorm:"foreignKey:FromLocationID" json:"from_location,omitempty"`
+	ToLocationID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"to_location_id"`
+	ToLocation     *Location      `gorm:"foreignKey:ToLocationID" json:"to_location,omitempty"`
+	Status         TransferStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Note           string         `gorm:"type:text" json:"note"`
+
+	DispatchedAt   *time.Time `json:"dispatched_at,omitempty"`
+	DispatchedBy   string     `gorm:"type:varchar(255)" json:"dispatched_by,omitempty"`
+	ReceivedAt     *time.Time `json:"received_at,omitempty"` // When the transfer was closed
+	ReceivedBy     string     `gorm:"type:varchar(255)" json:"received_by,omitempty"`
+	HasDiscrepancy bool       `gorm:"default:false" json:"has_discrepancy"`
+
+	Lines []StockTransferLine `gorm:"foreignKey:TransferID" json:"lines"`
+}
+
+// TableName specifies the table name for GORM
+func (StockTransfer) TableName() string {
+	return "stock_transfers"
+}
+
+// StockTransferLine is one product of a transfer
+type StockTransferLine struct {
+	ID          uuid.UUID       `gorm:"type:uuid;primary_key;" json:"id"`
+	TransferID  uuid.UUID       `gorm:"type:uuid;not null;index" json:"transfer_id"`
+	ProductID   uuid.UUID       `gorm:"type:uuid;not null" json:"product_id"`
+	Product     *Product        `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	Quantity    decimal.Decimal `gorm:"not null" json:"quantity"`               // Shipped
+	ReceivedQty decimal.Decimal `gorm:"not null;default:0" json:"received_qty"` // Received so far
+
+	// Shipped but never received (lost, damaged...), recorded when the transfer is closed
+	DiscrepancyQty  decimal.Decimal `gorm:"not null;default:0" json:"discrepancy_qty"`
+	DiscrepancyNote string          `gorm:"type:text" json:"discrepancy_note,omitempty"`
+
+	// Lots taken from the source (lot-tracked products), in FEFO order
+	Lots []TransferLineLot `gorm:"foreignKey:TransferLineID" json:"lots,omitempty"`
+
+	// Units shipped (serialized products), chosen when the transfer is created
+	Serials []TransferLineSerial `gorm:"foreignKey:TransferLineID" json:"serials,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (StockTransferLine) TableName() string {
+	return "stock_transfer_lines"
+}
+
+func (l *StockTransferLine) BeforeCreate(tx *gorm.DB) (err error) {
+	l.ID = uuid.New()
+	return
+}
+
+// Outstanding is the quantity still expected at the destination
+func (l *StockTransferLine) Outstanding() decimal.Decimal {
+	return l.Quantity - l.ReceivedQty - l.DiscrepancyQty
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"time"
+
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type StocktakeStatus string
+
+const (
+	StocktakeOpen      StocktakeStatus = "OPEN"      // Counting in progress
+	StocktakeApproved  StocktakeStatus = "APPROVED"  // Variances posted as adjustments
+	StocktakeCancelled StocktakeStatus = "CANCELLED" // Closed without touching stock
+)
+
+// Stocktake is a physical count of products at one location. Expected quantities
+// are snapshotted when the session opens; approval posts the variances (counted
+// minus expected) on top of the balance at that time, so movements recorded while
+// counting are kept. Each line's ResultingQty is the balance after approval.
+type Stocktake struct {
+	BaseModel
+	Number     string          `gorm:"type:varchar(30);uniqueIndex;not null" json:"number"`                                                        // e.g. STK-20240131-3F9A1C
+	LocationID uuid.UUID       `gorm:"type:uuid;not null;index;uniqueIndex:idx_stocktakes_open_location,where:status = 'OPEN'" json:"location_id"` // One open session per location
+	Location   *Location       `gorm:"foreignKey:LocationID" json:"location,omitempty"`
+	Status     StocktakeStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Note       string          `gorm:"type:text" json:"note"`
+
+	ApprovedAt  *time.Time `json:"approved_at,omitempty"`
+	ApprovedBy  string     `gorm:"type:varchar(100)" json:"approved_by,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+
+	Lines    []StocktakeLine    `gorm:"foreignKey:StocktakeID" json:"lines,omitempty"`
+	Progress *StocktakeProgress `gorm:"-" json:"progress,omitempty"`
+
+	// Products a location-wide stocktake left out, only set when it is created
+	Excluded []StocktakeExclusion `gorm:"-" json:"excluded,omitempty"`
+}
+
+// StocktakeExclusion is a product stocked at the location that can't be counted
+// in a stocktake (lot-tracked and serialized products are counted per lot/serial)
+type StocktakeExclusion struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Name      string    `json:"name"`
+	SKU       string    `json:"sku"`
+	Reason    string    `json:"reason"` // lot_tracked, serialized
+}
+
+// StocktakeProgress is how many lines of a stocktake have been counted
+type StocktakeProgress struct {
+	Counted int `json:"counted"`
+	Total   int `json:"total"`
+}
+
+// TableName specifies the table name for GORM
+func (Stocktake) TableName() string {
+	return "stocktakes"
+}
+
+// StocktakeLine is one product of a stocktake
+type StocktakeLine struct {
+	ID          uuid.UUID       `gorm:"type:uuid;primaryKey" json:"id"`
+	StocktakeID uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_stocktake_product" json:"stocktake_id"`
+	ProductID   uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_stocktake_product" json:"product_id"`
+	Product     *Product        `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	ExpectedQty decimal.Decimal `gorm:"not null;default:0" json:"expected_qty"` // Balance when the session opened
+
+	// Nil until someone counts the product
+	CountedQty *decimal.Decimal `json:"counted_qty"`
+	CountedBy  string           `gorm:"type:varchar(100)" json:"counted_by,omitempty"`
+	CountedAt  *time.Time       `json:"counted_at,omitempty"`
+	Variance   *decimal.Decimal `gorm:"-" json:"variance"` // Counted minus expected, set when loaded
+
+	// ADJUSTMENT transaction posted on approval (lines without variance have none)
+	AdjustmentID *uuid.UUID `gorm:"type:uuid" json:"adjustment_id,omitempty"`
+	// Location balance right after approval: the counted quantity plus whatever moved
+	// at the location between the snapshot and the approval
+	ResultingQty *decimal.Decimal `json:"resulting_qty,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (StocktakeLine) TableName() string {
+	return "stocktake_lines"
+}
+
+func (l *StocktakeLine) BeforeCreate(tx *gorm.DB) (err error) {
+	l.ID = uuid.New()
+	return
+}
+
+// AfterFind fills in the variance, nil while the line is not counted
+func (l *StocktakeLine) AfterFind(tx *gorm.DB) (err error) {
+	l.Variance = nil
+	if l.CountedQty != nil {
+		variance := *l.CountedQty - l.ExpectedQty
+		l.Variance = &variance
+	}
+	return
+}
@@ -1,25 +1,91 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+)
 
 type TransactionType string
 
 const (
-	TxIn  TransactionType = "IN"
-	TxOut TransactionType = "OUT"
+	TxIn  TransactionType = "IN"  // Purchase
+	TxOut TransactionType = "OUT" // Sale
+
+	// Stock-only movements, not purchases or sales: excluded from income/expense.
+	// Direction tells whether they bring stock IN or take it OUT.
+	TxAdjustment TransactionType = "ADJUSTMENT" // Damage, shrinkage, found stock (needs a reason code)
+	TxTransfer   TransactionType = "TRANSFER"   // Recorded by stock transfers (dispatch and receipt)
+	TxReturn     TransactionType = "RETURN"     // Customer or supplier returns (needs a reason code)
 )
 
+// IsStockOnly reports whether the type only moves stock, without income or expense
+func (t TransactionType) IsStockOnly() bool {
+	return t == TxAdjustment || t == TxTransfer || t == TxReturn
+}
+
 type Transaction struct {
 	BaseModel
-	ProductID     uuid.UUID       `gorm:"type:uuid;not null" json:"product_id" validate:"uuid_required"`
-	Product       Product         `json:"product" validate:"-"` // Relasi - skip validation
-	Type          TransactionType `gorm:"type:varchar(10);not null" json:"type" validate:"required,oneof=IN OUT"`
-	Quantity      int             `gorm:"not null" json:"quantity" validate:"required,gt=0"` // Qty harus > 0
-	TotalAmount   int64           `gorm:"not null" json:"total_amount"`                      // Snapshot price * quantity
-	PaymentMethod string          `gorm:"type:varchar(20)" json:"payment_method"`            // CASH, TRANSFER. Bisa kosong/0 logic.
+	ProductID     uuid.UUID       `gorm:"type:uuid;not null;index" json:"product_id" validate:"uuid_required"`
+	Product       Product         `json:"product" validate:"-"`               // Relasi - skip validation
+	LocationID    *uuid.UUID      `gorm:"type:uuid;index" json:"location_id"` // Empty = default location
+	Location      *Location       `json:"location,omitempty" validate:"-"`
+	Type          TransactionType `gorm:"type:varchar(10);not null" json:"type" validate:"required,oneof=IN OUT ADJUSTMENT TRANSFER RETURN"`
+	Direction     TransactionType `gorm:"type:varchar(10);not null;default:''" json:"direction" validate:"omitempty,oneof=IN OUT"` // IN or OUT, same as Type for IN/OUT
+	ReasonCode    string          `gorm:"type:varchar(30);index" json:"reason_code,omitempty"`                                     // ADJUSTMENT and RETURN, see ReasonCode
+	Quantity      decimal.Decimal `gorm:"not null" json:"quantity" validate:"required,gt=0"`                                       // Qty harus > 0
+	TotalAmount   int64           `gorm:"not null" json:"total_amount"`                                                            // Snapshot price * quantity
+	PaymentMethod string          `gorm:"type:varchar(20)" json:"payment_method"`                                                  // CASH, TRANSFER. Bisa kosong/0 logic.
 	Note          string          `json:"note"`
 
+	// Document the transaction is a line of, nil for single transactions
+	DocumentID *uuid.UUID `gorm:"type:uuid;index" json:"document_id,omitempty"`
+	// Stock transfer a TRANSFER transaction was recorded by
+	TransferID *uuid.UUID `gorm:"type:uuid;index" json:"transfer_id,omitempty"`
+	// Reservation an OUT transaction picks (draws from the held stock), optional
+	ReservationID *uuid.UUID `gorm:"type:uuid;index" json:"reservation_id,omitempty"`
+	// Part of Quantity drawn from the reservation's hold, put back on hold if voided
+	ReservedQty decimal.Decimal `gorm:"not null;default:0" json:"reserved_qty,omitempty"`
+	// Stocktake an ADJUSTMENT transaction posted the variance of
+	StocktakeID *uuid.UUID `gorm:"type:uuid;index" json:"stocktake_id,omitempty"`
+
+	// Unit the quantity was entered in. On input Quantity is in Unit (empty = the product's
+	// base unit); it is stored converted to the base unit, UnitQuantity keeps what was entered.
+	Unit         string          `gorm:"type:varchar(20)" json:"unit"`
+	UnitQuantity decimal.Decimal `gorm:"not null;default:0" json:"unit_quantity"`
+	UnitFactor   int             `gorm:"not null;default:1" json:"unit_factor"` // Base units per Unit
+
+	// Lots moved (lot-tracked products only)
+	Lots []TransactionLot `gorm:"foreignKey:TransactionID" json:"lots,omitempty" validate:"-"`
+
+	// Lot input, not stored on the transaction itself. IN: the lot received (required
+	// for lot-tracked products). OUT: an explicit lot, otherwise lots are picked FEFO.
+	LotNumber    string `gorm:"-" json:"lot_number,omitempty"`
+	ExpiryDate   string `gorm:"-" json:"expiry_date,omitempty"`   // YYYY-MM-DD, IN only
+	AllowExpired bool   `gorm:"-" json:"allow_expired,omitempty"` // OUT from expired lots, needs lot:override_expired
+
+	// Units moved (serialized products only). Serials is the input: one serial number
+	// per unit, registered on IN and required on OUT.
+	SerialUnits []TransactionSerial `gorm:"foreignKey:TransactionID" json:"serial_units,omitempty" validate:"-"`
+	Serials     []string            `gorm:"-" json:"serials,omitempty" validate:"-"`
+
+	// Void: a voided transaction is corrected by a reversal (opposite type, same
+	// quantities, lots and serials) that links back to it with ReversalOfID.
+	// Neither counts in financial summaries.
+	VoidedAt     *time.Time `gorm:"index" json:"voided_at,omitempty"`
+	VoidedBy     string     `gorm:"type:varchar(255)" json:"voided_by,omitempty"`
+	VoidReason   string     `gorm:"type:text" json:"void_reason,omitempty"`
+	ReversalID   *uuid.UUID `gorm:"type:uuid" json:"reversal_id,omitempty"`
+	ReversalOfID *uuid.UUID `gorm:"type:uuid;uniqueIndex" json:"reversal_of_id,omitempty"`
+
 	// User tracking
 	CreatedByUserID *string `gorm:"type:varchar(255)" json:"created_by_user_id,omitempty"`
 	CreatedByUser   *User   `gorm:"foreignKey:CreatedByUserID;references:ID" json:"created_by_user,omitempty"`
 }
+
+// IsVoided reports whether the transaction was voided (and reversed)
+func (t *Transaction) IsVoided() bool {
+	return t.VoidedAt != nil
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// TransactionDocument groups several products moved in one go (a customer checkout,
+// a supplier delivery). Every line is a Transaction of the document's type and
+// location; the lines are recorded atomically, all or none.
+type TransactionDocument struct {
+	BaseModel
+	Number        string          `gorm:"type:varchar(30);uniqueIndex;not null" json:"number"`
+	Type          TransactionType `gorm:"type:varchar(10);not null;index" json:"type" validate:"required,oneof=IN OUT"`
+	LocationID    *uuid.UUID      `gorm:"type:uuid;index" json:"location_id"` // Empty = default location
+	Location      *Location       `json:"location,omitempty" validate:"-"`
+	PaymentMethod string          `gorm:"type:varchar(20)" json:"payment_method"`
+	Note          string          `gorm:"type:text" json:"note"`
+	TotalAmount   int64           `gorm:"not null;default:0" json:"total_amount"` // Sum of the lines not voided
+
+	// Lines only need product_id, quantity and the optional unit/lot/serial input,
+	// type, location and payment method come from the document
+	Lines []Transaction `gorm:"foreignKey:DocumentID" json:"lines" validate:"required,min=1,dive"`
+
+	// User tracking
+	CreatedByUserID *string `gorm:"type:varchar(255)" json:"created_by_user_id,omitempty"`
+	CreatedByUser   *User   `gorm:"foreignKey:CreatedByUserID;references:ID" json:"created_by_user,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (TransactionDocument) TableName() string {
+	return "transaction_documents"
+}
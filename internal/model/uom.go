@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultUnits are seeded into the catalog at startup
+var DefaultUnits = []UnitOfMeasure{
+	{Code: "PCS", Name: "Pieces"},
+	{Code: "BOX", Name: "Box"},
+	{Code: "CTN", Name: "Carton"},
+	{Code: "PACK", Name: "Pack"},
+	{Code: "KG", Name: "Kilogram"},
+	{Code: "G", Name: "Gram"},
+	{Code: "L", Name: "Liter"},
+	{Code: "ML", Name: "Milliliter"},
+}
+
+// UnitOfMeasure is an entry of the unit catalog. Codes are stored upper case.
+type UnitOfMeasure struct {
+	BaseModel
+	Code     string `gorm:"type:varchar(20);uniqueIndex;not null" json:"code" validate:"required,max=20"`
+	Name     string `gorm:"type:varchar(100);not null" json:"name" validate:"required"`
+	IsActive bool   `gorm:"default:true" json:"is_active"`
+}
+
+// TableName specifies the table name for GORM
+func (UnitOfMeasure) TableName() string {
+	return "units_of_measure"
+}
+
+// ProductUnit is an alternative unit a product can be moved in, e.g. 1 BOX = 24 PCS
+// when the product's base unit (Product.Unit) is PCS
+type ProductUnit struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;" json:"id"`
+	ProductID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_product_units_product_unit" json:"product_id"`
+	UnitCode  string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_product_units_product_unit" json:"unit_code"`
+	Factor    int       `gorm:"not null" json:"factor"` // Base units in one of this unit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by"`
+}
+
+// TableName specifies the table name for GORM
+func (ProductUnit) TableName() string {
+	return "product_units"
+}
+
+func (u *ProductUnit) BeforeCreate(tx *gorm.DB) (err error) {
+	u.ID = uuid.New()
+	return
+}
@@ -21,6 +21,7 @@ type User struct {
 	Privileges   []Privilege `gorm:"many2many:user_privileges;" json:"privileges,omitempty"`
 	TokenVersion string      `gorm:"type:varchar(255);default:''" json:"-"` // For single session enforcement
 	LastSeenAt   *time.Time  `json:"last_seen_at,omitempty"`                // For user presence
+	SocketSeenAt *time.Time  `json:"-"`                                     // Refreshed by the instance holding the user's WebSocket
 }
 
 // SetPassword hashes and sets the user's password
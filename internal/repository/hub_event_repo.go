@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"time"
+
+	"go-inventory-ws/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type HubEventRepository interface {
+	Append(event *model.HubEvent) error
+	FindAfter(seq int64, limit int) ([]model.HubEvent, error)
+	OldestSeq() (int64, error)
+	DeleteOlderThan(cutoff time.Time) (int64, error)
+}
+
+type hubEventRepo struct {
+	db *gorm.DB
+}
+
+func NewHubEventRepo(db *gorm.DB) HubEventRepository {
+	return &hubEventRepo{db}
+}
+
+// Append inserts the event; Seq is filled from the table sequence
+func (r *hubEventRepo) Append(event *model.HubEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *hubEventRepo) FindAfter(seq int64, limit int) ([]model.HubEvent, error) {
+	var events []model.HubEvent
+	err := r.db.Where("seq > ?", seq).Order("seq ASC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// OldestSeq returns the lowest sequence still retained (0 when the log is empty)
+func (r *hubEventRepo) OldestSeq() (int64, error) {
+	var seq int64
+	err := r.db.Model(&model.HubEvent{}).Select("COALESCE(MIN(seq), 0)").Scan(&seq).Error
+	return seq, err
+}
+
+// DeleteOlderThan prunes events past retention.
+// The newest event is always kept so an empty log still means "nothing was ever published".
+func (r *hubEventRepo) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	result := r.db.Where("created_at < ? AND seq < (SELECT MAX(seq) FROM hub_events)", cutoff).
+		Delete(&model.HubEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"go-inventory-ws/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LocationRepository interface {
+	Create(location *model.Location) error
+	Update(location *model.Location) error
+	FindAll(includeInactive bool) ([]model.Location, error)
+	FindByID(id uuid.UUID) (*model.Location, error)
+	FindByCode(code string) (*model.Location, error)
+	FindDefault() (*model.Location, error)
+	SeedDefault() (*model.Location, error)
+}
+
+type locationRepo struct {
+	db *gorm.DB
+}
+
+func NewLocationRepo(db *gorm.DB) LocationRepository {
+	return &locationRepo{db}
+}
+
+func (r *locationRepo) Create(location *model.Location) error {
+	return r.db.Create(location).Error
+}
+
+func (r *locationRepo) Update(location *model.Location) error {
+	return r.db.Save(location).Error
+}
+
+func (r *locationRepo) FindAll(includeInactive bool) ([]model.Location, error) {
+	var locations []model.Location
+	query := r.db.Order("is_default DESC, name ASC")
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Find(&locations).Error
+	return locations, err
+}
+
+func (r *locationRepo) FindByID(id uuid.UUID) (*model.Location, error) {
+	var location model.Location
+	if err := r.db.First(&location, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (r *locationRepo) FindByCode(code string) (*model.Location, error) {
+	var location model.Location
+	if err := r.db.First(&location, "code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (r *locationRepo) FindDefault() (*model.Location, error) {
+	var location model.Location
+	if err := r.db.First(&location, "is_default = ?", true).Error; err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+// SeedDefault creates the default location if there is none yet
+func (r *locationRepo) SeedDefault() (*model.Location, error) {
+	if location, err := r.FindDefault(); err == nil {
+		return location, nil
+	}
+
+	location := &model.Location{
+		Code:      model.DefaultLocationCode,
+		Name:      "Main Location",
+		Type:      model.LocationStore,
+		IsDefault: true,
+		IsActive:  true,
+	}
+	location.CreatedBy = "system"
+	location.UpdatedBy = "system"
+	if err := r.db.Create(location).Error; err != nil {
+		return nil, err
+	}
+	return location, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"time"
+
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LotRepository manages lots of lot-tracked products. Lock lots after the product
+// and its balance (see StockRepository).
+type LotRepository interface {
+	LockLot(tx *gorm.DB, productID, locationID uuid.UUID, lotNumber string, expiry *time.Time) (*model.Lot, error)
+	LockAvailable(tx *gorm.DB, productID, locationID uuid.UUID) ([]model.Lot, error)
+	LockByIDs(tx *gorm.DB, ids []uuid.UUID) ([]model.Lot, error)
+	SetQuantity(tx *gorm.DB, lotID uuid.UUID, quantity decimal.Decimal) error
+	CreateTransactionLots(tx *gorm.DB, lots []model.TransactionLot) error
+	CreateTransferLineLots(tx *gorm.DB, lots []model.TransferLineLot) error
+	SaveTransferLineLot(tx *gorm.DB, lot *model.TransferLineLot) error
+	FindByProduct(productID uuid.UUID, includeEmpty bool) ([]model.Lot, error)
+	FindExpiring(before time.Time, locationID *uuid.UUID) ([]model.Lot, error)
+}
+
+type lotRepo struct {
+	db *gorm.DB
+}
+
+func NewLotRepo(db *gorm.DB) LotRepository {
+	return &lotRepo{db}
+}
+
+// LockLot returns the lot locked FOR UPDATE, creating it (empty) if it doesn't exist yet
+func (r *lotRepo) LockLot(tx *gorm.DB, productID, locationID uuid.UUID, lotNumber string, expiry *time.Time) (*model.Lot, error) {
+	lot := &model.Lot{
+		ProductID:  productID,
+		LocationID: locationID,
+		LotNumber:  lotNumber,
+		ExpiryDate: expiry,
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "location_id"}, {Name: "lot_number"}},
+		DoNothing: true,
+	}).Create(lot).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var locked model.Lot
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&locked, "product_id = ? AND location_id = ? AND lot_number = ?", productID, locationID, lotNumber).Error
+	if err != nil {
+		return nil, err
+	}
+	return &locked, nil
+}
+
+// LockAvailable locks the lots holding stock, first-expired-first-out
+// (lots without expiry last, then oldest first)
+func (r *lotRepo) LockAvailable(tx *gorm.DB, productID, locationID uuid.UUID) ([]model.Lot, error) {
+	var lots []model.Lot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND location_id = ? AND quantity > 0", productID, locationID).
+		Order("expiry_date ASC NULLS LAST, created_at ASC, id ASC").
+		Find(&lots).Error
+	return lots, err
+}
+
+// LockByIDs locks the given lots, in ID order
+func (r *lotRepo) LockByIDs(tx *gorm.DB, ids []uuid.UUID) ([]model.Lot, error) {
+	var lots []model.Lot
+	if len(ids) == 0 {
+		return lots, nil
+	}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).
+		Order("id ASC").
+		Find(&lots).Error
+	return lots, err
+}
+
+func (r *lotRepo) SetQuantity(tx *gorm.DB, lotID uuid.UUID, quantity decimal.Decimal) error {
+	return tx.Model(&model.Lot{}).Where("id = ?", lotID).Updates(map[string]interface{}{
+		"quantity":   quantity,
+		"updated_at": gorm.Expr("NOW()"),
+	}).Error
+}
+
+func (r *lotRepo) CreateTransactionLots(tx *gorm.DB, lots []model.TransactionLot) error {
+	if len(lots) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).Create(&lots).Error
+}
+
+func (r *lotRepo) CreateTransferLineLots(tx *gorm.DB, lots []model.TransferLineLot) error {
+	if len(lots) == 0 {
+		return nil
+	}
+	return tx.Create(&lots).Error
+}
+
+func (r *lotRepo) SaveTransferLineLot(tx *gorm.DB, lot *model.TransferLineLot) error {
+	return tx.Save(lot).Error
+}
+
+func (r *lotRepo) FindByProduct(productID uuid.UUID, includeEmpty bool) ([]model.Lot, error) {
+	var lots []model.Lot
+	query := r.db.Preload("Location").Where("product_id = ?", productID)
+	if !includeEmpty {
+		query = query.Where("quantity > 0")
+	}
+	err := query.Order("expiry_date ASC NULLS LAST, created_at ASC").Find(&lots).Error
+	return lots, err
+}
+
+// FindExpiring returns lots with stock expiring before the given date (expired ones included)
+func (r *lotRepo) FindExpiring(before time.Time, locationID *uuid.UUID) ([]model.Lot, error) {
+	var lots []model.Lot
+	query := r.db.Preload("Product").Preload("Location").
+		Where("quantity > 0 AND expiry_date IS NOT NULL AND expiry_date < ?", before)
+	if locationID != nil {
+		query = query.Where("location_id = ?", *locationID)
+	}
+	err := query.Order("expiry_date ASC, lot_number ASC").Find(&lots).Error
+	return lots, err
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"time"
+
+	"go-inventory-ws/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository interface {
+	Enqueue(tx *gorm.DB, event *model.OutboxEvent) error
+	ClaimPending(tx *gorm.DB, limit int) ([]model.OutboxEvent, error)
+	MarkDispatched(tx *gorm.DB, ids []int64) error
+	DeleteDispatchedBefore(cutoff time.Time) (int64, error)
+}
+
+type outboxRepo struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepo(db *gorm.DB) OutboxRepository {
+	return &outboxRepo{db}
+}
+
+// Enqueue menerima *gorm.DB (tx) agar event ikut commit/rollback bersama perubahan data
+func (r *outboxRepo) Enqueue(tx *gorm.DB, event *model.OutboxEvent) error {
+	return tx.Create(event).Error
+}
+
+// ClaimPending locks the oldest undispatched events. SKIP LOCKED lets several
+// dispatchers (or instances) work the table without relaying an event twice.
+func (r *outboxRepo) ClaimPending(tx *gorm.DB, limit int) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("dispatched_at IS NULL").
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *outboxRepo) MarkDispatched(tx *gorm.DB, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Model(&model.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Update("dispatched_at", gorm.Expr("NOW()")).Error
+}
+
+func (r *outboxRepo) DeleteDispatchedBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("dispatched_at IS NOT NULL AND dispatched_at < ?", cutoff).
+		Delete(&model.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
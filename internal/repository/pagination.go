@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// Pagination is an offset based page request (page starts at 1)
+type Pagination struct {
+	Page  int
+	Limit int
+}
+
+// Normalize applies defaults and caps the page size
+func (p *Pagination) Normalize() {
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	p.Limit = pageLimit(p.Limit)
+}
+
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// pageLimit applies the default and maximum page size to a keyset page
+func pageLimit(limit int) int {
+	if limit < 1 {
+		return DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
+
+// Cursor is a keyset pagination position: the (created_at, id) of the last row of a page
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode returns the opaque string handed to clients as next_cursor
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: createdAt, ID: uid}, nil
+}
+
+// containsPattern builds an ILIKE pattern matching s anywhere, with LIKE wildcards escaped
+func containsPattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
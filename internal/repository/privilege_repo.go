@@ -11,7 +11,7 @@ type PrivilegeRepository interface {
 	FindByCodes(codes []string) ([]model.Privilege, error)
 	FindAll() ([]model.Privilege, error)
 	Create(privilege *model.Privilege) error
-	SeedDefaults() error
+	SeedDefaults() ([]model.Privilege, error)
 }
 
 type privilegeRepo struct {
@@ -50,15 +50,17 @@ func (r *privilegeRepo) Create(privilege *model.Privilege) error {
 	return r.db.Create(privilege).Error
 }
 
-// SeedDefaults creates default privileges if they don't exist
-func (r *privilegeRepo) SeedDefaults() error {
+// SeedDefaults creates default privileges if they don't exist and returns the ones it created
+func (r *privilegeRepo) SeedDefaults() ([]model.Privilege, error) {
+	var created []model.Privilege
 	for _, p := range model.DefaultPrivileges {
 		var existing model.Privilege
 		if err := r.db.Where("code = ?", p.Code).First(&existing).Error; err == gorm.ErrRecordNotFound {
 			if err := r.db.Create(&p).Error; err != nil {
-				return err
+				return created, err
 			}
+			created = append(created, p)
 		}
 	}
-	return nil
+	return created, nil
 }
@@ -2,18 +2,49 @@ package repository
 
 import (
 	"go-inventory-ws/internal/model"
+	"go-inventory-ws/pkg/decimal"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductRepository interface {
 	Create(product *model.Product) error
-	FindAll() ([]model.Product, error)
+	FindAll(filter ProductFilter) ([]model.Product, int64, error)
 	FindByID(id uuid.UUID) (*model.Product, error)
 	FindBySKU(sku string) (*model.Product, error)
+	FindLowStock() ([]model.Product, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID, includeDeleted bool) (*model.Product, error)
 	Update(product *model.Product) error
-	UpdateStock(tx *gorm.DB, id uuid.UUID, newStock int, updatedBy string) error
+	UpdateStock(tx *gorm.DB, id uuid.UUID, newStock decimal.Decimal, updatedBy string) error
+	SoftDelete(tx *gorm.DB, id uuid.UUID, deletedBy string) error
+	SetArchived(tx *gorm.DB, id uuid.UUID, archived bool, updatedBy string) error
+	Restore(tx *gorm.DB, id uuid.UUID, updatedBy string) error
+}
+
+// ProductFilter narrows down GET /products. Zero values mean "no filter".
+type ProductFilter struct {
+	Pagination
+	Search          string // Matches SKU or name, case-insensitive
+	Unit            string
+	StockBelow      *decimal.Decimal // stock < StockBelow
+	MinPrice        *int64
+	MaxPrice        *int64
+	CreatedBy       string // User ID
+	IncludeArchived bool
+	SortBy          string // One of ProductSortColumns, default created_at
+	SortDesc        bool
+}
+
+// ProductSortColumns are the columns GET /products can be sorted by
+var ProductSortColumns = map[string]bool{
+	"name":       true,
+	"sku":        true,
+	"stock":      true,
+	"price":      true,
+	"created_at": true,
+	"updated_at": true,
 }
 
 type productRepo struct {
@@ -28,10 +59,59 @@ func (r *productRepo) Create(product *model.Product) error {
 	return r.db.Create(product).Error
 }
 
-func (r *productRepo) FindAll() ([]model.Product, error) {
+// FindAll returns one page of products matching the filter and the total number of matches
+func (r *productRepo) FindAll(filter ProductFilter) ([]model.Product, int64, error) {
+	filter.Normalize()
+
+	query := r.db.Model(&model.Product{})
+	if !filter.IncludeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	if filter.Search != "" {
+		pattern := containsPattern(filter.Search)
+		query = query.Where("(sku ILIKE ? OR name ILIKE ?)", pattern, pattern)
+	}
+	if filter.Unit != "" {
+		query = query.Where("unit = ?", filter.Unit)
+	}
+	if filter.StockBelow != nil {
+		query = query.Where("stock < ?", *filter.StockBelow)
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.CreatedBy != "" {
+		query = query.Where("created_by_user_id = ?", filter.CreatedBy)
+	}
+
+	// New session so the conditions can be reused by both Count and Find
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	sortBy := filter.SortBy
+	if !ProductSortColumns[sortBy] {
+		sortBy = "created_at"
+	}
+	// id as tie-breaker keeps pages stable when many rows share the sort value
+	order := clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: clause.Column{Name: sortBy}, Desc: filter.SortDesc},
+		{Column: clause.Column{Name: "id"}, Desc: filter.SortDesc},
+	}}
+
 	var products []model.Product
-	err := r.db.Preload("CreatedByUser").Preload("UpdatedByUser").Find(&products).Error
-	return products, err
+	err := query.Preload("CreatedByUser").Preload("UpdatedByUser").
+		Order(order).
+		Offset(filter.Offset()).
+		Limit(filter.Limit).
+		Find(&products).Error
+	return products, total, err
 }
 
 func (r *productRepo) FindByID(id uuid.UUID) (*model.Product, error) {
@@ -40,18 +120,41 @@ func (r *productRepo) FindByID(id uuid.UUID) (*model.Product, error) {
 	return &product, err
 }
 
+// FindBySKU includes soft-deleted products, their SKU stays reserved by the unique index
 func (r *productRepo) FindBySKU(sku string) (*model.Product, error) {
 	var product model.Product
-	err := r.db.First(&product, "sku = ?", sku).Error
+	err := r.db.Unscoped().First(&product, "sku = ?", sku).Error
 	return &product, err
 }
 
+// FindLowStock returns active products at or below their reorder point, most urgent first
+func (r *productRepo) FindLowStock() ([]model.Product, error) {
+	var products []model.Product
+	err := r.db.Where("archived_at IS NULL AND stock <= reorder_point").
+		Order("stock - reorder_point ASC, name ASC").
+		Find(&products).Error
+	return products, err
+}
+
+// FindByIDForUpdate loads and locks a product row (SELECT ... FOR UPDATE) inside tx
+func (r *productRepo) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID, includeDeleted bool) (*model.Product, error) {
+	var product model.Product
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	if includeDeleted {
+		query = query.Unscoped()
+	}
+	if err := query.First(&product, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
 func (r *productRepo) Update(product *model.Product) error {
 	return r.db.Save(product).Error
 }
 
 // UpdateStock menerima *gorm.DB (tx) agar bisa berjalan dalam transaksi
-func (r *productRepo) UpdateStock(tx *gorm.DB, id uuid.UUID, newStock int, updatedBy string) error {
+func (r *productRepo) UpdateStock(tx *gorm.DB, id uuid.UUID, newStock decimal.Decimal, updatedBy string) error {
 	return tx.Model(&model.Product{}).
 		Where("id = ?", id).
 		Updates(map[string]interface{}{
@@ -59,3 +162,35 @@ func (r *productRepo) UpdateStock(tx *gorm.DB, id uuid.UUID, newStock int, updat
 			"updated_by": updatedBy,
 		}).Error
 }
+
+// SoftDelete fills DeletedBy together with DeletedAt
+func (r *productRepo) SoftDelete(tx *gorm.DB, id uuid.UUID, deletedBy string) error {
+	return tx.Model(&model.Product{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"deleted_at": gorm.Expr("NOW()"),
+		"deleted_by": deletedBy,
+	}).Error
+}
+
+func (r *productRepo) SetArchived(tx *gorm.DB, id uuid.UUID, archived bool, updatedBy string) error {
+	updates := map[string]interface{}{
+		"archived_at": nil,
+		"archived_by": "",
+		"updated_by":  updatedBy,
+	}
+	if archived {
+		updates["archived_at"] = gorm.Expr("NOW()")
+		updates["archived_by"] = updatedBy
+	}
+	return tx.Model(&model.Product{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Restore brings back a soft-deleted and/or archived product
+func (r *productRepo) Restore(tx *gorm.DB, id uuid.UUID, updatedBy string) error {
+	return tx.Unscoped().Model(&model.Product{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"deleted_at":  nil,
+		"deleted_by":  "",
+		"archived_at": nil,
+		"archived_by": "",
+		"updated_by":  updatedBy,
+	}).Error
+}
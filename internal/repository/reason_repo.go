@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"strings"
+
+	"go-inventory-ws/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReasonRepository interface {
+	FindAll(txType model.TransactionType, includeInactive bool) ([]model.ReasonCode, error)
+	FindByCode(code string) (*model.ReasonCode, error)
+	Create(reason *model.ReasonCode) error
+	Update(reason *model.ReasonCode) error
+	SeedDefaults(defaults []model.ReasonCode) error
+}
+
+type reasonRepo struct {
+	db *gorm.DB
+}
+
+func NewReasonRepo(db *gorm.DB) ReasonRepository {
+	return &reasonRepo{db}
+}
+
+// FindAll lists the catalog, txType empty = every type
+func (r *reasonRepo) FindAll(txType model.TransactionType, includeInactive bool) ([]model.ReasonCode, error) {
+	var reasons []model.ReasonCode
+	query := r.db.Order("type ASC, code ASC")
+	if txType != "" {
+		query = query.Where("type = ?", txType)
+	}
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Find(&reasons).Error
+	return reasons, err
+}
+
+func (r *reasonRepo) FindByCode(code string) (*model.ReasonCode, error) {
+	var reason model.ReasonCode
+	if err := r.db.First(&reason, "code = ?", strings.ToUpper(code)).Error; err != nil {
+		return nil, err
+	}
+	return &reason, nil
+}
+
+func (r *reasonRepo) Create(reason *model.ReasonCode) error {
+	return r.db.Create(reason).Error
+}
+
+func (r *reasonRepo) Update(reason *model.ReasonCode) error {
+	return r.db.Save(reason).Error
+}
+
+// SeedDefaults adds the default reason codes, existing codes are left as they are
+func (r *reasonRepo) SeedDefaults(defaults []model.ReasonCode) error {
+	reasons := append([]model.ReasonCode(nil), defaults...)
+	for i := range reasons {
+		reasons[i].IsActive = true
+		reasons[i].CreatedBy = "system"
+		reasons[i].UpdatedBy = "system"
+		err := r.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "code"}},
+			DoNothing: true,
+		}).Create(&reasons[i]).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"time"
+
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReservationRepository manages stock reservations. Create reservations and check
+// availability with the balance locked (see StockRepository), so concurrent
+// reservations and OUT transactions can't promise the same stock twice.
+type ReservationRepository interface {
+	Create(tx *gorm.DB, reservation *model.Reservation) error
+	FindAll(filter ReservationFilter) ([]model.Reservation, int64, error)
+	FindByID(id uuid.UUID) (*model.Reservation, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.Reservation, error)
+	Save(tx *gorm.DB, reservation *model.Reservation) error
+	// ReservedQuantity is the outstanding quantity of active, unexpired reservations of a
+	// product at a location, leaving out one reservation when exclude is set
+	ReservedQuantity(tx *gorm.DB, productID, locationID uuid.UUID, exclude *uuid.UUID) (decimal.Decimal, error)
+	// ReservedByLocation is ReservedQuantity for every location of a product
+	ReservedByLocation(productID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error)
+	// ExpireDue marks up to limit active reservations past their expiry as EXPIRED and
+	// returns them. Rows locked by another transaction are skipped.
+	ExpireDue(tx *gorm.DB, now time.Time, limit int) ([]model.Reservation, error)
+}
+
+// ReservationFilter narrows down GET /reservations. Zero values mean "no filter".
+type ReservationFilter struct {
+	Pagination
+	Status     model.ReservationStatus
+	ProductID  *uuid.UUID
+	LocationID *uuid.UUID
+	Reference  string
+}
+
+type reservationRepo struct {
+	db *gorm.DB
+}
+
+func NewReservationRepo(db *gorm.DB) ReservationRepository {
+	return &reservationRepo{db}
+}
+
+func (r *reservationRepo) Create(tx *gorm.DB, reservation *model.Reservation) error {
+	return tx.Omit(clause.Associations).Create(reservation).Error
+}
+
+func (r *reservationRepo) FindAll(filter ReservationFilter) ([]model.Reservation, int64, error) {
+	filter.Normalize()
+
+	query := r.db.Model(&model.Reservation{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ProductID != nil {
+		query = query.Where("product_id = ?", *filter.ProductID)
+	}
+	if filter.LocationID != nil {
+		query = query.Where("location_id = ?", *filter.LocationID)
+	}
+	if filter.Reference != "" {
+		query = query.Where("reference = ?", filter.Reference)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reservations []model.Reservation
+	err := query.Preload("Product", unscoped).Preload("Location").
+		Order("created_at DESC, id DESC").
+		Offset(filter.Offset()).
+		Limit(filter.Limit).
+		Find(&reservations).Error
+	return reservations, total, err
+}
+
+func (r *reservationRepo) FindByID(id uuid.UUID) (*model.Reservation, error) {
+	var reservation model.Reservation
+	err := r.db.Preload("Product", unscoped).Preload("Location").First(&reservation, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (r *reservationRepo) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.Reservation, error) {
+	var reservation model.Reservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (r *reservationRepo) Save(tx *gorm.DB, reservation *model.Reservation) error {
+	return tx.Omit(clause.Associations).Save(reservation).Error
+}
+
+func (r *reservationRepo) ReservedQuantity(tx *gorm.DB, productID, locationID uuid.UUID, exclude *uuid.UUID) (decimal.Decimal, error) {
+	query := holding(tx.Model(&model.Reservation{})).
+		Where("product_id = ? AND location_id = ?", productID, locationID)
+	if exclude != nil {
+		query = query.Where("id <> ?", *exclude)
+	}
+
+	var reserved decimal.Decimal
+	err := query.Select("COALESCE(SUM(quantity - fulfilled_qty), 0)").Scan(&reserved).Error
+	return reserved, err
+}
+
+func (r *reservationRepo) ReservedByLocation(productID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	var rows []struct {
+		LocationID uuid.UUID
+		Reserved   decimal.Decimal
+	}
+	err := holding(r.db.Model(&model.Reservation{})).
+		Select("location_id, SUM(quantity - fulfilled_qty) AS reserved").
+		Where("product_id = ?", productID).
+		Group("location_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	reserved := make(map[uuid.UUID]decimal.Decimal, len(rows))
+	for _, row := range rows {
+		reserved[row.LocationID] = row.Reserved
+	}
+	return reserved, nil
+}
+
+// holding keeps the reservations still holding stock. Expired ones stop counting right
+// away, whether or not the expiry job has marked them yet.
+func holding(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ? AND expires_at > NOW()", model.ReservationActive)
+}
+
+func (r *reservationRepo) ExpireDue(tx *gorm.DB, now time.Time, limit int) ([]model.Reservation, error) {
+	var expired []model.Reservation
+	err := tx.Raw(`
+		UPDATE reservations SET status = ?, released_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM reservations
+			WHERE status = ? AND expires_at <= ? AND deleted_at IS NULL
+			ORDER BY expires_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, model.ReservationExpired, now, now, model.ReservationActive, now, limit).Scan(&expired).Error
+	return expired, err
+}
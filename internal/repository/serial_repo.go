@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"sort"
+
+	"go-inventory-ws/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SerialRepository manages the units of serialized products. Lock serials last,
+// after the product, its balances and lots.
+type SerialRepository interface {
+	LockSerials(tx *gorm.DB, productID uuid.UUID, serials []string) ([]model.SerialNumber, error)
+	Create(tx *gorm.DB, serials []model.SerialNumber) error
+	SetStatus(tx *gorm.DB, ids []uuid.UUID, status model.SerialStatus, locationID uuid.UUID) error
+	CreateTransactionSerials(tx *gorm.DB, serials []model.TransactionSerial) error
+	CreateTransferLineSerials(tx *gorm.DB, serials []model.TransferLineSerial) error
+	SaveTransferLineSerial(tx *gorm.DB, serial *model.TransferLineSerial) error
+	FindByProduct(productID uuid.UUID, status model.SerialStatus, locationID *uuid.UUID) ([]model.SerialNumber, error)
+	FindBySerial(serial string) ([]model.SerialNumber, error)
+	FindTransactions(serialID uuid.UUID) ([]model.Transaction, error)
+}
+
+type serialRepo struct {
+	db *gorm.DB
+}
+
+func NewSerialRepo(db *gorm.DB) SerialRepository {
+	return &serialRepo{db}
+}
+
+// LockSerials locks the existing units among the given serial numbers FOR UPDATE,
+// in serial order so concurrent requests can't deadlock. Unknown serials are skipped.
+func (r *serialRepo) LockSerials(tx *gorm.DB, productID uuid.UUID, serials []string) ([]model.SerialNumber, error) {
+	sorted := append([]string(nil), serials...)
+	sort.Strings(sorted)
+
+	var units []model.SerialNumber
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND serial IN ?", productID, sorted).
+		Order("serial ASC").
+		Find(&units).Error
+	return units, err
+}
+
+func (r *serialRepo) Create(tx *gorm.DB, serials []model.SerialNumber) error {
+	if len(serials) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).Create(&serials).Error
+}
+
+func (r *serialRepo) SetStatus(tx *gorm.DB, ids []uuid.UUID, status model.SerialStatus, locationID uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Model(&model.SerialNumber{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":      status,
+		"location_id": locationID,
+		"updated_at":  gorm.Expr("NOW()"),
+	}).Error
+}
+
+func (r *serialRepo) CreateTransactionSerials(tx *gorm.DB, serials []model.TransactionSerial) error {
+	if len(serials) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).Create(&serials).Error
+}
+
+func (r *serialRepo) CreateTransferLineSerials(tx *gorm.DB, serials []model.TransferLineSerial) error {
+	if len(serials) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).Create(&serials).Error
+}
+
+func (r *serialRepo) SaveTransferLineSerial(tx *gorm.DB, serial *model.TransferLineSerial) error {
+	return tx.Omit(clause.Associations).Save(serial).Error
+}
+
+// FindByProduct lists a product's units, optionally by status and location
+func (r *serialRepo) FindByProduct(productID uuid.UUID, status model.SerialStatus, locationID *uuid.UUID) ([]model.SerialNumber, error) {
+	var units []model.SerialNumber
+	query := r.db.Preload("Location").Where("product_id = ?", productID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if locationID != nil {
+		query = query.Where("location_id = ?", *locationID)
+	}
+	err := query.Order("serial ASC").Find(&units).Error
+	return units, err
+}
+
+// FindBySerial returns the units with this serial number (one per product at most)
+func (r *serialRepo) FindBySerial(serial string) ([]model.SerialNumber, error) {
+	var units []model.SerialNumber
+	err := r.db.Preload("Product", unscoped).Preload("Location").
+		Where("serial = ?", serial).
+		Order("created_at ASC").
+		Find(&units).Error
+	return units, err
+}
+
+// FindTransactions returns the transactions that moved a unit, oldest first
+func (r *serialRepo) FindTransactions(serialID uuid.UUID) ([]model.Transaction, error) {
+	var transactions []model.Transaction
+	err := r.db.Preload("Location").Preload("CreatedByUser").
+		Joins("JOIN transaction_serials ts ON ts.transaction_id = transactions.id").
+		Where("ts.serial_id = ?", serialID).
+		Order("transactions.created_at ASC, transactions.id ASC").
+		Find(&transactions).Error
+	return transactions, err
+}
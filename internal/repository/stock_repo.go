@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StockRepository manages per-location stock balances. Writes take the caller's
+// transaction; lock the product row first, then its balances, to avoid deadlocks.
+type StockRepository interface {
+	FindByProduct(productID uuid.UUID) ([]model.StockBalance, error)
+	FindBalance(productID, locationID uuid.UUID) (*model.StockBalance, error)
+	LockBalance(tx *gorm.DB, productID, locationID uuid.UUID) (*model.StockBalance, error)
+	SetQuantity(tx *gorm.DB, productID, locationID uuid.UUID, quantity decimal.Decimal) error
+	SetInTransit(tx *gorm.DB, productID, locationID uuid.UUID, inTransit decimal.Decimal) error
+	SetReorderPoint(productID, locationID uuid.UUID, reorderPoint *decimal.Decimal) error
+	SyncProductStock(tx *gorm.DB, productID uuid.UUID, updatedBy string) (decimal.Decimal, error)
+	FindLowStockAt(locationID uuid.UUID) ([]LocationStock, error)
+	BackfillDefaultLocation(locationID uuid.UUID) error
+}
+
+// LocationStock is a balance joined with its product, for location-scoped reports
+type LocationStock struct {
+	model.Product
+	LocationQuantity     decimal.Decimal `gorm:"column:location_quantity"`
+	LocationReorderPoint decimal.Decimal `gorm:"column:location_reorder_point"` // Effective (override or product's)
+}
+
+type stockRepo struct {
+	db *gorm.DB
+}
+
+func NewStockRepo(db *gorm.DB) StockRepository {
+	return &stockRepo{db}
+}
+
+func (r *stockRepo) FindByProduct(productID uuid.UUID) ([]model.StockBalance, error) {
+	var balances []model.StockBalance
+	err := r.db.Preload("Location").
+		Where("product_id = ?", productID).
+		Order("location_id").
+		Find(&balances).Error
+	return balances, err
+}
+
+func (r *stockRepo) FindBalance(productID, locationID uuid.UUID) (*model.StockBalance, error) {
+	var balance model.StockBalance
+	err := r.db.First(&balance, "product_id = ? AND location_id = ?", productID, locationID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// LockBalance returns the balance row locked FOR UPDATE, creating an empty one first if needed
+func (r *stockRepo) LockBalance(tx *gorm.DB, productID, locationID uuid.UUID) (*model.StockBalance, error) {
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.StockBalance{
+		ProductID:  productID,
+		LocationID: locationID,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var balance model.StockBalance
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&balance, "product_id = ? AND location_id = ?", productID, locationID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+func (r *stockRepo) SetQuantity(tx *gorm.DB, productID, locationID uuid.UUID, quantity decimal.Decimal) error {
+	return tx.Model(&model.StockBalance{}).
+		Where("product_id = ? AND location_id = ?", productID, locationID).
+		Updates(map[string]interface{}{
+			"quantity":   quantity,
+			"updated_at": gorm.Expr("NOW()"),
+		}).Error
+}
+
+func (r *stockRepo) SetInTransit(tx *gorm.DB, productID, locationID uuid.UUID, inTransit decimal.Decimal) error {
+	return tx.Model(&model.StockBalance{}).
+		Where("product_id = ? AND location_id = ?", productID, locationID).
+		Updates(map[string]interface{}{
+			"in_transit": inTransit,
+			"updated_at": gorm.Expr("NOW()"),
+		}).Error
+}
+
+func (r *stockRepo) SetReorderPoint(productID, locationID uuid.UUID, reorderPoint *decimal.Decimal) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "location_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reorder_point", "updated_at"}),
+	}).Create(&model.StockBalance{
+		ProductID:    productID,
+		LocationID:   locationID,
+		ReorderPoint: reorderPoint,
+	}).Error
+}
+
+// SyncProductStock recomputes products.stock as the total of its balances and returns it
+func (r *stockRepo) SyncProductStock(tx *gorm.DB, productID uuid.UUID, updatedBy string) (decimal.Decimal, error) {
+	var total decimal.Decimal
+	err := tx.Raw(`
+		UPDATE products
+		SET stock = (SELECT COALESCE(SUM(quantity + in_transit), 0) FROM stock_balances WHERE product_id = ?),
+			updated_by = ?, updated_at = NOW()
+		WHERE id = ?
+		RETURNING stock`, productID, updatedBy, productID).Scan(&total).Error
+	return total, err
+}
+
+// FindLowStockAt returns active products at or below their reorder point at a location
+func (r *stockRepo) FindLowStockAt(locationID uuid.UUID) ([]LocationStock, error) {
+	var rows []LocationStock
+	err := r.db.Table("stock_balances b").
+		Select("p.*, b.quantity AS location_quantity, COALESCE(b.reorder_point, p.reorder_point) AS location_reorder_point").
+		Joins("JOIN products p ON p.id = b.product_id").
+		Where("b.location_id = ? AND p.deleted_at IS NULL AND p.archived_at IS NULL", locationID).
+		Where("b.quantity <= COALESCE(b.reorder_point, p.reorder_point)").
+		Order("b.quantity - COALESCE(b.reorder_point, p.reorder_point) ASC, p.name ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// BackfillDefaultLocation moves stock and transactions that predate locations to
+// the default location: products without any balance get one holding their stock,
+// transactions without a location are assigned to it
+func (r *stockRepo) BackfillDefaultLocation(locationID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO stock_balances (product_id, location_id, quantity, updated_at)
+			SELECT p.id, ?, p.stock, NOW() FROM products p
+			WHERE NOT EXISTS (SELECT 1 FROM stock_balances b WHERE b.product_id = p.id)`, locationID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE transactions SET location_id = ? WHERE location_id IS NULL", locationID).Error
+	})
+}
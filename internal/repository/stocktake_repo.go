@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"time"
+
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StocktakeRepository manages stocktake sessions. Counts lock the session row
+// FOR SHARE, so several users can count at once while approval (FOR UPDATE) waits
+// for the counts in flight.
+type StocktakeRepository interface {
+	Create(tx *gorm.DB, stocktake *model.Stocktake) error
+	FindAll(filter StocktakeFilter) ([]model.Stocktake, int64, error)
+	FindByID(id uuid.UUID) (*model.Stocktake, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.Stocktake, error)
+	FindByIDForShare(tx *gorm.DB, id uuid.UUID) (*model.Stocktake, error)
+	FindOpenAt(locationID uuid.UUID) (*model.Stocktake, error)
+	// SnapshotLines returns the lines of a new stocktake with the current balances at the
+	// location: the given products, or every product holding a balance there
+	SnapshotLines(tx *gorm.DB, locationID uuid.UUID, productIDs []uuid.UUID) ([]model.StocktakeLine, error)
+	// ExcludedAt returns the products holding a balance at the location that
+	// SnapshotLines leaves out of a location-wide stocktake
+	ExcludedAt(tx *gorm.DB, locationID uuid.UUID) ([]model.StocktakeExclusion, error)
+	RecordCount(tx *gorm.DB, stocktakeID, productID uuid.UUID, quantity decimal.Decimal, add bool, countedBy string) (*model.StocktakeLine, error)
+	Progress(tx *gorm.DB, id uuid.UUID) (model.StocktakeProgress, error)
+	Save(tx *gorm.DB, stocktake *model.Stocktake) error
+	SaveLine(tx *gorm.DB, line *model.StocktakeLine) error
+}
+
+// StocktakeFilter narrows down GET /stocktakes. Zero values mean "no filter".
+type StocktakeFilter struct {
+	Pagination
+	Status     model.StocktakeStatus
+	LocationID *uuid.UUID
+}
+
+type stocktakeRepo struct {
+	db *gorm.DB
+}
+
+func NewStocktakeRepo(db *gorm.DB) StocktakeRepository {
+	return &stocktakeRepo{db}
+}
+
+// Create inserts the stocktake and its lines
+func (r *stocktakeRepo) Create(tx *gorm.DB, stocktake *model.Stocktake) error {
+	return tx.Create(stocktake).Error
+}
+
+func (r *stocktakeRepo) FindAll(filter StocktakeFilter) ([]model.Stocktake, int64, error) {
+	filter.Normalize()
+
+	query := r.db.Model(&model.Stocktake{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.LocationID != nil {
+		query = query.Where("location_id = ?", *filter.LocationID)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var stocktakes []model.Stocktake
+	err := query.Preload("Location").
+		Order("created_at DESC, id DESC").
+		Offset(filter.Offset()).
+		Limit(filter.Limit).
+		Find(&stocktakes).Error
+	if err != nil || len(stocktakes) == 0 {
+		return stocktakes, total, err
+	}
+
+	// Progress of the whole page in one query
+	ids := make([]uuid.UUID, len(stocktakes))
+	for i, st := range stocktakes {
+		ids[i] = st.ID
+	}
+	var rows []struct {
+		StocktakeID uuid.UUID
+		Counted     int
+		Total       int
+	}
+	err = r.db.Model(&model.StocktakeLine{}).
+		Select("stocktake_id, COUNT(counted_qty) AS counted, COUNT(*) AS total").
+		Where("stocktake_id IN ?", ids).
+		Group("stocktake_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	progress := make(map[uuid.UUID]model.StocktakeProgress, len(rows))
+	for _, row := range rows {
+		progress[row.StocktakeID] = model.StocktakeProgress{Counted: row.Counted, Total: row.Total}
+	}
+	for i := range stocktakes {
+		p := progress[stocktakes[i].ID]
+		stocktakes[i].Progress = &p
+	}
+	return stocktakes, total, nil
+}
+
+func (r *stocktakeRepo) FindByID(id uuid.UUID) (*model.Stocktake, error) {
+	var stocktake model.Stocktake
+	err := r.db.Preload("Location").Preload("Lines", lineOrder).Preload("Lines.Product", unscoped).
+		First(&stocktake, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stocktake, nil
+}
+
+// FindByIDForUpdate locks the stocktake row and loads its lines, for approval and cancellation
+func (r *stocktakeRepo) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.Stocktake, error) {
+	var stocktake model.Stocktake
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&stocktake, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Where("stocktake_id = ?", id).Order("product_id").Find(&stocktake.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &stocktake, nil
+}
+
+// FindByIDForShare locks the stocktake row against status changes, without its lines
+func (r *stocktakeRepo) FindByIDForShare(tx *gorm.DB, id uuid.UUID) (*model.Stocktake, error) {
+	var stocktake model.Stocktake
+	err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+		First(&stocktake, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stocktake, nil
+}
+
+// FindOpenAt returns the open stocktake of a location, there is at most one
+func (r *stocktakeRepo) FindOpenAt(locationID uuid.UUID) (*model.Stocktake, error) {
+	var stocktake model.Stocktake
+	err := r.db.First(&stocktake, "location_id = ? AND status = ?", locationID, model.StocktakeOpen).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stocktake, nil
+}
+
+// lineOrder lists stocktake lines by product name, the order counters walk the shelves in
+func lineOrder(db *gorm.DB) *gorm.DB {
+	return db.Select("stocktake_lines.*").
+		Joins("JOIN products ON products.id = stocktake_lines.product_id").
+		Order("products.name ASC, stocktake_lines.id ASC")
+}
+
+// SnapshotLines reads every balance in one statement, so the expected quantities are
+// consistent with each other. Lot-tracked and serialized products are left out.
+func (r *stocktakeRepo) SnapshotLines(tx *gorm.DB, locationID uuid.UUID, productIDs []uuid.UUID) ([]model.StocktakeLine, error) {
+	query := tx.Table("products p").
+		Select("p.id AS product_id, COALESCE(b.quantity, 0) AS expected_qty").
+		Where("p.deleted_at IS NULL AND p.archived_at IS NULL AND p.track_lots = false AND p.serialized = false")
+	if len(productIDs) > 0 {
+		query = query.Joins("LEFT JOIN stock_balances b ON b.product_id = p.id AND b.location_id = ?", locationID).
+			Where("p.id IN ?", productIDs)
+	} else {
+		query = query.Joins("JOIN stock_balances b ON b.product_id = p.id AND b.location_id = ?", locationID)
+	}
+
+	var lines []model.StocktakeLine
+	err := query.Order("p.name ASC, p.id ASC").Scan(&lines).Error
+	return lines, err
+}
+
+func (r *stocktakeRepo) ExcludedAt(tx *gorm.DB, locationID uuid.UUID) ([]model.StocktakeExclusion, error) {
+	var excluded []model.StocktakeExclusion
+	err := tx.Table("products p").
+		Select("p.id AS product_id, p.name, p.sku, CASE WHEN p.serialized THEN 'serialized' ELSE 'lot_tracked' END AS reason").
+		Joins("JOIN stock_balances b ON b.product_id = p.id AND b.location_id = ?", locationID).
+		Where("p.deleted_at IS NULL AND p.archived_at IS NULL AND (p.track_lots = true OR p.serialized = true)").
+		Order("p.name ASC, p.id ASC").
+		Scan(&excluded).Error
+	return excluded, err
+}
+
+// RecordCount sets the counted quantity of a line, or adds to it (several people
+// counting the same product on different shelves). The update is a single statement,
+// so concurrent additions are never lost.
+func (r *stocktakeRepo) RecordCount(tx *gorm.DB, stocktakeID, productID uuid.UUID, quantity decimal.Decimal, add bool, countedBy string) (*model.StocktakeLine, error) {
+	counted := gorm.Expr("?::numeric", quantity)
+	if add {
+		counted = gorm.Expr("COALESCE(counted_qty, 0) + ?::numeric", quantity)
+	}
+
+	var lines []model.StocktakeLine
+	result := tx.Model(&lines).Clauses(clause.Returning{}).
+		Where("stocktake_id = ? AND product_id = ?", stocktakeID, productID).
+		Updates(map[string]interface{}{
+			"counted_qty": counted,
+			"counted_by":  countedBy,
+			"counted_at":  time.Now(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	line := &lines[0]
+	if err := line.AfterFind(tx); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+func (r *stocktakeRepo) Progress(tx *gorm.DB, id uuid.UUID) (model.StocktakeProgress, error) {
+	var progress model.StocktakeProgress
+	err := tx.Model(&model.StocktakeLine{}).
+		Select("COUNT(counted_qty) AS counted, COUNT(*) AS total").
+		Where("stocktake_id = ?", id).
+		Scan(&progress).Error
+	return progress, err
+}
+
+// Save updates the stocktake header only, lines are saved with SaveLine
+func (r *stocktakeRepo) Save(tx *gorm.DB, stocktake *model.Stocktake) error {
+	return tx.Omit(clause.Associations).Save(stocktake).Error
+}
+
+func (r *stocktakeRepo) SaveLine(tx *gorm.DB, line *model.StocktakeLine) error {
+	return tx.Omit(clause.Associations).Save(line).Error
+}
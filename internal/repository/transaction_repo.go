@@ -1,27 +1,42 @@
 package repository
 
 import (
+	"strings"
 	"time"
 
 	"go-inventory-ws/internal/model"
+	"go-inventory-ws/pkg/decimal"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TransactionRepository interface {
-	GetStockMovement(startDate, endDate time.Time) ([]StockMovementData, error)
-	GetDashboardStats() (*DashboardStats, error)
-	FindAll() ([]model.Transaction, error)
+	// locationID nil = all locations
+	GetStockMovement(startDate, endDate time.Time, locationID *uuid.UUID) ([]StockMovementData, error)
+	GetDashboardStats(locationID *uuid.UUID) (*DashboardStats, error)
+	FindAll(filter TransactionFilter) ([]model.Transaction, *Cursor, error)
+	FindLedger(productID uuid.UUID, locationID *uuid.UUID, after *Cursor, limit int) ([]LedgerEntry, *Cursor, error)
 	FindByID(id uuid.UUID) (*model.Transaction, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.Transaction, error)
+	MarkVoided(tx *gorm.DB, id, reversalID uuid.UUID, reason, voidedBy string) error
 	GetFinancialSummary(startDate, endDate time.Time) (int64, int64, error)
+	CreateDocument(tx *gorm.DB, document *model.TransactionDocument) error
+	SetDocumentTotal(tx *gorm.DB, id uuid.UUID, total int64) error
+	// RecomputeDocumentTotal sets the document total to the sum of its lines not voided
+	RecomputeDocumentTotal(tx *gorm.DB, id uuid.UUID) error
+	FindDocuments(filter DocumentFilter) ([]model.TransactionDocument, int64, error)
+	FindDocumentByID(id uuid.UUID) (*model.TransactionDocument, error)
+	GetShrinkage(startDate, endDate time.Time, locationID *uuid.UUID) ([]ShrinkageByReason, []ShrinkageByProduct, error)
+	BackfillDirections() error
 }
 
 // StockMovementData untuk chart data
 type StockMovementData struct {
-	Date     string `json:"date"`
-	Inbound  int    `json:"inbound"`
-	Outbound int    `json:"outbound"`
+	Date     string          `json:"date"`
+	Inbound  decimal.Decimal `json:"inbound"`
+	Outbound decimal.Decimal `json:"outbound"`
 }
 
 // DashboardStats untuk overview stats
@@ -31,6 +46,60 @@ type DashboardStats struct {
 	TotalValuation int64 `json:"total_valuation"`
 }
 
+// TransactionFilter narrows down GET /transactions. Zero values mean "no filter".
+type TransactionFilter struct {
+	From          *time.Time
+	To            *time.Time
+	ProductID     *uuid.UUID
+	LocationID    *uuid.UUID
+	Type          model.TransactionType
+	ReasonCode    string
+	PaymentMethod string
+	UserID        string  // Creator
+	ExcludeVoided bool    // Hide voided transactions and their reversals
+	After         *Cursor // Keyset position, nil for the first page
+	Limit         int
+}
+
+// ShrinkageByReason sums the ADJUSTMENT transactions of one reason code. Values are
+// the price snapshots of the transactions (whole currency units).
+type ShrinkageByReason struct {
+	ReasonCode   string `json:"reason_code"`
+	ReasonName   string `json:"reason_name"`
+	Transactions int64  `json:"transactions"`
+	OutValue     int64  `json:"out_value"` // Written off (damage, theft...)
+	InValue      int64  `json:"in_value"`  // Found
+	NetValue     int64  `json:"net_value"` // OutValue - InValue, the value lost
+}
+
+// ShrinkageByProduct sums the ADJUSTMENT transactions of one product
+type ShrinkageByProduct struct {
+	ProductID   uuid.UUID       `json:"product_id"`
+	SKU         string          `json:"sku"`
+	Name        string          `json:"name"`
+	Unit        string          `json:"unit"`
+	OutQuantity decimal.Decimal `json:"out_quantity"`
+	InQuantity  decimal.Decimal `json:"in_quantity"`
+	NetQuantity decimal.Decimal `json:"net_quantity"` // OutQuantity - InQuantity
+	OutValue    int64           `json:"out_value"`
+	InValue     int64           `json:"in_value"`
+	NetValue    int64           `json:"net_value"`
+}
+
+// DocumentFilter narrows down GET /transaction-documents. Zero values mean "no filter".
+type DocumentFilter struct {
+	Pagination
+	Type       model.TransactionType
+	LocationID *uuid.UUID
+}
+
+// LedgerEntry is a transaction with the product stock (total, or at the
+// requested location) right after it
+type LedgerEntry struct {
+	model.Transaction
+	BalanceAfter decimal.Decimal `json:"balance_after"`
+}
+
 type transactionRepo struct {
 	db *gorm.DB
 }
@@ -39,15 +108,24 @@ func NewTransactionRepo(db *gorm.DB) TransactionRepository {
 	return &transactionRepo{db}
 }
 
-func (r *transactionRepo) GetStockMovement(startDate, endDate time.Time) ([]StockMovementData, error) {
+func (r *transactionRepo) GetStockMovement(startDate, endDate time.Time, locationID *uuid.UUID) ([]StockMovementData, error) {
 	var results []StockMovementData
 
+	// Voided transactions and their reversals are left out of the chart. Transfers only
+	// move stock between locations, they count at a location but not overall.
+	query := r.db.Model(&model.Transaction{}).Where("voided_at IS NULL AND reversal_of_id IS NULL")
+	if locationID != nil {
+		query = query.Where("location_id = ?", *locationID)
+	} else {
+		query = query.Where("type <> ?", model.TxTransfer)
+	}
+
 	// Query untuk aggregate transactions per hari
-	rows, err := r.db.Model(&model.Transaction{}).
+	rows, err := query.
 		Select(`
 			DATE(created_at) as date,
-			COALESCE(SUM(CASE WHEN type = 'IN' THEN quantity ELSE 0 END), 0) as inbound,
-			COALESCE(SUM(CASE WHEN type = 'OUT' THEN quantity ELSE 0 END), 0) as outbound
+			COALESCE(SUM(CASE WHEN direction = 'IN' THEN quantity ELSE 0 END), 0) as inbound,
+			COALESCE(SUM(CASE WHEN direction = 'OUT' THEN quantity ELSE 0 END), 0) as outbound
 		`).
 		Where("created_at BETWEEN ? AND ?", startDate, endDate).
 		Group("DATE(created_at)").
@@ -70,30 +148,230 @@ func (r *transactionRepo) GetStockMovement(startDate, endDate time.Time) ([]Stoc
 	return results, nil
 }
 
-func (r *transactionRepo) FindAll() ([]model.Transaction, error) {
+// FindAll returns one page of transactions, newest first, and the cursor of the
+// next page (nil on the last page)
+func (r *transactionRepo) FindAll(filter TransactionFilter) ([]model.Transaction, *Cursor, error) {
+	limit := pageLimit(filter.Limit)
+
+	query := r.db.Model(&model.Transaction{})
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.ProductID != nil {
+		query = query.Where("product_id = ?", *filter.ProductID)
+	}
+	if filter.LocationID != nil {
+		query = query.Where("location_id = ?", *filter.LocationID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.ReasonCode != "" {
+		query = query.Where("reason_code = ?", strings.ToUpper(filter.ReasonCode))
+	}
+	if filter.PaymentMethod != "" {
+		query = query.Where("payment_method = ?", filter.PaymentMethod)
+	}
+	if filter.UserID != "" {
+		query = query.Where("created_by_user_id = ?", filter.UserID)
+	}
+	if filter.ExcludeVoided {
+		query = query.Where("voided_at IS NULL AND reversal_of_id IS NULL")
+	}
+	if filter.After != nil {
+		query = query.Where("(created_at, id) < (?, ?)", filter.After.CreatedAt, filter.After.ID)
+	}
+
+	// One extra row tells whether there is a next page
+	var transactions []model.Transaction
+	// Preload Product dan CreatedByUser (Unscoped: history stays readable after a product is deleted)
+	err := query.Preload("Product", unscoped).Preload("Location").Preload("Lots.Lot").Preload("SerialUnits.Serial").Preload("CreatedByUser").
+		Order("created_at DESC, id DESC").
+		Limit(limit + 1).
+		Find(&transactions).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(transactions) <= limit {
+		return transactions, nil, nil
+	}
+	transactions = transactions[:limit]
+	last := transactions[limit-1]
+	return transactions, &Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
+
+// FindLedger returns one page of a product's transactions, newest first, each with
+// the stock balance right after it. Balances are derived backwards from the current
+// product stock, so they stay correct even when stock was set outside transactions
+// (initial stock, manual edits). Stock transfers are recorded as TRANSFER transactions
+// at both locations; they don't change the product total (in-transit stock is part
+// of it) and are skipped there. Quantities lost in transit are ADJUSTMENT OUT
+// transactions linked to the transfer: they leave the product total but come out of
+// in-transit stock, so location ledgers skip them.
+func (r *transactionRepo) FindLedger(productID uuid.UUID, locationID *uuid.UUID, after *Cursor, limit int) ([]LedgerEntry, *Cursor, error) {
+	limit = pageLimit(limit)
+
+	type balanceRow struct {
+		ID           uuid.UUID
+		BalanceAfter decimal.Decimal
+	}
+
+	// Starting point: the current product total, or the balance at the location
+	currentSQL := "(SELECT stock FROM products WHERE id = ?)"
+	movedSQL := "CASE WHEN t.type = 'TRANSFER' THEN 0 WHEN t.direction = 'IN' THEN t.quantity ELSE -t.quantity END"
+	scopeSQL := "TRUE"
+	args := []interface{}{productID, productID}
+	if locationID != nil {
+		currentSQL = "(SELECT COALESCE(MAX(quantity), 0) FROM stock_balances WHERE product_id = ? AND location_id = ?)"
+		movedSQL = "CASE WHEN t.type = 'ADJUSTMENT' AND t.transfer_id IS NOT NULL THEN 0 WHEN t.direction = 'IN' THEN t.quantity ELSE -t.quantity END"
+		scopeSQL = "t.location_id = ?"
+		args = []interface{}{productID, *locationID, productID, *locationID}
+	}
+
+	cursorSQL := "TRUE"
+	if after != nil {
+		cursorSQL = "(created_at, id) < (?, ?)"
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	args = append(args, limit+1)
+
+	var rows []balanceRow
+	err := r.db.Raw(`
+		SELECT id, balance_after FROM (
+			SELECT t.id, t.created_at,
+				`+currentSQL+` - COALESCE(SUM(`+movedSQL+`) OVER (
+					ORDER BY t.created_at DESC, t.id DESC
+					ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+				), 0) AS balance_after
+			FROM transactions t
+			WHERE t.product_id = ? AND `+scopeSQL+` AND t.deleted_at IS NULL
+		) ledger
+		WHERE `+cursorSQL+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?`, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *Cursor
+	if len(rows) > limit {
+		rows = rows[:limit]
+		next = &Cursor{}
+	}
+	if len(rows) == 0 {
+		return []LedgerEntry{}, nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
 	var transactions []model.Transaction
-	// Preload Product dan CreatedByUser
-	err := r.db.Preload("Product").Preload("CreatedByUser").Order("created_at DESC").Find(&transactions).Error
-	return transactions, err
+	if err := r.db.Preload("Product", unscoped).Preload("Location").Preload("CreatedByUser").Where("id IN ?", ids).Find(&transactions).Error; err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[uuid.UUID]model.Transaction, len(transactions))
+	for _, tx := range transactions {
+		byID[tx.ID] = tx
+	}
+
+	entries := make([]LedgerEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, LedgerEntry{Transaction: byID[row.ID], BalanceAfter: row.BalanceAfter})
+	}
+	if next != nil {
+		last := entries[len(entries)-1]
+		next.CreatedAt = last.CreatedAt
+		next.ID = last.ID
+	}
+	return entries, next, nil
 }
 
 func (r *transactionRepo) FindByID(id uuid.UUID) (*model.Transaction, error) {
 	var transaction model.Transaction
-	err := r.db.Preload("Product").Preload("CreatedByUser").First(&transaction, "id = ?", id).Error
+	err := r.db.Preload("Product", unscoped).Preload("Location").Preload("Lots.Lot").Preload("SerialUnits.Serial").Preload("CreatedByUser").First(&transaction, "id = ?", id).Error
 	return &transaction, err
 }
 
-func (r *transactionRepo) GetDashboardStats() (*DashboardStats, error) {
+// FindByIDForUpdate locks the transaction row (so it can only be voided once) and
+// loads its lots and serials
+func (r *transactionRepo) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.Transaction, error) {
+	var transaction model.Transaction
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&transaction, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Preload("Lot").Where("transaction_id = ?", id).Find(&transaction.Lots).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Preload("Serial").Where("transaction_id = ?", id).Find(&transaction.SerialUnits).Error; err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func (r *transactionRepo) MarkVoided(tx *gorm.DB, id, reversalID uuid.UUID, reason, voidedBy string) error {
+	return tx.Model(&model.Transaction{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"voided_at":   gorm.Expr("NOW()"),
+		"voided_by":   voidedBy,
+		"void_reason": reason,
+		"reversal_id": reversalID,
+		"updated_by":  voidedBy,
+		"updated_at":  gorm.Expr("NOW()"),
+	}).Error
+}
+
+func (r *transactionRepo) GetDashboardStats(locationID *uuid.UUID) (*DashboardStats, error) {
+	if locationID != nil {
+		return r.getLocationStats(*locationID)
+	}
+
 	var stats DashboardStats
 
-	// Total Products
-	r.db.Model(&model.Product{}).Count(&stats.TotalProducts)
+	// Total Products (archived products are excluded from the dashboard)
+	r.db.Model(&model.Product{}).Where("archived_at IS NULL").Count(&stats.TotalProducts)
 
-	// Low Stock Count (stock < 10)
-	r.db.Model(&model.Product{}).Where("stock < ?", 10).Count(&stats.LowStockCount)
+	// Low Stock Count (stock at or below each product's reorder point)
+	r.db.Model(&model.Product{}).Where("archived_at IS NULL AND stock <= reorder_point").Count(&stats.LowStockCount)
 
-	// Total Valuation (SUM of stock * price)
-	r.db.Model(&model.Product{}).Select("COALESCE(SUM(stock * price), 0)").Scan(&stats.TotalValuation)
+	// Total Valuation (SUM of stock * price, rounded to whole currency units)
+	r.db.Model(&model.Product{}).Select("COALESCE(ROUND(SUM(stock * price)), 0)::bigint").Scan(&stats.TotalValuation)
+
+	return &stats, nil
+}
+
+// getLocationStats is GetDashboardStats scoped to one location's balances
+func (r *transactionRepo) getLocationStats(locationID uuid.UUID) (*DashboardStats, error) {
+	var stats DashboardStats
+
+	balances := r.db.Table("stock_balances b").
+		Joins("JOIN products p ON p.id = b.product_id").
+		Where("b.location_id = ? AND p.deleted_at IS NULL AND p.archived_at IS NULL", locationID).
+		Session(&gorm.Session{}) // Reused by the three queries below
+
+	// Products stocked at the location
+	if err := balances.Where("b.quantity > 0").Count(&stats.TotalProducts).Error; err != nil {
+		return nil, err
+	}
+
+	// Low Stock Count (location override of the reorder point, else the product's)
+	err := balances.Where("b.quantity <= COALESCE(b.reorder_point, p.reorder_point)").
+		Count(&stats.LowStockCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Valuation of the stock held at the location
+	err = balances.Select("COALESCE(ROUND(SUM(b.quantity * p.price)), 0)::bigint").
+		Scan(&stats.TotalValuation).Error
+	if err != nil {
+		return nil, err
+	}
 
 	return &stats, nil
 }
@@ -105,9 +383,15 @@ func (r *transactionRepo) GetFinancialSummary(startDate, endDate time.Time) (int
 	// According to user request:
 	// Pemasukan (Income) = Query from Type IN
 	// Pengeluaran (Expense) = Query from Type OUT
+	// Stock-only types (ADJUSTMENT, TRANSFER, RETURN) are neither, see GetShrinkage
+
+	// Voided transactions and their reversals cancel out, neither is counted
+	counted := r.db.Model(&model.Transaction{}).
+		Where("voided_at IS NULL AND reversal_of_id IS NULL").
+		Session(&gorm.Session{})
 
 	// Calculate Income (Type IN)
-	err := r.db.Model(&model.Transaction{}).
+	err := counted.
 		Where("type = ? AND created_at BETWEEN ? AND ?", model.TxIn, startDate, endDate).
 		Select("COALESCE(SUM(total_amount), 0)").
 		Scan(&income).Error
@@ -116,7 +400,7 @@ func (r *transactionRepo) GetFinancialSummary(startDate, endDate time.Time) (int
 	}
 
 	// Calculate Expense (Type OUT)
-	err = r.db.Model(&model.Transaction{}).
+	err = counted.
 		Where("type = ? AND created_at BETWEEN ? AND ?", model.TxOut, startDate, endDate).
 		Select("COALESCE(SUM(total_amount), 0)").
 		Scan(&expense).Error
@@ -126,3 +410,124 @@ func (r *transactionRepo) GetFinancialSummary(startDate, endDate time.Time) (int
 
 	return income, expense, nil
 }
+
+// CreateDocument inserts the document header only, the lines are recorded one by one
+// (with their lots and serials) by the caller
+func (r *transactionRepo) CreateDocument(tx *gorm.DB, document *model.TransactionDocument) error {
+	return tx.Omit(clause.Associations).Create(document).Error
+}
+
+func (r *transactionRepo) SetDocumentTotal(tx *gorm.DB, id uuid.UUID, total int64) error {
+	return tx.Model(&model.TransactionDocument{}).Where("id = ?", id).Update("total_amount", total).Error
+}
+
+func (r *transactionRepo) RecomputeDocumentTotal(tx *gorm.DB, id uuid.UUID) error {
+	return tx.Exec(`
+		UPDATE transaction_documents
+		SET total_amount = (
+			SELECT COALESCE(SUM(total_amount), 0) FROM transactions
+			WHERE document_id = ? AND voided_at IS NULL AND deleted_at IS NULL
+		), updated_at = NOW()
+		WHERE id = ?`, id, id).Error
+}
+
+func (r *transactionRepo) FindDocuments(filter DocumentFilter) ([]model.TransactionDocument, int64, error) {
+	filter.Normalize()
+
+	query := r.db.Model(&model.TransactionDocument{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.LocationID != nil {
+		query = query.Where("location_id = ?", *filter.LocationID)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var documents []model.TransactionDocument
+	err := query.Preload("Location").Preload("CreatedByUser").
+		Order("created_at DESC, id DESC").
+		Offset(filter.Offset()).
+		Limit(filter.Limit).
+		Find(&documents).Error
+	return documents, total, err
+}
+
+func (r *transactionRepo) FindDocumentByID(id uuid.UUID) (*model.TransactionDocument, error) {
+	var document model.TransactionDocument
+	err := r.db.Preload("Location").Preload("CreatedByUser").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC, id ASC") }).
+		Preload("Lines.Product", unscoped).Preload("Lines.Lots.Lot").Preload("Lines.SerialUnits.Serial").
+		First(&document, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// GetShrinkage sums the ADJUSTMENT transactions of a period per reason code and per
+// product. Voided adjustments and their reversals are left out.
+func (r *transactionRepo) GetShrinkage(startDate, endDate time.Time, locationID *uuid.UUID) ([]ShrinkageByReason, []ShrinkageByProduct, error) {
+	where := "t.type = ? AND t.voided_at IS NULL AND t.reversal_of_id IS NULL AND t.deleted_at IS NULL AND t.created_at BETWEEN ? AND ?"
+	args := []interface{}{model.TxAdjustment, startDate, endDate}
+	if locationID != nil {
+		where += " AND t.location_id = ?"
+		args = append(args, *locationID)
+	}
+
+	var byReason []ShrinkageByReason
+	err := r.db.Raw(`
+		SELECT t.reason_code, COALESCE(MAX(rc.name), t.reason_code) AS reason_name,
+			COUNT(*) AS transactions,
+			COALESCE(SUM(CASE WHEN t.direction = 'OUT' THEN t.total_amount ELSE 0 END), 0) AS out_value,
+			COALESCE(SUM(CASE WHEN t.direction = 'IN' THEN t.total_amount ELSE 0 END), 0) AS in_value
+		FROM transactions t
+		LEFT JOIN reason_codes rc ON rc.code = t.reason_code
+		WHERE `+where+`
+		GROUP BY t.reason_code
+		ORDER BY out_value DESC, t.reason_code ASC`, args...).Scan(&byReason).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range byReason {
+		byReason[i].NetValue = byReason[i].OutValue - byReason[i].InValue
+	}
+
+	var byProduct []ShrinkageByProduct
+	err = r.db.Raw(`
+		SELECT p.id AS product_id, p.sku, p.name, p.unit,
+			COALESCE(SUM(CASE WHEN t.direction = 'OUT' THEN t.quantity ELSE 0 END), 0) AS out_quantity,
+			COALESCE(SUM(CASE WHEN t.direction = 'IN' THEN t.quantity ELSE 0 END), 0) AS in_quantity,
+			COALESCE(SUM(CASE WHEN t.direction = 'OUT' THEN t.total_amount ELSE 0 END), 0) AS out_value,
+			COALESCE(SUM(CASE WHEN t.direction = 'IN' THEN t.total_amount ELSE 0 END), 0) AS in_value
+		FROM transactions t
+		JOIN products p ON p.id = t.product_id
+		WHERE `+where+`
+		GROUP BY p.id, p.sku, p.name, p.unit
+		ORDER BY out_value DESC, p.name ASC`, args...).Scan(&byProduct).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range byProduct {
+		byProduct[i].NetQuantity = byProduct[i].OutQuantity - byProduct[i].InQuantity
+		byProduct[i].NetValue = byProduct[i].OutValue - byProduct[i].InValue
+	}
+	return byReason, byProduct, nil
+}
+
+// BackfillDirections sets the direction of transactions predating stock-only types,
+// where it is their type (IN or OUT)
+func (r *transactionRepo) BackfillDirections() error {
+	return r.db.Unscoped().Model(&model.Transaction{}).
+		Where("direction = '' AND type IN ?", []model.TransactionType{model.TxIn, model.TxOut}).
+		UpdateColumn("direction", gorm.Expr("type")).Error
+}
+
+// unscoped is a Preload condition that also loads soft-deleted rows
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
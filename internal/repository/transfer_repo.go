@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"go-inventory-ws/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TransferRepository interface {
+	Create(tx *gorm.DB, transfer *model.StockTransfer) error
+	FindAll(filter TransferFilter) ([]model.StockTransfer, int64, error)
+	FindByID(id uuid.UUID) (*model.StockTransfer, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.StockTransfer, error)
+	Save(tx *gorm.DB, transfer *model.StockTransfer) error
+	SaveLine(tx *gorm.DB, line *model.StockTransferLine) error
+}
+
+// TransferFilter narrows down GET /transfers. Zero values mean "no filter".
+type TransferFilter struct {
+	Pagination
+	Status     model.TransferStatus
+	LocationID *uuid.UUID // Source or destination
+}
+
+type transferRepo struct {
+	db *gorm.DB
+}
+
+func NewTransferRepo(db *gorm.DB) TransferRepository {
+	return &transferRepo{db}
+}
+
+// Create inserts the transfer and its lines
+func (r *transferRepo) Create(tx *gorm.DB, transfer *model.StockTransfer) error {
+	return tx.Create(transfer).Error
+}
+
+func (r *transferRepo) FindAll(filter TransferFilter) ([]model.StockTransfer, int64, error) {
+	filter.Normalize()
+
+	query := r.db.Model(&model.StockTransfer{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.LocationID != nil {
+		query = query.Where("from_location_id = ? OR to_location_id = ?", *filter.LocationID, *filter.LocationID)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transfers []model.StockTransfer
+	err := query.Preload("FromLocation").Preload("ToLocation").Preload("Lines").
+		Order("created_at DESC, id DESC").
+		Offset(filter.Offset()).
+		Limit(filter.Limit).
+		Find(&transfers).Error
+	return transfers, total, err
+}
+
+func (r *transferRepo) FindByID(id uuid.UUID) (*model.StockTransfer, error) {
+	var transfer model.StockTransfer
+	err := r.db.Preload("FromLocation").Preload("ToLocation").
+		Preload("Lines").Preload("Lines.Product", unscoped).Preload("Lines.Lots", lotOrder).Preload("Lines.Serials.Serial").
+		First(&transfer, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// FindByIDForUpdate locks the transfer row, so state changes of one transfer are serialized
+func (r *transferRepo) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.StockTransfer, error) {
+	var transfer model.StockTransfer
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&transfer, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Preload("Lots", lotOrder).Preload("Serials.Serial").Where("transfer_id = ?", id).Order("id").Find(&transfer.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// lotOrder keeps the lots of a transfer line in the FEFO order they were picked in
+func lotOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("expiry_date ASC NULLS LAST, id ASC")
+}
+
+// Save updates the transfer header only, lines are saved with SaveLine
+func (r *transferRepo) Save(tx *gorm.DB, transfer *model.StockTransfer) error {
+	return tx.Omit(clause.Associations).Save(transfer).Error
+}
+
+func (r *transferRepo) SaveLine(tx *gorm.DB, line *model.StockTransferLine) error {
+	return tx.Omit(clause.Associations).Save(line).Error
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"strings"
+
+	"go-inventory-ws/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UnitRepository interface {
+	FindAll(includeInactive bool) ([]model.UnitOfMeasure, error)
+	FindByCode(code string) (*model.UnitOfMeasure, error)
+	Create(unit *model.UnitOfMeasure) error
+	Update(unit *model.UnitOfMeasure) error
+	FindProductUnits(productID uuid.UUID) ([]model.ProductUnit, error)
+	FindProductUnit(productID uuid.UUID, code string) (*model.ProductUnit, error)
+	SaveProductUnit(unit *model.ProductUnit) error
+	DeleteProductUnit(productID uuid.UUID, code string) (int64, error)
+	SeedDefaults(defaults []model.UnitOfMeasure) error
+}
+
+type unitRepo struct {
+	db *gorm.DB
+}
+
+func NewUnitRepo(db *gorm.DB) UnitRepository {
+	return &unitRepo{db}
+}
+
+func (r *unitRepo) FindAll(includeInactive bool) ([]model.UnitOfMeasure, error) {
+	var units []model.UnitOfMeasure
+	query := r.db.Order("code ASC")
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Find(&units).Error
+	return units, err
+}
+
+// FindByCode looks a unit up case-insensitively (product units predating the catalog were free text)
+func (r *unitRepo) FindByCode(code string) (*model.UnitOfMeasure, error) {
+	var unit model.UnitOfMeasure
+	if err := r.db.First(&unit, "code = ?", strings.ToUpper(code)).Error; err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+func (r *unitRepo) Create(unit *model.UnitOfMeasure) error {
+	return r.db.Create(unit).Error
+}
+
+func (r *unitRepo) Update(unit *model.UnitOfMeasure) error {
+	return r.db.Save(unit).Error
+}
+
+func (r *unitRepo) FindProductUnits(productID uuid.UUID) ([]model.ProductUnit, error) {
+	var units []model.ProductUnit
+	err := r.db.Where("product_id = ?", productID).Order("factor ASC").Find(&units).Error
+	return units, err
+}
+
+func (r *unitRepo) FindProductUnit(productID uuid.UUID, code string) (*model.ProductUnit, error) {
+	var unit model.ProductUnit
+	err := r.db.First(&unit, "product_id = ? AND unit_code = ?", productID, strings.ToUpper(code)).Error
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+// SaveProductUnit creates or updates the conversion of a product unit
+func (r *unitRepo) SaveProductUnit(unit *model.ProductUnit) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "unit_code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"factor", "updated_at", "updated_by"}),
+	}).Create(unit).Error
+}
+
+func (r *unitRepo) DeleteProductUnit(productID uuid.UUID, code string) (int64, error) {
+	result := r.db.Where("product_id = ? AND unit_code = ?", productID, strings.ToUpper(code)).
+		Delete(&model.ProductUnit{})
+	return result.RowsAffected, result.Error
+}
+
+// SeedDefaults adds the default units and the units already used by products to the
+// catalog, and fills in the entered quantity of transactions predating units
+func (r *unitRepo) SeedDefaults(defaults []model.UnitOfMeasure) error {
+	var used []string
+	if err := r.db.Unscoped().Model(&model.Product{}).
+		Where("unit <> ''").Distinct().Pluck("UPPER(unit)", &used).Error; err != nil {
+		return err
+	}
+
+	units := append([]model.UnitOfMeasure(nil), defaults...)
+	for _, code := range used {
+		units = append(units, model.UnitOfMeasure{Code: code, Name: code})
+	}
+	for i := range units {
+		units[i].IsActive = true
+		units[i].CreatedBy = "system"
+		units[i].UpdatedBy = "system"
+		err := r.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "code"}},
+			DoNothing: true,
+		}).Create(&units[i]).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.db.Model(&model.Transaction{}).
+		Where("unit_quantity = 0").
+		Updates(map[string]interface{}{
+			"unit_quantity": gorm.Expr("quantity"),
+			"unit_factor":   1,
+		}).Error
+}
@@ -18,6 +18,8 @@ type UserRepository interface {
 	FindAll() ([]model.User, error)
 	UpdateTokenVersion(userID uuid.UUID, version string) error
 	UpdateLastSeen(userID uuid.UUID) error
+	FindLastSeen() ([]model.User, error)
+	TouchSocketSeen(userIDs []string) error
 }
 
 type userRepo struct {
@@ -83,3 +85,24 @@ func (r *userRepo) UpdateTokenVersion(userID uuid.UUID, version string) error {
 func (r *userRepo) UpdateLastSeen(userID uuid.UUID) error {
 	return r.db.Model(&model.User{}).Where("id = ?", userID).Update("last_seen_at", gorm.Expr("NOW()")).Error
 }
+
+// FindLastSeen returns active users with only the fields presence needs (no preloads)
+func (r *userRepo) FindLastSeen() ([]model.User, error) {
+	var users []model.User
+	if err := r.db.Select("id", "full_name", "last_seen_at", "socket_seen_at").
+		Where("is_active = ?", true).
+		Order("full_name ASC").
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// TouchSocketSeen marks users as connected through a WebSocket on this instance.
+// Unlike UpdateLastSeen it does not extend the session inactivity timeout.
+func (r *userRepo) TouchSocketSeen(userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.User{}).Where("id IN ?", userIDs).Update("socket_seen_at", gorm.Expr("NOW()")).Error
+}
@@ -1,7 +1,6 @@
 package service
 
 import (
-	"encoding/json"
 	"errors"
 	"time"
 
@@ -44,12 +43,14 @@ type TokenValidationResponse struct {
 type authService struct {
 	userRepo repository.UserRepository
 	wsHub    *ws.Hub
+	presence PresenceService
 }
 
-func NewAuthService(userRepo repository.UserRepository, hub *ws.Hub) AuthService {
+func NewAuthService(userRepo repository.UserRepository, hub *ws.Hub, presence PresenceService) AuthService {
 	return &authService{
 		userRepo: userRepo,
 		wsHub:    hub,
+		presence: presence,
 	}
 }
 
@@ -97,6 +98,9 @@ func (s *authService) Login(email, password string) (*LoginResponse, error) {
 		return nil, errors.New("failed to update session")
 	}
 
+	// Close WebSocket connections still bound to the previous session
+	s.wsHub.CloseStaleSessions(user.ID.String(), newTokenVersion)
+
 	// 6. Generate JWT token with TokenVersion
 	token, err := jwt.GenerateToken(user.ID, user.Email, user.FullName, roleCode, user.GetPrivilegeCodes(), newTokenVersion)
 	if err != nil {
@@ -193,19 +197,8 @@ func (s *authService) Heartbeat(userID uuid.UUID) error {
 		return err
 	}
 
-	// 2. Broadcast status "online" ke semua client
-	// Kita broadcast setiap heartbeat agar user yang baru connect dapat info terbaru
-	// Frontend sebaiknya handle duplicate/throttle jika perlu
-	go func() {
-		payload := map[string]interface{}{
-			"type":         "user_status_update",
-			"user_id":      userID.String(),
-			"status":       "online",
-			"last_seen_at": time.Now(),
-		}
-		msg, _ := json.Marshal(payload)
-		s.wsHub.Broadcast <- msg
-	}()
+	// 2. Presence decides whether this is a transition worth broadcasting
+	s.presence.Touch(userID)
 
 	return nil
 }
@@ -4,11 +4,14 @@ import (
 	"time"
 
 	"go-inventory-ws/internal/repository"
+
+	"github.com/google/uuid"
 )
 
 type DashboardService interface {
-	GetStockMovement(days int) ([]repository.StockMovementData, error)
-	GetDashboardStats() (*repository.DashboardStats, error)
+	// locationID nil = all locations
+	GetStockMovement(days int, locationID *uuid.UUID) ([]repository.StockMovementData, error)
+	GetDashboardStats(locationID *uuid.UUID) (*repository.DashboardStats, error)
 }
 
 type dashboardService struct {
@@ -19,13 +22,13 @@ func NewDashboardService(txRepo repository.TransactionRepository) DashboardServi
 	return &dashboardService{txRepo: txRepo}
 }
 
-func (s *dashboardService) GetStockMovement(days int) ([]repository.StockMovementData, error) {
+func (s *dashboardService) GetStockMovement(days int, locationID *uuid.UUID) ([]repository.StockMovementData, error) {
 	endDate := time.Now()
 	startDate := endDate.AddDate(0, 0, -days)
 
-	return s.txRepo.GetStockMovement(startDate, endDate)
+	return s.txRepo.GetStockMovement(startDate, endDate, locationID)
 }
 
-func (s *dashboardService) GetDashboardStats() (*repository.DashboardStats, error) {
-	return s.txRepo.GetDashboardStats()
+func (s *dashboardService) GetDashboardStats(locationID *uuid.UUID) (*repository.DashboardStats, error) {
+	return s.txRepo.GetDashboardStats(locationID)
 }
@@ -0,0 +1,584 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/internal/ws"
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Service tests run against fake repositories sharing one in-memory fakeStore.
+// The *gorm.DB the services get is backed by a driver that accepts every statement
+// without a database: transactions begin and commit, and the rows services insert
+// themselves (tx.Create) are captured into the store. Writes are not rolled back,
+// so tests only look at the store after calls that succeeded or failed before writing.
+
+// fakeConnector opens fakeConns, see sql.OpenDB
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return nil }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+type balanceKey struct {
+	productID  uuid.UUID
+	locationID uuid.UUID
+}
+
+// fakeStore holds the rows of every fake repository
+type fakeStore struct {
+	locations    map[uuid.UUID]*model.Location
+	products     map[uuid.UUID]*model.Product
+	balances     map[balanceKey]*model.StockBalance
+	transactions map[uuid.UUID]*model.Transaction
+	reservations map[uuid.UUID]*model.Reservation
+	transfers    map[uuid.UUID]*model.StockTransfer
+	stocktakes   map[uuid.UUID]*model.Stocktake
+
+	// Transactions inserted by the services, in order
+	created []*model.Transaction
+
+	outboxMutex sync.Mutex
+	outbox      []model.OutboxEvent
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		locations:    make(map[uuid.UUID]*model.Location),
+		products:     make(map[uuid.UUID]*model.Product),
+		balances:     make(map[balanceKey]*model.StockBalance),
+		transactions: make(map[uuid.UUID]*model.Transaction),
+		reservations: make(map[uuid.UUID]*model.Reservation),
+		transfers:    make(map[uuid.UUID]*model.StockTransfer),
+		stocktakes:   make(map[uuid.UUID]*model.Stocktake),
+	}
+}
+
+// newFakeDB returns a *gorm.DB on the fake driver that records created transactions in the store
+func newFakeDB(t *testing.T, store *fakeStore) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(fakeConnector{})}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Callback().Create().After("gorm:create").Register("test:record_transactions", func(db *gorm.DB) {
+		if t, ok := db.Statement.Dest.(*model.Transaction); ok {
+			saved := *t
+			store.transactions[t.ID] = &saved
+			store.created = append(store.created, &saved)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+type fakeLocationRepo struct {
+	repository.LocationRepository
+	store *fakeStore
+}
+
+func (r *fakeLocationRepo) FindByID(id uuid.UUID) (*model.Location, error) {
+	location, ok := r.store.locations[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return location, nil
+}
+
+func (r *fakeLocationRepo) FindDefault() (*model.Location, error) {
+	for _, location := range r.store.locations {
+		if location.IsDefault {
+			return location, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeReasonRepo serves the default reason codes
+type fakeReasonRepo struct {
+	repository.ReasonRepository
+}
+
+func (fakeReasonRepo) FindByCode(code string) (*model.ReasonCode, error) {
+	for _, reason := range model.DefaultReasonCodes {
+		if reason.Code == code {
+			reason.IsActive = true
+			return &reason, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakeProductRepo struct {
+	repository.ProductRepository
+	store *fakeStore
+}
+
+func (r *fakeProductRepo) FindByID(id uuid.UUID) (*model.Product, error) {
+	product, ok := r.store.products[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *product
+	return &copied, nil
+}
+
+func (r *fakeProductRepo) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID, includeDeleted bool) (*model.Product, error) {
+	return r.FindByID(id)
+}
+
+type fakeStockRepo struct {
+	repository.StockRepository
+	store *fakeStore
+}
+
+func (r *fakeStockRepo) LockBalance(tx *gorm.DB, productID, locationID uuid.UUID) (*model.StockBalance, error) {
+	key := balanceKey{productID, locationID}
+	if r.store.balances[key] == nil {
+		r.store.balances[key] = &model.StockBalance{ProductID: productID, LocationID: locationID}
+	}
+	copied := *r.store.balances[key]
+	return &copied, nil
+}
+
+func (r *fakeStockRepo) SetQuantity(tx *gorm.DB, productID, locationID uuid.UUID, quantity decimal.Decimal) error {
+	r.store.balances[balanceKey{productID, locationID}].Quantity = quantity
+	return nil
+}
+
+func (r *fakeStockRepo) SetInTransit(tx *gorm.DB, productID, locationID uuid.UUID, inTransit decimal.Decimal) error {
+	r.store.balances[balanceKey{productID, locationID}].InTransit = inTransit
+	return nil
+}
+
+func (r *fakeStockRepo) SyncProductStock(tx *gorm.DB, productID uuid.UUID, updatedBy string) (decimal.Decimal, error) {
+	total := decimal.Zero
+	for key, balance := range r.store.balances {
+		if key.productID == productID {
+			total += balance.Quantity + balance.InTransit
+		}
+	}
+	r.store.products[productID].Stock = total
+	return total, nil
+}
+
+type fakeTransactionRepo struct {
+	repository.TransactionRepository
+	store *fakeStore
+}
+
+func (r *fakeTransactionRepo) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.Transaction, error) {
+	t, ok := r.store.transactions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *t
+	return &copied, nil
+}
+
+func (r *fakeTransactionRepo) MarkVoided(tx *gorm.DB, id, reversalID uuid.UUID, reason, voidedBy string) error {
+	now := time.Now()
+	t := r.store.transactions[id]
+	t.VoidedAt = &now
+	t.VoidedBy = voidedBy
+	t.VoidReason = reason
+	t.ReversalID = &reversalID
+	return nil
+}
+
+func (r *fakeTransactionRepo) RecomputeDocumentTotal(tx *gorm.DB, id uuid.UUID) error {
+	return nil
+}
+
+type fakeReservationRepo struct {
+	repository.ReservationRepository
+	store *fakeStore
+}
+
+func (r *fakeReservationRepo) Create(tx *gorm.DB, reservation *model.Reservation) error {
+	reservation.ID = uuid.New()
+	return r.Save(tx, reservation)
+}
+
+func (r *fakeReservationRepo) FindByID(id uuid.UUID) (*model.Reservation, error) {
+	reservation, ok := r.store.reservations[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *reservation
+	return &copied, nil
+}
+
+func (r *fakeReservationRepo) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.Reservation, error) {
+	return r.FindByID(id)
+}
+
+func (r *fakeReservationRepo) Save(tx *gorm.DB, reservation *model.Reservation) error {
+	saved := *reservation
+	saved.Product, saved.Location = nil, nil
+	r.store.reservations[reservation.ID] = &saved
+	return nil
+}
+
+func (r *fakeReservationRepo) ReservedQuantity(tx *gorm.DB, productID, locationID uuid.UUID, exclude *uuid.UUID) (decimal.Decimal, error) {
+	reserved := decimal.Zero
+	now := time.Now()
+	for _, reservation := range r.store.reservations {
+		if reservation.ProductID != productID || reservation.LocationID != locationID || !reservation.IsHolding(now) {
+			continue
+		}
+		if exclude != nil && reservation.ID == *exclude {
+			continue
+		}
+		reserved += reservation.Outstanding()
+	}
+	return reserved, nil
+}
+
+func (r *fakeReservationRepo) ExpireDue(tx *gorm.DB, now time.Time, limit int) ([]model.Reservation, error) {
+	var expired []model.Reservation
+	for _, reservation := range r.store.reservations {
+		if len(expired) == limit {
+			break
+		}
+		if reservation.Status == model.ReservationActive && !reservation.ExpiresAt.After(now) {
+			reservation.Status = model.ReservationExpired
+			reservation.ReleasedAt = &now
+			expired = append(expired, *reservation)
+		}
+	}
+	return expired, nil
+}
+
+type fakeTransferRepo struct {
+	repository.TransferRepository
+	store *fakeStore
+}
+
+func (r *fakeTransferRepo) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.StockTransfer, error) {
+	transfer, ok := r.store.transfers[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *transfer
+	copied.Lines = append([]model.StockTransferLine(nil), transfer.Lines...)
+	return &copied, nil
+}
+
+func (r *fakeTransferRepo) Save(tx *gorm.DB, transfer *model.StockTransfer) error {
+	saved := *transfer
+	saved.Lines = append([]model.StockTransferLine(nil), transfer.Lines...)
+	r.store.transfers[transfer.ID] = &saved
+	return nil
+}
+
+func (r *fakeTransferRepo) SaveLine(tx *gorm.DB, line *model.StockTransferLine) error {
+	lines := r.store.transfers[line.TransferID].Lines
+	for i := range lines {
+		if lines[i].ID == line.ID {
+			lines[i] = *line
+		}
+	}
+	return nil
+}
+
+type fakeStocktakeRepo struct {
+	repository.StocktakeRepository
+	store *fakeStore
+}
+
+// FindByID returns a copy of the stocktake with the variance of its counted lines set
+func (r *fakeStocktakeRepo) FindByID(id uuid.UUID) (*model.Stocktake, error) {
+	stocktake, ok := r.store.stocktakes[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *stocktake
+	copied.Lines = append([]model.StocktakeLine(nil), stocktake.Lines...)
+	sort.Slice(copied.Lines, func(i, j int) bool {
+		return copied.Lines[i].ProductID.String() < copied.Lines[j].ProductID.String()
+	})
+	for i := range copied.Lines {
+		line := &copied.Lines[i]
+		if line.CountedQty != nil {
+			variance := *line.CountedQty - line.ExpectedQty
+			line.Variance = &variance
+		}
+	}
+	return &copied, nil
+}
+
+func (r *fakeStocktakeRepo) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model.Stocktake, error) {
+	return r.FindByID(id)
+}
+
+func (r *fakeStocktakeRepo) Save(tx *gorm.DB, stocktake *model.Stocktake) error {
+	saved := *stocktake
+	saved.Lines = r.store.stocktakes[stocktake.ID].Lines
+	r.store.stocktakes[stocktake.ID] = &saved
+	return nil
+}
+
+func (r *fakeStocktakeRepo) SaveLine(tx *gorm.DB, line *model.StocktakeLine) error {
+	lines := r.store.stocktakes[line.StocktakeID].Lines
+	for i := range lines {
+		if lines[i].ID == line.ID {
+			lines[i] = *line
+		}
+	}
+	return nil
+}
+
+type fakeOutboxRepo struct {
+	repository.OutboxRepository
+	store *fakeStore
+}
+
+func (r *fakeOutboxRepo) Enqueue(tx *gorm.DB, event *model.OutboxEvent) error {
+	r.store.outboxMutex.Lock()
+	defer r.store.outboxMutex.Unlock()
+	event.ID = int64(len(r.store.outbox) + 1)
+	event.CreatedAt = time.Now()
+	r.store.outbox = append(r.store.outbox, *event)
+	return nil
+}
+
+func (r *fakeOutboxRepo) ClaimPending(tx *gorm.DB, limit int) ([]model.OutboxEvent, error) {
+	r.store.outboxMutex.Lock()
+	defer r.store.outboxMutex.Unlock()
+	var pending []model.OutboxEvent
+	for _, event := range r.store.outbox {
+		if event.DispatchedAt == nil && len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+	return pending, nil
+}
+
+func (r *fakeOutboxRepo) MarkDispatched(tx *gorm.DB, ids []int64) error {
+	r.store.outboxMutex.Lock()
+	defer r.store.outboxMutex.Unlock()
+	now := time.Now()
+	for _, id := range ids {
+		r.store.outbox[id-1].DispatchedAt = &now
+	}
+	return nil
+}
+
+// pendingEventTypes lists the envelope types of the outbox events not dispatched yet
+func (s *fakeStore) pendingEventTypes(t *testing.T) []string {
+	t.Helper()
+	s.outboxMutex.Lock()
+	defer s.outboxMutex.Unlock()
+	var types []string
+	for _, event := range s.outbox {
+		if event.DispatchedAt != nil {
+			continue
+		}
+		var envelope struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(event.Payload), &envelope); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, envelope.Type)
+	}
+	return types
+}
+
+// fakeEventLog is the hub's event log. Once failAfter more appends succeeded, Append fails
+// until failAfter is set back to -1.
+type fakeEventLog struct {
+	mu        sync.Mutex
+	events    []model.HubEvent
+	failAfter int
+}
+
+var errEventLogDown = errors.New("event log down")
+
+func (l *fakeEventLog) Append(event *model.HubEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.failAfter == 0 {
+		return errEventLogDown
+	}
+	if l.failAfter > 0 {
+		l.failAfter--
+	}
+	event.Seq = int64(len(l.events) + 1)
+	l.events = append(l.events, *event)
+	return nil
+}
+
+func (l *fakeEventLog) FindAfter(seq int64, limit int) ([]model.HubEvent, error) {
+	return nil, nil
+}
+
+func (l *fakeEventLog) OldestSeq() (int64, error) {
+	return 1, nil
+}
+
+func (l *fakeEventLog) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (l *fakeEventLog) setFailAfter(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failAfter = n
+}
+
+func (l *fakeEventLog) logged() []model.HubEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]model.HubEvent(nil), l.events...)
+}
+
+// testEnv wires the services under test to the fakes
+type testEnv struct {
+	store    *fakeStore
+	db       *gorm.DB
+	eventLog *fakeEventLog
+	outbox   *Outbox
+
+	locations LocationService
+	units     UnitService
+	reasons   ReasonService
+
+	products     *fakeProductRepo
+	stock        *fakeStockRepo
+	transactions *fakeTransactionRepo
+	reservations *fakeReservationRepo
+
+	// Default location
+	warehouse *model.Location
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := newFakeStore()
+	eventLog := &fakeEventLog{failAfter: -1}
+	hub := ws.NewHub(eventLog)
+	go hub.Run()
+
+	env := &testEnv{
+		store:        store,
+		db:           newFakeDB(t, store),
+		eventLog:     eventLog,
+		products:     &fakeProductRepo{store: store},
+		stock:        &fakeStockRepo{store: store},
+		transactions: &fakeTransactionRepo{store: store},
+		reservations: &fakeReservationRepo{store: store},
+		locations:    NewLocationService(&fakeLocationRepo{store: store}),
+		reasons:      NewReasonService(fakeReasonRepo{}),
+	}
+	env.units = NewUnitService(nil, env.products)
+	env.outbox = NewOutbox(&fakeOutboxRepo{store: store}, env.db, hub)
+	env.warehouse = env.addLocation("Warehouse", true)
+	return env
+}
+
+func (e *testEnv) inventoryService() InventoryService {
+	return NewInventoryService(e.products, e.transactions, e.stock, nil, nil, e.reservations, e.locations, e.units, e.reasons, e.db, e.outbox)
+}
+
+func (e *testEnv) reservationService() *reservationService {
+	return NewReservationService(e.reservations, e.products, e.stock, e.locations, e.units, e.db, e.outbox).(*reservationService)
+}
+
+func (e *testEnv) addLocation(name string, isDefault bool) *model.Location {
+	location := &model.Location{Code: name, Name: name, Type: model.LocationWarehouse, IsDefault: isDefault, IsActive: true}
+	location.ID = uuid.New()
+	e.store.locations[location.ID] = location
+	return location
+}
+
+func (e *testEnv) addProduct(name string, price int64) *model.Product {
+	product := &model.Product{Name: name, SKU: name, Price: price, Unit: "pcs"}
+	product.ID = uuid.New()
+	e.store.products[product.ID] = product
+	return product
+}
+
+// setBalance sets a product's balance at a location and syncs its total stock
+func (e *testEnv) setBalance(product *model.Product, location *model.Location, quantity, inTransit decimal.Decimal) {
+	e.store.balances[balanceKey{product.ID, location.ID}] = &model.StockBalance{
+		ProductID:  product.ID,
+		LocationID: location.ID,
+		Quantity:   quantity,
+		InTransit:  inTransit,
+	}
+	e.stock.SyncProductStock(nil, product.ID, "")
+}
+
+func (e *testEnv) balance(product *model.Product, location *model.Location) model.StockBalance {
+	if balance := e.store.balances[balanceKey{product.ID, location.ID}]; balance != nil {
+		return *balance
+	}
+	return model.StockBalance{}
+}
+
+func (e *testEnv) addReservation(product *model.Product, location *model.Location, quantity decimal.Decimal, expiresAt time.Time) *model.Reservation {
+	reservation := &model.Reservation{
+		ProductID:  product.ID,
+		LocationID: location.ID,
+		Quantity:   quantity,
+		Status:     model.ReservationActive,
+		ExpiresAt:  expiresAt,
+	}
+	e.reservations.Create(nil, reservation)
+	return reservation
+}
+
+func qty(n int) decimal.Decimal {
+	return decimal.FromInt(n)
+}
@@ -1,47 +1,133 @@
 package service
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"go-inventory-ws/internal/events"
 	"go-inventory-ws/internal/model"
 	"go-inventory-ws/internal/repository"
 	"go-inventory-ws/internal/ws"
+	"go-inventory-ws/pkg/decimal"
 	"go-inventory-ws/pkg/validator"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrProductArchived = errors.New("product is archived")
+	ErrProductHasStock = errors.New("product still has stock, bring it to 0 before deleting")
+
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrTransactionVoided   = errors.New("transaction is already voided")
+	ErrReversalNotVoidable = errors.New("a reversal cannot be voided, record a new transaction instead")
+	ErrVoidReasonMissing   = errors.New("a reason is required to void a transaction")
+	ErrStockNotEditable    = errors.New("stock can't be edited on the product, record an ADJUSTMENT transaction instead")
+	ErrTransferNotVoidable = errors.New("transfer movements and transit losses cannot be voided, they belong to a stock transfer")
+)
+
 type InventoryService interface {
-	CreateProduct(req *model.Product, userID, userName, userEmail string) error
-	UpdateProduct(id uuid.UUID, req *model.Product, userID, userName, userEmail string) (*model.Product, error)
+	CreateProduct(req *CreateProductRequest, userID, userName, userEmail string) error
+	UpdateProduct(id uuid.UUID, req *UpdateProductRequest, userID, userName, userEmail string) (*model.Product, error)
+	ArchiveProduct(id uuid.UUID, userID, userName, userEmail string) (*model.Product, error)
+	RestoreProduct(id uuid.UUID, userID, userName, userEmail string) (*model.Product, error)
+	DeleteProduct(id uuid.UUID, userID, userName, userEmail string) error
 	RecordTransaction(req *model.Transaction, userID, userName, userEmail string) error
-	GetAllProducts() ([]model.Product, error)
-	GetAllTransactions() ([]model.Transaction, error)
+	RecordDocument(req *model.TransactionDocument, userID, userName, userEmail string) error
+	ListDocuments(filter repository.DocumentFilter) ([]model.TransactionDocument, int64, error)
+	GetDocumentByID(id uuid.UUID) (*model.TransactionDocument, error)
+	ListProducts(filter repository.ProductFilter) ([]model.Product, int64, error)
+	GetLowStockReport(locationID *uuid.UUID) ([]LowStockItem, error)
+	GetProductStock(productID uuid.UUID) (*model.Product, []model.StockBalance, error)
+	SetLocationReorderPoint(productID, locationID uuid.UUID, reorderPoint *decimal.Decimal) error
+	ListTransactions(filter repository.TransactionFilter) ([]model.Transaction, *repository.Cursor, error)
+	GetProductLedger(productID uuid.UUID, locationID *uuid.UUID, after *repository.Cursor, limit int) (*model.Product, []repository.LedgerEntry, *repository.Cursor, error)
 	GetTransactionByID(id uuid.UUID) (*model.Transaction, error)
+	VoidTransaction(id uuid.UUID, reason, userID, userName, userEmail string) (*model.Transaction, error)
 	GetFinancialStats(startDate, endDate time.Time) (map[string]interface{}, error) // Added
+	GetShrinkageReport(startDate, endDate time.Time, locationID *uuid.UUID) (*ShrinkageReport, error)
+}
+
+// ShrinkageReport is GET /finance/shrinkage: stock adjustments of a period, kept out
+// of income and expense
+type ShrinkageReport struct {
+	From       time.Time                       `json:"from"`
+	To         time.Time                       `json:"to"`
+	LocationID *uuid.UUID                      `json:"location_id,omitempty"`
+	NetValue   int64                           `json:"net_value"` // Value lost, found stock deducted
+	ByReason   []repository.ShrinkageByReason  `json:"by_reason"`
+	ByProduct  []repository.ShrinkageByProduct `json:"by_product"`
+}
+
+// LowStockItem is one row of GET /products/low-stock. When the report is scoped to
+// a location, Stock and ReorderPoint are those of the location.
+type LowStockItem struct {
+	ProductID         uuid.UUID        `json:"product_id"`
+	SKU               string           `json:"sku"`
+	Name              string           `json:"name"`
+	Unit              string           `json:"unit"`
+	LocationID        *uuid.UUID       `json:"location_id,omitempty"`
+	Stock             decimal.Decimal  `json:"stock"`
+	ReorderPoint      decimal.Decimal  `json:"reorder_point"`
+	ReorderQty        decimal.Decimal  `json:"reorder_qty"`
+	MinStock          *decimal.Decimal `json:"min_stock,omitempty"`
+	MaxStock          *decimal.Decimal `json:"max_stock,omitempty"`
+	SuggestedOrderQty decimal.Decimal  `json:"suggested_order_qty"`
+	Critical          bool             `json:"critical"` // Below MinStock
 }
 
 type inventoryService struct {
 	productRepo     repository.ProductRepository
 	transactionRepo repository.TransactionRepository // Added
+	stockRepo       repository.StockRepository       // Per-location balances
+	lotRepo         repository.LotRepository         // Lots of lot-tracked products
+	serialRepo      repository.SerialRepository      // Units of serialized products
+	reservationRepo repository.ReservationRepository // Stock held for orders
+	locations       LocationService
+	units           UnitService   // Unit catalog and per-product conversions
+	reasons         ReasonService // Reason codes of adjustments and returns
 	db              *gorm.DB
-	wsHub           *ws.Hub
+	outbox          *Outbox // WebSocket events are published through the transactional outbox
 }
 
-func NewInventoryService(pRepo repository.ProductRepository, tRepo repository.TransactionRepository, db *gorm.DB, hub *ws.Hub) InventoryService {
+func NewInventoryService(pRepo repository.ProductRepository, tRepo repository.TransactionRepository, sRepo repository.StockRepository, lRepo repository.LotRepository, snRepo repository.SerialRepository, rRepo repository.ReservationRepository, locations LocationService, units UnitService, reasons ReasonService, db *gorm.DB, outbox *Outbox) InventoryService {
 	return &inventoryService{
 		productRepo:     pRepo,
 		transactionRepo: tRepo, // Added
+		stockRepo:       sRepo,
+		lotRepo:         lRepo,
+		serialRepo:      snRepo,
+		reservationRepo: rRepo,
+		locations:       locations,
+		units:           units,
+		reasons:         reasons,
 		db:              db,
-		wsHub:           hub,
+		outbox:          outbox,
 	}
 }
 
-func (s *inventoryService) CreateProduct(req *model.Product, userID, userName, userEmail string) error {
+// CreateProductRequest is a product plus whether reorder_point was sent, so an
+// explicit 0 is kept and only a missing one gets the default
+type CreateProductRequest struct {
+	model.Product
+	ReorderPoint *decimal.Decimal `json:"reorder_point"`
+}
+
+// Reorder point of products created without one
+var defaultReorderPoint = decimal.FromInt(10)
+
+func (s *inventoryService) CreateProduct(in *CreateProductRequest, userID, userName, userEmail string) error {
+	req := &in.Product
+	req.ReorderPoint = defaultReorderPoint
+	if in.ReorderPoint != nil {
+		req.ReorderPoint = *in.ReorderPoint
+	}
+
 	// 1. Validasi Struct Dasar
 	if errs := validator.ValidateStruct(req); len(errs) > 0 {
 		firstErr := errs[0]
@@ -49,10 +135,32 @@ func (s *inventoryService) CreateProduct(req *model.Product, userID, userName, u
 		fmt.Println(">>> DEBUG VALIDATION ERROR:", errorMsg)
 		return errors.New(errorMsg)
 	}
+	if err := validateReorderLevels(req); err != nil {
+		return err
+	}
+	if req.Stock < 0 {
+		return errors.New("initial stock must not be negative")
+	}
+	if req.TrackLots && req.Stock != 0 {
+		return errors.New("lot-tracked products start without stock, receive it through IN transactions with a lot number")
+	}
+	if req.Serialized && req.Stock != 0 {
+		return errors.New("serialized products start without stock, receive it through IN transactions with serial numbers")
+	}
+	if req.Unit != "" {
+		unit, err := s.units.CatalogUnit(req.Unit)
+		if err != nil {
+			return err
+		}
+		req.Unit = unit
+	}
 
 	// 2. Cek Duplikasi SKU (Business Logic Validation)
 	existing, _ := s.productRepo.FindBySKU(req.SKU)
 	if existing != nil && existing.ID != uuid.Nil {
+		if existing.DeletedAt.Valid {
+			return errors.New("SKU belongs to a deleted product, restore it instead")
+		}
 		return errors.New("SKU already exists")
 	}
 
@@ -61,105 +169,250 @@ func (s *inventoryService) CreateProduct(req *model.Product, userID, userName, u
 	req.UpdatedBy = userID
 	req.CreatedByUserID = &userID
 	req.UpdatedByUserID = &userID
+	req.Balances = nil
 
-	// 4. Simpan ke Database
-	if err := s.productRepo.Create(req); err != nil {
+	// Initial stock goes to the default location
+	location, err := s.locations.ResolveLocation(nil)
+	if err != nil {
 		return err
 	}
 
-	// 5. Broadcast ke WebSocket dengan user info
-	go func() {
-		payload := map[string]interface{}{
-			"type":   "stock_update",
-			"action": "product_created",
-			"product": map[string]interface{}{
-				"id":    req.ID,
-				"sku":   req.SKU,
-				"name":  req.Name,
-				"stock": req.Stock,
-				"price": req.Price,
-			},
-			"user": map[string]interface{}{
-				"id":    userID,
-				"name":  userName,
-				"email": userEmail,
-			},
-			"message": fmt.Sprintf("%s created product '%s'", userName, req.Name),
-		}
-		msg, _ := json.Marshal(payload)
-		s.wsHub.Broadcast <- msg
-	}()
+	// 4. Simpan ke Database + 5. Broadcast ke WebSocket dengan user info (via outbox, same tx)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(req).Error; err != nil {
+			return err
+		}
+		if _, err := s.stockRepo.LockBalance(tx, req.ID, location.ID); err != nil {
+			return err
+		}
+		if err := s.stockRepo.SetQuantity(tx, req.ID, location.ID, req.Stock); err != nil {
+			return err
+		}
+
+		event := events.New(events.ProductCreated{
+			Product: productSummary(req),
+			Message: fmt.Sprintf("%s created product '%s'", userName, req.Name),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", ws.TopicProducts, ws.ProductTopic(req.ID))
+	})
+	if err != nil {
+		return err
+	}
 
+	s.outbox.Wake()
 	return nil
 }
 
-func (s *inventoryService) UpdateProduct(id uuid.UUID, req *model.Product, userID, userName, userEmail string) (*model.Product, error) {
+// UpdateProductRequest is a product plus the stock the client sent, if any. Stock
+// used to be editable here; a client still sending a different value gets an error
+// instead of having it silently ignored.
+type UpdateProductRequest struct {
+	model.Product
+	Stock *decimal.Decimal `json:"stock"`
+}
+
+func (s *inventoryService) UpdateProduct(id uuid.UUID, in *UpdateProductRequest, userID, userName, userEmail string) (*model.Product, error) {
+	req := &in.Product
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return nil, fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+	if err := validateReorderLevels(req); err != nil {
+		return nil, err
+	}
+	if req.Unit != "" {
+		unit, err := s.units.CatalogUnit(req.Unit)
+		if err != nil {
+			return nil, err
+		}
+		req.Unit = unit
+	}
+
 	var updatedProduct *model.Product
 
 	// Gunakan Transaction Block dengan Locking
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		var existing model.Product
 		// 1. Cari & Lock Product (Pessimistic Locking)
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&existing, "id = ?", id).Error; err != nil {
-			return errors.New("product not found")
+		locked, err := s.productRepo.FindByIDForUpdate(tx, id, false)
+		if err != nil {
+			return ErrProductNotFound
+		}
+		existing := *locked
+		if in.Stock != nil && *in.Stock != existing.Stock {
+			return fmt.Errorf("%w (current stock: %s)", ErrStockNotEditable, existing.Stock)
 		}
 
 		// 2. Track perubahan stock untuk broadcast
 		oldStock := existing.Stock
 
-		// 3. Update fields
+		// 3. Update fields. Stock is not editable here: it is the total of the
+		// location balances and only changes through transactions.
 		existing.Name = req.Name
 		existing.SKU = req.SKU
-		existing.Stock = req.Stock
+		if !strings.EqualFold(req.Unit, existing.Unit) {
+			// Stock and unit conversions are expressed in the base unit
+			if existing.Stock != 0 {
+				return errors.New("the base unit can only be changed while the product has no stock")
+			}
+			_, conversions, err := s.units.GetProductUnits(existing.ID)
+			if err != nil {
+				return err
+			}
+			if len(conversions) > 0 {
+				return errors.New("remove the product's unit conversions before changing its base unit")
+			}
+		}
 		existing.Unit = req.Unit
 		existing.Price = req.Price
+		existing.ReorderPoint = req.ReorderPoint
+		existing.ReorderQty = req.ReorderQty
+		existing.MinStock = req.MinStock
+		existing.MaxStock = req.MaxStock
+		if req.TrackLots != existing.TrackLots {
+			// Existing stock has no lots to pick from
+			if existing.Stock != 0 {
+				return errors.New("lot tracking can only be switched while the product has no stock")
+			}
+			existing.TrackLots = req.TrackLots
+		}
+		if req.Serialized != existing.Serialized {
+			// Existing stock has no serial numbers
+			if existing.Stock != 0 {
+				return errors.New("serial tracking can only be switched while the product has no stock")
+			}
+			existing.Serialized = req.Serialized
+		}
 		existing.UpdatedBy = userID
 		existing.UpdatedByUserID = &userID
 
-		// 4. Simpan ke database (pakai tx)
-		if err := s.productRepo.UpdateStock(tx, existing.ID, existing.Stock, userID); err != nil {
-			// Note: productRepo.Update mungkin tidak support tx arg standard,
-			// sebaiknya kita pakai tx.Save langsung disini untuk konsistensi locking
-			return tx.Save(&existing).Error
+		// 4. Simpan ke database (pakai tx, masih di bawah lock)
+		if err := tx.Save(&existing).Error; err != nil {
+			return err
 		}
 
 		updatedProduct = &existing
 
-		// 5. Broadcast ke WebSocket (move inside success path but outside tx usually,
-		// to avoid broadcast on rollback. But we use goroutine so it's fine)
-		go func() {
-			payload := map[string]interface{}{
-				"type":   "stock_update",
-				"action": "product_updated",
-				"product": map[string]interface{}{
-					"id":        existing.ID,
-					"sku":       existing.SKU,
-					"name":      existing.Name,
-					"old_stock": oldStock,
-					"new_stock": existing.Stock,
-					"price":     existing.Price,
-				},
-				"user": map[string]interface{}{
-					"id":    userID,
-					"name":  userName,
-					"email": userEmail,
-				},
-				"message": fmt.Sprintf("%s updated product '%s'", userName, existing.Name),
-			}
-			msg, _ := json.Marshal(payload)
-			s.wsHub.Broadcast <- msg
-		}()
-
-		return nil
+		// 5. Broadcast ke WebSocket via outbox: written in this tx, relayed only after commit
+		event := events.New(events.ProductUpdated{
+			Product:  productSummary(&existing),
+			OldStock: oldStock,
+			NewStock: existing.Stock,
+			Message:  fmt.Sprintf("%s updated product '%s'", userName, existing.Name),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", ws.TopicProducts, ws.ProductTopic(existing.ID))
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
+	s.outbox.Wake()
 	return updatedProduct, nil
 }
 
+// ArchiveProduct hides a product from GET /products and blocks new transactions on it,
+// while its transaction history stays intact
+func (s *inventoryService) ArchiveProduct(id uuid.UUID, userID, userName, userEmail string) (*model.Product, error) {
+	var archived *model.Product
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		product, err := s.productRepo.FindByIDForUpdate(tx, id, false)
+		if err != nil {
+			return ErrProductNotFound
+		}
+		if product.IsArchived() {
+			return ErrProductArchived
+		}
+
+		if err := s.productRepo.SetArchived(tx, product.ID, true, userID); err != nil {
+			return err
+		}
+		now := time.Now()
+		product.ArchivedAt = &now
+		product.ArchivedBy = userID
+		archived = product
+
+		event := events.New(events.ProductArchived{
+			Product: productSummary(product),
+			Message: fmt.Sprintf("%s archived product '%s'", userName, product.Name),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", ws.TopicProducts, ws.ProductTopic(product.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return archived, nil
+}
+
+// RestoreProduct brings back an archived or soft-deleted product
+func (s *inventoryService) RestoreProduct(id uuid.UUID, userID, userName, userEmail string) (*model.Product, error) {
+	var restored *model.Product
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		product, err := s.productRepo.FindByIDForUpdate(tx, id, true)
+		if err != nil {
+			return ErrProductNotFound
+		}
+		if !product.DeletedAt.Valid && !product.IsArchived() {
+			return errors.New("product is neither archived nor deleted")
+		}
+
+		if err := s.productRepo.Restore(tx, product.ID, userID); err != nil {
+			return err
+		}
+		product.DeletedAt = gorm.DeletedAt{}
+		product.DeletedBy = ""
+		product.ArchivedAt = nil
+		product.ArchivedBy = ""
+		restored = product
+
+		event := events.New(events.ProductRestored{
+			Product: productSummary(product),
+			Message: fmt.Sprintf("%s restored product '%s'", userName, product.Name),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", ws.TopicProducts, ws.ProductTopic(product.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return restored, nil
+}
+
+// DeleteProduct soft-deletes a product. Only products without stock can be deleted,
+// otherwise the stock would silently disappear from valuation.
+func (s *inventoryService) DeleteProduct(id uuid.UUID, userID, userName, userEmail string) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock so a concurrent IN can't add stock between the check and the delete
+		product, err := s.productRepo.FindByIDForUpdate(tx, id, false)
+		if err != nil {
+			return ErrProductNotFound
+		}
+		if product.Stock != 0 {
+			return ErrProductHasStock
+		}
+
+		if err := s.productRepo.SoftDelete(tx, product.ID, userID); err != nil {
+			return err
+		}
+
+		event := events.New(events.ProductDeleted{
+			Product: productSummary(product),
+			Message: fmt.Sprintf("%s deleted product '%s'", userName, product.Name),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", ws.TopicProducts, ws.ProductTopic(product.ID))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.outbox.Wake()
+	return nil
+}
+
 func (s *inventoryService) RecordTransaction(req *model.Transaction, userID, userName, userEmail string) error {
 	// 1. Validasi Input
 	if errs := validator.ValidateStruct(req); len(errs) > 0 {
@@ -170,101 +423,848 @@ func (s *inventoryService) RecordTransaction(req *model.Transaction, userID, use
 	}
 
 	// 1b. Strict Validation for Payment and Logic
-	// User requested "bisa diisi 0" (can be 0) for now until payment gateway is setup.
-	if req.PaymentMethod != "CASH" && req.PaymentMethod != "TRANSFER" && req.PaymentMethod != "0" && req.PaymentMethod != "" {
-		return errors.New("invalid payment method: must be CASH, TRANSFER, or 0")
+	if err := validatePaymentMethod(req.PaymentMethod); err != nil {
+		return err
 	}
+	if err := s.resolveDirection(req); err != nil {
+		return err
+	}
+
+	// Transactions without a location go to the default one (clients predating locations)
+	location, err := s.locations.ResolveLocation(req.LocationID)
+	if err != nil {
+		return err
+	}
+	req.LocationID = &location.ID
+	req.Location = nil
+	req.DocumentID = nil
 
 	// Gunakan Transaction Block (Atomic Operation)
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		var product model.Product
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&product, "id = ?", req.ProductID).Error; err != nil {
-			return errors.New("product not found")
-		}
-
-		// Calculate Total Amount accurately (Snapshot)
-		req.TotalAmount = product.Price * int64(req.Quantity)
-
-		// B. Hitung Logic Stok
-		newStock := product.Stock
-		if req.Type == model.TxIn {
-			newStock += req.Quantity
-		} else if req.Type == model.TxOut {
-			if product.Stock < req.Quantity {
-				return errors.New("insufficient stock remaining")
-			}
-			newStock -= req.Quantity
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock order: product first, then its balance (see repository.StockRepository)
+		product, err := s.productRepo.FindByIDForUpdate(tx, req.ProductID, false)
+		if err != nil {
+			return ErrProductNotFound
+		}
+		if product.IsArchived() {
+			return ErrProductArchived
 		}
 
-		// C. Update Stok Product
-		if err := s.productRepo.UpdateStock(tx, product.ID, newStock, userID); err != nil {
+		applied, err := s.applyTransaction(tx, req, product, location, userID)
+		if err != nil {
 			return err
 		}
 
-		// D. Simpan Log Transaksi dengan user ID
-		req.CreatedBy = userID
-		req.UpdatedBy = userID
-		req.CreatedByUserID = &userID
-		if err := tx.Create(req).Error; err != nil {
+		// E. Broadcast ke WebSocket dengan user info (via outbox, relayed after commit)
+		actionVerb := "added"
+		if req.Direction == model.TxOut {
+			actionVerb = "removed"
+		}
+		actor := &events.Actor{ID: userID, Name: userName, Email: userEmail}
+
+		// Broadcast Stock Update (no financial fields, goes to every product subscriber)
+		stockEvent := events.New(events.TransactionCreated{
+			TransactionID: req.ID,
+			Type:          string(req.Type),
+			Direction:     string(req.Direction),
+			ReasonCode:    req.ReasonCode,
+			Quantity:      req.Quantity,
+			Unit:          req.Unit,
+			UnitQuantity:  req.UnitQuantity,
+			ProductID:     product.ID,
+			ProductName:   product.Name,
+			ProductSKU:    product.SKU,
+			NewStock:      applied.newStock,
+			LocationID:    location.ID,
+			LocationName:  location.Name,
+			LocationStock: applied.newLocationStock,
+			Lots:          lotQuantities(applied.picks),
+			Serials:       serialNumbers(applied.units),
+			Message:       fmt.Sprintf("%s %s %s of '%s' at %s (%s)", userName, actionVerb, quantityText(req), product.Name, location.Name, transactionLabel(req)),
+		}, actor)
+		if err := s.outbox.Enqueue(tx, stockEvent, "", ws.TopicProducts, ws.ProductTopic(product.ID), ws.LocationTopic(location.ID)); err != nil {
+			return err
+		}
+
+		// Broadcast Financial Update (Notify that financial stats might have changed)
+		// Clients should re-fetch /api/finance/stats or we can push a flag
+		// Amounts only reach users allowed to view transactions.
+		// Stock-only movements (adjustments, returns) don't change income/expense.
+		if !req.Type.IsStockOnly() {
+			finEvent := events.New(events.FinancialUpdate{
+				TransactionID: req.ID,
+				Type:          string(req.Type),
+				TotalAmount:   req.TotalAmount,
+				PaymentMethod: req.PaymentMethod,
+				ProductID:     product.ID,
+				Message:       "Financial stats updated due to new transaction",
+			}, actor)
+			if err := s.outbox.Enqueue(tx, finEvent, "transaction:view", ws.TopicFinance); err != nil {
+				return err
+			}
+		}
+
+		return enqueueLowStockAlerts(tx, s.outbox, applied, location, actor)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.outbox.Wake()
+	return nil
+}
+
+// RecordDocument records every line of a document in one database transaction: either
+// all products move or none does. Clients get one aggregated event instead of one per line.
+func (s *inventoryService) RecordDocument(req *model.TransactionDocument, userID, userName, userEmail string) error {
+	if err := validatePaymentMethod(req.PaymentMethod); err != nil {
+		return err
+	}
+
+	// Type and payment method are set on the document, not per line
+	for i := range req.Lines {
+		req.Lines[i].Type = req.Type
+		req.Lines[i].Direction = req.Type
+		req.Lines[i].ReasonCode = ""
+		req.Lines[i].PaymentMethod = req.PaymentMethod
+	}
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+	seen := make(map[uuid.UUID]bool, len(req.Lines))
+	for _, line := range req.Lines {
+		if seen[line.ProductID] {
+			return errors.New("each product can only appear once per document")
+		}
+		seen[line.ProductID] = true
+	}
+
+	location, err := s.locations.ResolveLocation(req.LocationID)
+	if err != nil {
+		return err
+	}
+	req.LocationID = &location.ID
+	req.Location = nil
+	req.Number = documentNumber("TRX")
+	req.TotalAmount = 0
+	req.CreatedBy = userID
+	req.UpdatedBy = userID
+	req.CreatedByUserID = &userID
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock every product first, sorted by ID (same order as transfer dispatch), so
+		// concurrent documents sharing products can't deadlock. Balances, lots and
+		// serials are only ever locked by the holder of their product's lock.
+		productIDs := make([]uuid.UUID, 0, len(req.Lines))
+		for _, line := range req.Lines {
+			productIDs = append(productIDs, line.ProductID)
+		}
+		sort.Slice(productIDs, func(i, j int) bool {
+			return productIDs[i].String() < productIDs[j].String()
+		})
+		products := make(map[uuid.UUID]*model.Product, len(productIDs))
+		for _, id := range productIDs {
+			product, err := s.productRepo.FindByIDForUpdate(tx, id, false)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrProductNotFound, id)
+			}
+			if product.IsArchived() {
+				return fmt.Errorf("%w: '%s'", ErrProductArchived, product.Name)
+			}
+			products[id] = product
+		}
+
+		if err := s.transactionRepo.CreateDocument(tx, req); err != nil {
 			return err
 		}
 
-		// E. Broadcast ke WebSocket dengan user info
-		go func() {
-			actionType := "IN"
-			actionVerb := "added"
-			if req.Type == model.TxOut {
-				actionType = "OUT"
-				actionVerb = "removed"
+		// Lines keep the order they were entered in (receipt order)
+		applied := make([]*appliedTransaction, len(req.Lines))
+		for i := range req.Lines {
+			line := &req.Lines[i]
+			line.LocationID = &location.ID
+			line.Location = nil
+			line.DocumentID = &req.ID
+			line.Note = strings.TrimSpace(line.Note)
+			if line.Note == "" {
+				line.Note = req.Note
 			}
 
-			// Broadcast Stock Update
-			stockPayload := map[string]interface{}{
-				"type":   "stock_update",
-				"action": "transaction_created",
-				"transaction": map[string]interface{}{
-					"id":             req.ID,
-					"type":           actionType,
-					"quantity":       req.Quantity,
-					"total_amount":   req.TotalAmount,
-					"payment_method": req.PaymentMethod,
-					"product_id":     product.ID,
-					"product": map[string]interface{}{
-						"name": product.Name,
-						"sku":  product.SKU,
-					},
-					"new_stock": newStock,
-				},
-				"user": map[string]interface{}{
-					"id":    userID,
-					"name":  userName,
-					"email": userEmail,
-				},
-				"message": fmt.Sprintf("%s %s %d units of '%s' (%s)", userName, actionVerb, req.Quantity, product.Name, actionType),
+			a, err := s.applyTransaction(tx, line, products[line.ProductID], location, userID)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", i+1, err)
 			}
-			stockMsg, _ := json.Marshal(stockPayload)
-			s.wsHub.Broadcast <- stockMsg
-
-			// Broadcast Financial Update (Notify that financial stats might have changed)
-			// Clients should re-fetch /api/finance/stats or we can push a flag
-			finPayload := map[string]interface{}{
-				"type":    "financial_update",
-				"message": "Financial stats updated due to new transaction",
+			applied[i] = a
+			req.TotalAmount += line.TotalAmount
+		}
+		if err := s.transactionRepo.SetDocumentTotal(tx, req.ID, req.TotalAmount); err != nil {
+			return err
+		}
+
+		actionType := "IN"
+		actionVerb := "received"
+		if req.Type == model.TxOut {
+			actionType = "OUT"
+			actionVerb = "issued"
+		}
+		actor := &events.Actor{ID: userID, Name: userName, Email: userEmail}
+
+		// One stock event for the whole document, no amounts (see RecordTransaction)
+		lines := make([]events.DocumentLine, len(req.Lines))
+		topics := []string{ws.TopicProducts, ws.LocationTopic(location.ID)}
+		for i, line := range req.Lines {
+			product := products[line.ProductID]
+			lines[i] = events.DocumentLine{
+				TransactionID: line.ID,
+				ProductID:     product.ID,
+				ProductName:   product.Name,
+				ProductSKU:    product.SKU,
+				Quantity:      line.Quantity,
+				Unit:          line.Unit,
+				UnitQuantity:  line.UnitQuantity,
+				NewStock:      applied[i].newStock,
+				LocationStock: applied[i].newLocationStock,
+				Lots:          lotQuantities(applied[i].picks),
+				Serials:       serialNumbers(applied[i].units),
 			}
-			finMsg, _ := json.Marshal(finPayload)
-			s.wsHub.Broadcast <- finMsg
-		}()
+			topics = append(topics, ws.ProductTopic(product.ID))
+		}
+		stockEvent := events.New(events.TransactionDocumentCreated{
+			DocumentID:   req.ID,
+			Number:       req.Number,
+			Type:         actionType,
+			LocationID:   location.ID,
+			LocationName: location.Name,
+			Lines:        lines,
+			Message:      fmt.Sprintf("%s %s document %s with %d products at %s (%s)", userName, actionVerb, req.Number, len(lines), location.Name, actionType),
+		}, actor)
+		if err := s.outbox.Enqueue(tx, stockEvent, "", topics...); err != nil {
+			return err
+		}
 
+		finEvent := events.New(events.FinancialUpdate{
+			DocumentID:    &req.ID,
+			Type:          actionType,
+			TotalAmount:   req.TotalAmount,
+			PaymentMethod: req.PaymentMethod,
+			Message:       "Financial stats updated due to new transaction document",
+		}, actor)
+		if err := s.outbox.Enqueue(tx, finEvent, "transaction:view", ws.TopicFinance); err != nil {
+			return err
+		}
+
+		for _, a := range applied {
+			if err := enqueueLowStockAlerts(tx, s.outbox, a, location, actor); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	s.outbox.Wake()
+	return nil
+}
+
+// VoidTransaction corrects a mistaken transaction: a reversal with the opposite type
+// and the same quantities, lots and serials is recorded and the original is marked as
+// voided. Returns the reversal.
+func (s *inventoryService) VoidTransaction(id uuid.UUID, reason, userID, userName, userEmail string) (*model.Transaction, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrVoidReasonMissing
+	}
+
+	var reversal *model.Transaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock order: the transaction row, then product, balance, lots and serials
+		// (same as RecordTransaction)
+		original, err := s.transactionRepo.FindByIDForUpdate(tx, id)
+		if err != nil {
+			return ErrTransactionNotFound
+		}
+		if original.IsVoided() {
+			return ErrTransactionVoided
+		}
+		if original.ReversalOfID != nil {
+			return ErrReversalNotVoidable
+		}
+		if original.Type == model.TxTransfer || original.TransferID != nil {
+			return ErrTransferNotVoidable
+		}
+
+		product, err := s.productRepo.FindByIDForUpdate(tx, original.ProductID, false)
+		if err != nil {
+			return ErrProductNotFound
+		}
+		location, err := s.locations.ResolveLocation(original.LocationID)
+		if err != nil {
+			return err
+		}
+		balance, err := s.stockRepo.LockBalance(tx, product.ID, location.ID)
+		if err != nil {
+			return err
+		}
+
+		// The reversal moves stock the other way. A purchase is reversed by a sale and
+		// vice versa, adjustments and returns keep their type and reason.
+		reverseDirection := model.TxOut
+		if original.Direction == model.TxOut {
+			reverseDirection = model.TxIn
+		}
+		reverseType := original.Type
+		if !original.Type.IsStockOnly() {
+			reverseType = reverseDirection
+		}
+		newLocationStock := balance.Quantity
+		if reverseDirection == model.TxIn {
+			newLocationStock += original.Quantity
+		} else {
+			if balance.Quantity < original.Quantity {
+				return fmt.Errorf("cannot void: only %s of '%s' left at %s, the received stock was already used", balance.Quantity, product.Name, location.Name)
+			}
+			// Stock held by reservations must stay on hand
+			reserved, err := s.reservationRepo.ReservedQuantity(tx, product.ID, location.ID, nil)
+			if err != nil {
+				return err
+			}
+			if balance.Quantity-reserved < original.Quantity {
+				return fmt.Errorf("cannot void: only %s of '%s' available at %s, %s is reserved", balance.Quantity-reserved, product.Name, location.Name, reserved)
+			}
+			newLocationStock -= original.Quantity
+		}
+
+		// Exactly the lots of the original go back (or out again)
+		var picks []lotPick
+		if len(original.Lots) > 0 {
+			ids := make([]uuid.UUID, len(original.Lots))
+			for i, txLot := range original.Lots {
+				ids[i] = txLot.LotID
+			}
+			lots, err := s.lotRepo.LockByIDs(tx, ids)
+			if err != nil {
+				return err
+			}
+			byID := make(map[uuid.UUID]*model.Lot, len(lots))
+			for i := range lots {
+				byID[lots[i].ID] = &lots[i]
+			}
+			for _, txLot := range original.Lots {
+				lot := byID[txLot.LotID]
+				if lot == nil {
+					return fmt.Errorf("lot %s not found", txLot.LotID)
+				}
+				if reverseDirection == model.TxIn {
+					lot.Quantity += txLot.Quantity
+				} else {
+					if lot.Quantity < txLot.Quantity {
+						return fmt.Errorf("cannot void: lot %s has only %s left", lot.LotNumber, lot.Quantity)
+					}
+					lot.Quantity -= txLot.Quantity
+				}
+				if err := s.lotRepo.SetQuantity(tx, lot.ID, lot.Quantity); err != nil {
+					return err
+				}
+				picks = append(picks, lotPick{lot: lot, quantity: txLot.Quantity})
+			}
+		}
+
+		// Serials: received units leave the inventory again, issued units come back
+		var units []model.SerialNumber
+		if len(original.SerialUnits) > 0 {
+			serials := make([]string, 0, len(original.SerialUnits))
+			for _, txSerial := range original.SerialUnits {
+				if txSerial.Serial != nil {
+					serials = append(serials, txSerial.Serial.Serial)
+				}
+			}
+			if reverseDirection == model.TxOut {
+				units, err = moveSerials(tx, s.serialRepo, product.ID, serials, model.SerialInStock, location.ID, model.SerialOut, location.ID)
+			} else {
+				units, err = moveSerials(tx, s.serialRepo, product.ID, serials, model.SerialOut, location.ID, model.SerialInStock, location.ID)
+			}
+			if err != nil {
+				return fmt.Errorf("cannot void: %w", err)
+			}
+		}
+
+		if err := s.stockRepo.SetQuantity(tx, product.ID, location.ID, newLocationStock); err != nil {
+			return err
+		}
+		newStock, err := s.stockRepo.SyncProductStock(tx, product.ID, userID)
+		if err != nil {
+			return err
+		}
+		applied := newAppliedTransaction(product, balance, reverseDirection, newStock, newLocationStock)
+		applied.picks = picks
+		applied.units = units
+
+		reversal = &model.Transaction{
+			ProductID:     product.ID,
+			LocationID:    &location.ID,
+			Type:          reverseType,
+			Direction:     reverseDirection,
+			ReasonCode:    original.ReasonCode,
+			Quantity:      original.Quantity,
+			TotalAmount:   original.TotalAmount,
+			PaymentMethod: original.PaymentMethod,
+			Note:          fmt.Sprintf("Void of transaction %s: %s", original.ID, reason),
+			Unit:          original.Unit,
+			UnitQuantity:  original.UnitQuantity,
+			UnitFactor:    original.UnitFactor,
+			ReversalOfID:  &original.ID,
+		}
+		reversal.CreatedBy = userID
+		reversal.UpdatedBy = userID
+		reversal.CreatedByUserID = &userID
+		if err := tx.Create(reversal).Error; err != nil {
+			return err
+		}
+		if err := linkLotsAndSerials(tx, s.lotRepo, s.serialRepo, reversal, picks, units); err != nil {
+			return err
+		}
+		if err := s.transactionRepo.MarkVoided(tx, original.ID, reversal.ID, reason, userID); err != nil {
+			return err
+		}
+		// A voided sale that picked a reservation puts the picked quantity back on hold
+		if err := restoreReservation(tx, s.reservationRepo, original, userID); err != nil {
+			return err
+		}
+		// The document total only counts the lines still standing
+		if original.DocumentID != nil {
+			if err := s.transactionRepo.RecomputeDocumentTotal(tx, *original.DocumentID); err != nil {
+				return err
+			}
+		}
+
+		actor := &events.Actor{ID: userID, Name: userName, Email: userEmail}
+		stockEvent := events.New(events.TransactionVoided{
+			TransactionID: original.ID,
+			ReversalID:    reversal.ID,
+			Type:          string(reverseType),
+			Direction:     string(reverseDirection),
+			Quantity:      reversal.Quantity,
+			ProductID:     product.ID,
+			ProductName:   product.Name,
+			ProductSKU:    product.SKU,
+			NewStock:      newStock,
+			LocationID:    location.ID,
+			LocationName:  location.Name,
+			LocationStock: newLocationStock,
+			Lots:          lotQuantities(picks),
+			Serials:       serialNumbers(units),
+			Reason:        reason,
+			Message:       fmt.Sprintf("%s voided a transaction of %s '%s' at %s: %s", userName, quantityText(original), product.Name, location.Name, reason),
+		}, actor)
+		if err := s.outbox.Enqueue(tx, stockEvent, "", ws.TopicProducts, ws.ProductTopic(product.ID), ws.LocationTopic(location.ID)); err != nil {
+			return err
+		}
+
+		if !original.Type.IsStockOnly() {
+			finEvent := events.New(events.FinancialUpdate{
+				TransactionID: original.ID,
+				Type:          string(original.Type),
+				TotalAmount:   original.TotalAmount,
+				PaymentMethod: original.PaymentMethod,
+				ProductID:     product.ID,
+				Voided:        true,
+				Message:       "Financial stats updated due to voided transaction",
+			}, actor)
+			if err := s.outbox.Enqueue(tx, finEvent, "transaction:view", ws.TopicFinance); err != nil {
+				return err
+			}
+		}
+
+		return enqueueLowStockAlerts(tx, s.outbox, applied, location, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return reversal, nil
+}
+
+// appliedTransaction is what recording one transaction changed, for its events
+type appliedTransaction struct {
+	product          *model.Product
+	picks            []lotPick
+	units            []model.SerialNumber
+	newStock         decimal.Decimal
+	newLocationStock decimal.Decimal
+	// Reorder point crossed by an OUT, overall and at the location
+	crossedReorderPoint         bool
+	crossedLocationReorderPoint bool
+	locationReorderPoint        decimal.Decimal
+}
+
+// newAppliedTransaction notes the reorder points an OUT crossed, from the product and
+// balance as they were before the movement, then updates the product's stock
+func newAppliedTransaction(product *model.Product, balance *model.StockBalance, direction model.TransactionType, newStock, newLocationStock decimal.Decimal) *appliedTransaction {
+	applied := &appliedTransaction{
+		product:          product,
+		newStock:         newStock,
+		newLocationStock: newLocationStock,
+	}
+	applied.crossedReorderPoint = direction == model.TxOut && !product.IsLowStock() && newStock <= product.ReorderPoint
+	if direction == model.TxOut && balance.ReorderPoint != nil &&
+		balance.Quantity > *balance.ReorderPoint && newLocationStock <= *balance.ReorderPoint {
+		applied.crossedLocationReorderPoint = true
+		applied.locationReorderPoint = *balance.ReorderPoint
+	}
+	product.Stock = newStock
+	return applied
+}
+
+// applyTransaction moves the stock of one transaction and saves it with its lots and
+// serials. The product must be locked by the caller; balances, lots and serials are
+// locked here, in that order.
+func (s *inventoryService) applyTransaction(tx *gorm.DB, req *model.Transaction, product *model.Product, location *model.Location, userID string) (*appliedTransaction, error) {
+	req.Lots = nil
+	req.SerialUnits = nil
+	req.ReservedQty = decimal.Zero
+
+	expiry, err := parseExpiryDate(req.ExpiryDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Quantity comes in the entered unit, everything below works in base units
+	unit, factor, err := s.units.ResolveUnit(product, req.Unit)
+	if err != nil {
+		return nil, err
+	}
+	req.Unit = unit
+	req.UnitFactor = factor
+	req.UnitQuantity = req.Quantity
+	if req.Quantity, err = req.Quantity.MulInt(factor); err != nil {
+		return nil, err
+	}
+
+	balance, err := s.stockRepo.LockBalance(tx, product.ID, location.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sales can't take stock held by reservations, except the one they pick.
+	// Adjustments and returns record stock that is physically gone, reserved or not.
+	reserved := decimal.Zero
+	if req.Type == model.TxOut {
+		if reserved, err = claimReservation(tx, s.reservationRepo, req, location, userID); err != nil {
+			return nil, err
+		}
+	} else if req.ReservationID != nil {
+		return nil, errors.New("only OUT transactions can pick a reservation")
+	}
+
+	// Lot-tracked products: IN goes into the given lot, OUT picks lots FEFO
+	// (or the given lot). Lots are locked after the balance.
+	var picks []lotPick
+	if product.TrackLots {
+		if req.Direction == model.TxIn {
+			if req.LotNumber == "" {
+				return nil, ErrLotNumberMissing
+			}
+			lot, err := receiveLot(tx, s.lotRepo, product.ID, location.ID, req.LotNumber, expiry, req.Quantity)
+			if err != nil {
+				return nil, err
+			}
+			picks = []lotPick{{lot: lot, quantity: req.Quantity}}
+		} else if req.Direction == model.TxOut {
+			picks, err = pickLots(tx, s.lotRepo, product.ID, location.ID, req.Quantity, req.LotNumber, req.AllowExpired)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else if req.LotNumber != "" || expiry != nil {
+		return nil, ErrLotsNotTracked
+	}
+
+	// Serialized products: IN registers the units, OUT takes out exactly the given ones.
+	// Serials are locked last.
+	var units []model.SerialNumber
+	if product.Serialized {
+		serials, err := normalizeSerials(req.Serials, req.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		if req.Direction == model.TxIn {
+			units, err = registerSerials(tx, s.serialRepo, product.ID, location.ID, serials)
+		} else if req.Direction == model.TxOut {
+			units, err = moveSerials(tx, s.serialRepo, product.ID, serials, model.SerialInStock, location.ID, model.SerialOut, location.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else if len(req.Serials) > 0 {
+		return nil, ErrSerialsNotTracked
+	}
+
+	// Calculate Total Amount accurately (Snapshot)
+	if req.TotalAmount, err = req.Quantity.MulAmount(product.Price); err != nil {
+		return nil, err
+	}
+
+	// B. Hitung Logic Stok (di lokasi transaksi)
+	newLocationStock := balance.Quantity
+	if req.Direction == model.TxIn {
+		newLocationStock += req.Quantity
+	} else if req.Direction == model.TxOut {
+		if balance.Quantity < req.Quantity {
+			return nil, fmt.Errorf("insufficient stock remaining for '%s' at %s", product.Name, location.Name)
+		}
+		if balance.Quantity-reserved < req.Quantity {
+			return nil, fmt.Errorf("insufficient stock available for '%s' at %s: %s on hand, %s reserved", product.Name, location.Name, balance.Quantity, reserved)
+		}
+		newLocationStock -= req.Quantity
+	}
+
+	// C. Update Stok: balance di lokasi, lalu total di product
+	if err := s.stockRepo.SetQuantity(tx, product.ID, location.ID, newLocationStock); err != nil {
+		return nil, err
+	}
+	newStock, err := s.stockRepo.SyncProductStock(tx, product.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	applied := newAppliedTransaction(product, balance, req.Direction, newStock, newLocationStock)
+	applied.picks = picks
+	applied.units = units
+
+	// D. Simpan Log Transaksi dengan user ID
+	req.CreatedBy = userID
+	req.UpdatedBy = userID
+	req.CreatedByUserID = &userID
+	if err := tx.Create(req).Error; err != nil {
+		return nil, err
+	}
+	if err := linkLotsAndSerials(tx, s.lotRepo, s.serialRepo, req, picks, units); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// linkLotsAndSerials records which lots and serialized units a saved transaction moved
+func linkLotsAndSerials(tx *gorm.DB, lotRepo repository.LotRepository, serialRepo repository.SerialRepository, t *model.Transaction, picks []lotPick, units []model.SerialNumber) error {
+	if len(picks) > 0 {
+		txLots := make([]model.TransactionLot, len(picks))
+		for i, pick := range picks {
+			txLots[i] = model.TransactionLot{TransactionID: t.ID, LotID: pick.lot.ID, Quantity: pick.quantity}
+		}
+		if err := lotRepo.CreateTransactionLots(tx, txLots); err != nil {
+			return err
+		}
+		t.Lots = txLots
+	}
+	if len(units) > 0 {
+		txSerials := make([]model.TransactionSerial, len(units))
+		for i, unit := range units {
+			txSerials[i] = model.TransactionSerial{TransactionID: t.ID, SerialID: unit.ID}
+		}
+		if err := serialRepo.CreateTransactionSerials(tx, txSerials); err != nil {
+			return err
+		}
+		t.SerialUnits = txSerials
+	}
+	return nil
+}
+
+// enqueueLowStockAlerts alerts once when an OUT crosses the reorder point, of the
+// product and of the location balance
+func enqueueLowStockAlerts(tx *gorm.DB, outbox *Outbox, applied *appliedTransaction, location *model.Location, actor *events.Actor) error {
+	product := applied.product
+	if applied.crossedReorderPoint {
+		alert := events.New(events.LowStockAlert{
+			Product:           productSummary(product),
+			ReorderPoint:      product.ReorderPoint,
+			ReorderQty:        product.ReorderQty,
+			SuggestedOrderQty: product.SuggestedOrderQty(),
+			Critical:          product.MinStock != nil && applied.newStock < *product.MinStock,
+			Message:           fmt.Sprintf("'%s' is low on stock: %s left (reorder point %s)", product.Name, applied.newStock, product.ReorderPoint),
+		}, actor)
+		if err := outbox.Enqueue(tx, alert, "", ws.TopicProducts, ws.ProductTopic(product.ID)); err != nil {
+			return err
+		}
+	}
+	if applied.crossedLocationReorderPoint {
+		locationStock := applied.newLocationStock
+		alert := events.New(events.LowStockAlert{
+			Product:           productSummary(product),
+			LocationID:        &location.ID,
+			LocationName:      location.Name,
+			LocationStock:     &locationStock,
+			ReorderPoint:      applied.locationReorderPoint,
+			ReorderQty:        product.ReorderQty,
+			SuggestedOrderQty: product.ReorderQty,
+			Message:           fmt.Sprintf("'%s' is low on stock at %s: %s left (reorder point %s)", product.Name, location.Name, locationStock, applied.locationReorderPoint),
+		}, actor)
+		if err := outbox.Enqueue(tx, alert, "", ws.TopicProducts, ws.ProductTopic(product.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveDirection sets the direction a transaction moves stock in and checks its
+// reason code. TRANSFER transactions are only recorded by stock transfers.
+func (s *inventoryService) resolveDirection(req *model.Transaction) error {
+	switch req.Type {
+	case model.TxIn, model.TxOut:
+		if req.Direction != "" && req.Direction != req.Type {
+			return fmt.Errorf("%s transactions can only move stock %s", req.Type, req.Type)
+		}
+		req.Direction = req.Type
+	case model.TxTransfer:
+		return errors.New("TRANSFER transactions are recorded by stock transfers, see /transfers")
+	default:
+		if req.Direction == "" {
+			return fmt.Errorf("%s transactions need a direction (IN or OUT)", req.Type)
+		}
+		// Not a sale or purchase, nothing is paid
+		req.PaymentMethod = ""
+	}
+
+	code, err := s.reasons.ResolveReason(req.Type, req.Direction, req.ReasonCode)
+	if err != nil {
+		return err
+	}
+	req.ReasonCode = code
+	return nil
+}
+
+// validatePaymentMethod: user requested "bisa diisi 0" (can be 0) for now until
+// payment gateway is setup
+func validatePaymentMethod(method string) error {
+	if method != "CASH" && method != "TRANSFER" && method != "0" && method != "" {
+		return errors.New("invalid payment method: must be CASH, TRANSFER, or 0")
+	}
+	return nil
+}
+
+func (s *inventoryService) ListDocuments(filter repository.DocumentFilter) ([]model.TransactionDocument, int64, error) {
+	return s.transactionRepo.FindDocuments(filter)
+}
+
+func (s *inventoryService) GetDocumentByID(id uuid.UUID) (*model.TransactionDocument, error) {
+	return s.transactionRepo.FindDocumentByID(id)
+}
+
+func (s *inventoryService) ListProducts(filter repository.ProductFilter) ([]model.Product, int64, error) {
+	return s.productRepo.FindAll(filter)
+}
+
+// GetLowStockReport lists active products at or below their reorder point,
+// overall or at one location
+func (s *inventoryService) GetLowStockReport(locationID *uuid.UUID) ([]LowStockItem, error) {
+	if locationID != nil {
+		return s.getLocationLowStockReport(*locationID)
+	}
+
+	products, err := s.productRepo.FindLowStock()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]LowStockItem, len(products))
+	for i := range products {
+		p := &products[i]
+		items[i] = LowStockItem{
+			ProductID:         p.ID,
+			SKU:               p.SKU,
+			Name:              p.Name,
+			Unit:              p.Unit,
+			Stock:             p.Stock,
+			ReorderPoint:      p.ReorderPoint,
+			ReorderQty:        p.ReorderQty,
+			MinStock:          p.MinStock,
+			MaxStock:          p.MaxStock,
+			SuggestedOrderQty: p.SuggestedOrderQty(),
+			Critical:          p.MinStock != nil && p.Stock < *p.MinStock,
+		}
+	}
+	return items, nil
+}
+
+func (s *inventoryService) getLocationLowStockReport(locationID uuid.UUID) ([]LowStockItem, error) {
+	if _, err := s.locations.ResolveLocation(&locationID); err != nil {
+		return nil, err
+	}
+	rows, err := s.stockRepo.FindLowStockAt(locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]LowStockItem, len(rows))
+	for i, row := range rows {
+		items[i] = LowStockItem{
+			ProductID:         row.ID,
+			SKU:               row.SKU,
+			Name:              row.Name,
+			Unit:              row.Unit,
+			LocationID:        &locationID,
+			Stock:             row.LocationQuantity,
+			ReorderPoint:      row.LocationReorderPoint,
+			ReorderQty:        row.ReorderQty,
+			SuggestedOrderQty: row.ReorderQty,
+		}
+	}
+	return items, nil
 }
 
-func (s *inventoryService) GetAllProducts() ([]model.Product, error) {
-	return s.productRepo.FindAll()
+// GetProductStock returns the product with its balance at every location, with the
+// quantity reserved there and what is left available
+func (s *inventoryService) GetProductStock(productID uuid.UUID) (*model.Product, []model.StockBalance, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, nil, ErrProductNotFound
+	}
+	balances, err := s.stockRepo.FindByProduct(productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	reserved, err := s.reservationRepo.ReservedByLocation(productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range balances {
+		balances[i].Reserved = reserved[balances[i].LocationID]
+		balances[i].Available = balances[i].Quantity - balances[i].Reserved
+	}
+	return product, balances, nil
+}
+
+// SetLocationReorderPoint overrides the product's reorder point at one location (nil clears it)
+func (s *inventoryService) SetLocationReorderPoint(productID, locationID uuid.UUID, reorderPoint *decimal.Decimal) error {
+	if reorderPoint != nil && *reorderPoint < 0 {
+		return errors.New("reorder_point must not be negative")
+	}
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return ErrProductNotFound
+	}
+	if _, err := s.locations.ResolveLocation(&locationID); err != nil {
+		return err
+	}
+	return s.stockRepo.SetReorderPoint(productID, locationID, reorderPoint)
 }
 
-func (s *inventoryService) GetAllTransactions() ([]model.Transaction, error) {
-	return s.transactionRepo.FindAll()
+func (s *inventoryService) ListTransactions(filter repository.TransactionFilter) ([]model.Transaction, *repository.Cursor, error) {
+	return s.transactionRepo.FindAll(filter)
+}
+
+// GetProductLedger returns a page of the product's stock movements with running balance
+func (s *inventoryService) GetProductLedger(productID uuid.UUID, locationID *uuid.UUID, after *repository.Cursor, limit int) (*model.Product, []repository.LedgerEntry, *repository.Cursor, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, nil, nil, ErrProductNotFound
+	}
+	entries, next, err := s.transactionRepo.FindLedger(productID, locationID, after, limit)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return product, entries, next, nil
 }
 
 func (s *inventoryService) GetTransactionByID(id uuid.UUID) (*model.Transaction, error) {
@@ -281,7 +1281,7 @@ func (s *inventoryService) GetFinancialStats(startDate, endDate time.Time) (map[
 	// 2. Get Current Valuation and other stats (using existing Dashboard logic or part of it)
 	// Usually dashboard stats are overall, but Valuation is snapshot.
 	// We can reuse GetDashboardStats for valuation.
-	stats, err := s.transactionRepo.GetDashboardStats()
+	stats, err := s.transactionRepo.GetDashboardStats(nil)
 	if err != nil {
 		return nil, err
 	}
@@ -294,3 +1294,63 @@ func (s *inventoryService) GetFinancialStats(startDate, endDate time.Time) (map[
 		"period_end":      endDate.Format("2006-01-02"),
 	}, nil
 }
+
+func (s *inventoryService) GetShrinkageReport(startDate, endDate time.Time, locationID *uuid.UUID) (*ShrinkageReport, error) {
+	byReason, byProduct, err := s.transactionRepo.GetShrinkage(startDate, endDate, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ShrinkageReport{
+		From:       startDate,
+		To:         endDate,
+		LocationID: locationID,
+		ByReason:   byReason,
+		ByProduct:  byProduct,
+	}
+	for _, row := range byReason {
+		report.NetValue += row.NetValue
+	}
+	return report, nil
+}
+
+// validateReorderLevels checks MinStock <= ReorderPoint <= MaxStock when set
+func validateReorderLevels(p *model.Product) error {
+	if p.MinStock != nil && *p.MinStock > p.ReorderPoint {
+		return errors.New("min_stock must not be greater than reorder_point")
+	}
+	if p.MaxStock != nil && p.ReorderPoint > *p.MaxStock {
+		return errors.New("reorder_point must not be greater than max_stock")
+	}
+	return nil
+}
+
+// quantityText describes a transaction quantity, in the entered unit when it isn't the base one
+func quantityText(t *model.Transaction) string {
+	if t.UnitFactor > 1 {
+		return fmt.Sprintf("%s %s (%s units)", t.UnitQuantity, t.Unit, t.Quantity)
+	}
+	return fmt.Sprintf("%s units", t.Quantity)
+}
+
+// transactionLabel names a transaction in event messages: IN, OUT, ADJUSTMENT OUT: DAMAGED
+func transactionLabel(t *model.Transaction) string {
+	if !t.Type.IsStockOnly() {
+		return string(t.Type)
+	}
+	if t.ReasonCode == "" {
+		return fmt.Sprintf("%s %s", t.Type, t.Direction)
+	}
+	return fmt.Sprintf("%s %s: %s", t.Type, t.Direction, t.ReasonCode)
+}
+
+// productSummary builds the product snapshot carried by product events
+func productSummary(p *model.Product) events.ProductSummary {
+	return events.ProductSummary{
+		ID:    p.ID,
+		SKU:   p.SKU,
+		Name:  p.Name,
+		Stock: p.Stock,
+		Price: p.Price,
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+)
+
+func TestVoidSaleRestoresReservation(t *testing.T) {
+	env := newTestEnv(t)
+	inventory := env.inventoryService()
+	product := env.addProduct("Soap", 2500)
+	env.setBalance(product, env.warehouse, qty(10), 0)
+	reservation := env.addReservation(product, env.warehouse, qty(5), time.Now().Add(time.Hour))
+
+	// The sale picks the whole reservation
+	sale := &model.Transaction{ProductID: product.ID, Type: model.TxOut, Quantity: qty(5), PaymentMethod: "CASH", ReservationID: &reservation.ID}
+	if err := inventory.RecordTransaction(sale, "u1", "Ana", "ana@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if r := env.store.reservations[reservation.ID]; r.Status != model.ReservationFulfilled || r.FulfilledQty != qty(5) {
+		t.Fatalf("reservation after sale = %s, fulfilled %s, want FULFILLED 5", r.Status, r.FulfilledQty)
+	}
+
+	reversal, err := inventory.VoidTransaction(sale.ID, "wrong customer", "u2", "Budi", "budi@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reversal.Type != model.TxIn || reversal.Direction != model.TxIn || reversal.Quantity != qty(5) || reversal.TotalAmount != sale.TotalAmount {
+		t.Errorf("reversal = %s %s %s worth %d, want IN 5 worth %d", reversal.Type, reversal.Direction, reversal.Quantity, reversal.TotalAmount, sale.TotalAmount)
+	}
+	if reversal.ReversalOfID == nil || *reversal.ReversalOfID != sale.ID {
+		t.Error("reversal does not link back to the sale")
+	}
+	original := env.store.transactions[sale.ID]
+	if !original.IsVoided() || original.ReversalID == nil || *original.ReversalID != reversal.ID || original.VoidReason != "wrong customer" {
+		t.Errorf("original not marked voided with its reversal: %+v", original)
+	}
+	if b := env.balance(product, env.warehouse); b.Quantity != qty(10) {
+		t.Errorf("balance = %s, want 10", b.Quantity)
+	}
+	if r := env.store.reservations[reservation.ID]; r.Status != model.ReservationActive || r.FulfilledQty != 0 || r.ReleasedAt != nil {
+		t.Errorf("reservation after void = %s, fulfilled %s, want ACTIVE 0", r.Status, r.FulfilledQty)
+	}
+
+	pending := env.store.pendingEventTypes(t)
+	if pending[len(pending)-2] != events.TypeTransactionVoided || pending[len(pending)-1] != events.TypeFinancialUpdate {
+		t.Errorf("events = %v, want the void to end with %s and %s", pending, events.TypeTransactionVoided, events.TypeFinancialUpdate)
+	}
+
+	if _, err := inventory.VoidTransaction(sale.ID, "again", "u2", "Budi", "budi@example.com"); !errors.Is(err, ErrTransactionVoided) {
+		t.Errorf("second void: %v, want %v", err, ErrTransactionVoided)
+	}
+	if _, err := inventory.VoidTransaction(reversal.ID, "undo", "u2", "Budi", "budi@example.com"); !errors.Is(err, ErrReversalNotVoidable) {
+		t.Errorf("void of the reversal: %v, want %v", err, ErrReversalNotVoidable)
+	}
+}
+
+func TestVoidPartialPickRestoresOnlyWhatItTook(t *testing.T) {
+	env := newTestEnv(t)
+	inventory := env.inventoryService()
+	product := env.addProduct("Soap", 2500)
+	env.setBalance(product, env.warehouse, qty(10), 0)
+	reservation := env.addReservation(product, env.warehouse, qty(5), time.Now().Add(time.Hour))
+
+	first := &model.Transaction{ProductID: product.ID, Type: model.TxOut, Quantity: qty(2), ReservationID: &reservation.ID}
+	second := &model.Transaction{ProductID: product.ID, Type: model.TxOut, Quantity: qty(2), ReservationID: &reservation.ID}
+	for _, sale := range []*model.Transaction{first, second} {
+		if err := inventory.RecordTransaction(sale, "u1", "Ana", "ana@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := inventory.VoidTransaction(first.ID, "duplicate", "u1", "Ana", "ana@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if r := env.store.reservations[reservation.ID]; r.Status != model.ReservationActive || r.FulfilledQty != qty(2) {
+		t.Errorf("reservation = %s, fulfilled %s, want ACTIVE 2", r.Status, r.FulfilledQty)
+	}
+	if b := env.balance(product, env.warehouse); b.Quantity != qty(8) {
+		t.Errorf("balance = %s, want 8", b.Quantity)
+	}
+}
+
+func TestVoidPurchaseKeepsReservedStock(t *testing.T) {
+	env := newTestEnv(t)
+	inventory := env.inventoryService()
+	product := env.addProduct("Soap", 2500)
+
+	purchase := &model.Transaction{ProductID: product.ID, Type: model.TxIn, Quantity: qty(5), PaymentMethod: "TRANSFER"}
+	if err := inventory.RecordTransaction(purchase, "u1", "Ana", "ana@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	env.addReservation(product, env.warehouse, qty(4), time.Now().Add(time.Hour))
+
+	_, err := inventory.VoidTransaction(purchase.ID, "wrong supplier", "u1", "Ana", "ana@example.com")
+	if err == nil || !strings.Contains(err.Error(), "is reserved") {
+		t.Fatalf("void of reserved stock: %v, want a reserved stock error", err)
+	}
+	if b := env.balance(product, env.warehouse); b.Quantity != qty(5) {
+		t.Errorf("balance = %s, want 5", b.Quantity)
+	}
+	if env.store.transactions[purchase.ID].IsVoided() {
+		t.Error("purchase was marked voided")
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/pkg/validator"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrLocationNotFound = errors.New("location not found")
+	ErrLocationInactive = errors.New("location is inactive")
+)
+
+type LocationService interface {
+	CreateLocation(req *model.Location, userID string) error
+	UpdateLocation(id uuid.UUID, req *UpdateLocationRequest, userID string) (*model.Location, error)
+	GetLocations(includeInactive bool) ([]model.Location, error)
+	GetLocation(id uuid.UUID) (*model.Location, error)
+	// ResolveLocation returns the given active location, or the default one when id is nil
+	ResolveLocation(id *uuid.UUID) (*model.Location, error)
+}
+
+type UpdateLocationRequest struct {
+	Code     string             `json:"code" validate:"required"`
+	Name     string             `json:"name" validate:"required"`
+	Type     model.LocationType `json:"type" validate:"required,oneof=STORE WAREHOUSE"`
+	Address  string             `json:"address"`
+	IsActive *bool              `json:"is_active"` // Optional
+}
+
+type locationService struct {
+	locationRepo repository.LocationRepository
+}
+
+func NewLocationService(locationRepo repository.LocationRepository) LocationService {
+	return &locationService{locationRepo: locationRepo}
+}
+
+func (s *locationService) CreateLocation(req *model.Location, userID string) error {
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+	if existing, err := s.locationRepo.FindByCode(req.Code); err == nil && existing != nil {
+		return errors.New("location code already exists")
+	}
+
+	// There is exactly one default location, the seeded one
+	req.IsDefault = false
+	req.IsActive = true
+	req.CreatedBy = userID
+	req.UpdatedBy = userID
+	return s.locationRepo.Create(req)
+}
+
+func (s *locationService) UpdateLocation(id uuid.UUID, req *UpdateLocationRequest, userID string) (*model.Location, error) {
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return nil, fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+
+	location, err := s.locationRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrLocationNotFound
+	}
+	if location.Code != req.Code {
+		if existing, err := s.locationRepo.FindByCode(req.Code); err == nil && existing != nil {
+			return nil, errors.New("location code already exists")
+		}
+	}
+	if req.IsActive != nil {
+		if location.IsDefault && !*req.IsActive {
+			return nil, errors.New("the default location cannot be deactivated")
+		}
+		location.IsActive = *req.IsActive
+	}
+
+	location.Code = req.Code
+	location.Name = req.Name
+	location.Type = req.Type
+	location.Address = req.Address
+	location.UpdatedBy = userID
+	if err := s.locationRepo.Update(location); err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+func (s *locationService) GetLocations(includeInactive bool) ([]model.Location, error) {
+	return s.locationRepo.FindAll(includeInactive)
+}
+
+// GetLocation returns a location whether active or not
+func (s *locationService) GetLocation(id uuid.UUID) (*model.Location, error) {
+	location, err := s.locationRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrLocationNotFound
+	}
+	return location, nil
+}
+
+func (s *locationService) ResolveLocation(id *uuid.UUID) (*model.Location, error) {
+	if id == nil {
+		location, err := s.locationRepo.FindDefault()
+		if err != nil {
+			return nil, ErrLocationNotFound
+		}
+		return location, nil
+	}
+
+	location, err := s.locationRepo.FindByID(*id)
+	if err != nil {
+		return nil, ErrLocationNotFound
+	}
+	if !location.IsActive {
+		return nil, ErrLocationInactive
+	}
+	return location, nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLotExpired       = errors.New("lot is expired, only users with lot:override_expired can take stock out of it")
+	ErrLotNumberMissing = errors.New("lot_number is required for lot-tracked products")
+	ErrLotsNotTracked   = errors.New("product does not track lots")
+)
+
+const defaultExpiringDays = 30
+
+type LotService interface {
+	GetProductLots(productID uuid.UUID, includeEmpty bool) ([]model.Lot, error)
+	// GetExpiringLots returns lots with stock expiring within the given number of days,
+	// already expired lots included
+	GetExpiringLots(days int, locationID *uuid.UUID) ([]ExpiringLot, error)
+}
+
+// ExpiringLot is one row of GET /lots/expiring
+type ExpiringLot struct {
+	model.Lot
+	DaysLeft int  `json:"days_left"` // Negative when expired
+	Expired  bool `json:"expired"`
+}
+
+type lotService struct {
+	lotRepo     repository.LotRepository
+	productRepo repository.ProductRepository
+}
+
+func NewLotService(lotRepo repository.LotRepository, productRepo repository.ProductRepository) LotService {
+	return &lotService{lotRepo: lotRepo, productRepo: productRepo}
+}
+
+func (s *lotService) GetProductLots(productID uuid.UUID, includeEmpty bool) ([]model.Lot, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+	return s.lotRepo.FindByProduct(productID, includeEmpty)
+}
+
+func (s *lotService) GetExpiringLots(days int, locationID *uuid.UUID) ([]ExpiringLot, error) {
+	if days <= 0 {
+		days = defaultExpiringDays
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	lots, err := s.lotRepo.FindExpiring(today.AddDate(0, 0, days+1), locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ExpiringLot, len(lots))
+	for i := range lots {
+		lot := &lots[i]
+		expiry := time.Date(lot.ExpiryDate.Year(), lot.ExpiryDate.Month(), lot.ExpiryDate.Day(), 0, 0, 0, 0, time.UTC)
+		result[i] = ExpiringLot{
+			Lot:      *lot,
+			DaysLeft: int(expiry.Sub(today).Hours() / 24),
+			Expired:  lot.IsExpired(now),
+		}
+	}
+	return result, nil
+}
+
+// lotPick is a quantity taken out of (or put into) one lot
+type lotPick struct {
+	lot      *model.Lot
+	quantity decimal.Decimal
+}
+
+// receiveLot adds quantity to a lot at a location, creating the lot on its first receipt.
+// Must run in the caller's transaction, after the product and balance are locked.
+func receiveLot(tx *gorm.DB, repo repository.LotRepository, productID, locationID uuid.UUID, lotNumber string, expiry *time.Time, quantity decimal.Decimal) (*model.Lot, error) {
+	lot, err := repo.LockLot(tx, productID, locationID, lotNumber, expiry)
+	if err != nil {
+		return nil, err
+	}
+	// Receipts without an expiry date go into the lot as it is
+	if expiry != nil && formatDate(lot.ExpiryDate) != formatDate(expiry) {
+		return nil, fmt.Errorf("lot %s already exists with expiry date %q", lotNumber, formatDate(lot.ExpiryDate))
+	}
+
+	lot.Quantity += quantity
+	if err := repo.SetQuantity(tx, lot.ID, lot.Quantity); err != nil {
+		return nil, err
+	}
+	return lot, nil
+}
+
+// pickLots takes quantity out of a product's lots at a location: from the given lot,
+// or first-expired-first-out across lots. Expired lots are skipped (or rejected when
+// asked for explicitly) unless allowExpired.
+// Must run in the caller's transaction, after the product and balance are locked.
+func pickLots(tx *gorm.DB, repo repository.LotRepository, productID, locationID uuid.UUID, quantity decimal.Decimal, lotNumber string, allowExpired bool) ([]lotPick, error) {
+	now := time.Now()
+	var picks []lotPick
+
+	if lotNumber != "" {
+		lot, err := repo.LockLot(tx, productID, locationID, lotNumber, nil)
+		if err != nil {
+			return nil, err
+		}
+		if lot.IsExpired(now) && !allowExpired {
+			return nil, ErrLotExpired
+		}
+		if lot.Quantity < quantity {
+			return nil, fmt.Errorf("insufficient stock in lot %s: %s left", lotNumber, lot.Quantity)
+		}
+		picks = append(picks, lotPick{lot: lot, quantity: quantity})
+	} else {
+		lots, err := repo.LockAvailable(tx, productID, locationID)
+		if err != nil {
+			return nil, err
+		}
+
+		remaining, expiredQty := quantity, decimal.Zero
+		for i := range lots {
+			if remaining == 0 {
+				break
+			}
+			lot := &lots[i]
+			if lot.IsExpired(now) && !allowExpired {
+				expiredQty += lot.Quantity
+				continue
+			}
+			take := lot.Quantity
+			if take > remaining {
+				take = remaining
+			}
+			picks = append(picks, lotPick{lot: lot, quantity: take})
+			remaining -= take
+		}
+
+		if remaining > 0 {
+			if expiredQty > 0 {
+				return nil, fmt.Errorf("insufficient non-expired stock in lots (%s units are in expired lots)", expiredQty)
+			}
+			return nil, errors.New("insufficient stock in lots")
+		}
+	}
+
+	for _, pick := range picks {
+		pick.lot.Quantity -= pick.quantity
+		if err := repo.SetQuantity(tx, pick.lot.ID, pick.lot.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	return picks, nil
+}
+
+// lotQuantities converts picks for events
+func lotQuantities(picks []lotPick) []events.LotQuantity {
+	if len(picks) == 0 {
+		return nil
+	}
+	quantities := make([]events.LotQuantity, len(picks))
+	for i, pick := range picks {
+		quantities[i] = events.LotQuantity{
+			LotNumber:  pick.lot.LotNumber,
+			ExpiryDate: formatDate(pick.lot.ExpiryDate),
+			Quantity:   pick.quantity,
+		}
+	}
+	return quantities
+}
+
+// parseExpiryDate parses an optional YYYY-MM-DD date
+func parseExpiryDate(raw string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, errors.New("invalid expiry_date format, use YYYY-MM-DD")
+	}
+	return &t, nil
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
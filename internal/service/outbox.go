@@ -0,0 +1,133 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/internal/ws"
+
+	"gorm.io/gorm"
+)
+
+const (
+	outboxBatchSize    = 100
+	outboxPollInterval = time.Second
+	outboxRetention    = 24 * time.Hour
+)
+
+// Outbox publishes WebSocket events transactionally: services write events with
+// Enqueue inside their db.Transaction, and Run relays them to the hub after commit.
+// A rolled back transaction never publishes, and a committed event stays pending
+// until the hub has stored it in its event log, so it is never lost. Delivery is
+// at least once: if marking an event dispatched fails after the hub took it, the
+// next relay sends it again, and clients de-duplicate on the envelope id.
+type Outbox struct {
+	repo  repository.OutboxRepository
+	db    *gorm.DB
+	wsHub *ws.Hub
+	wake  chan struct{}
+}
+
+func NewOutbox(repo repository.OutboxRepository, db *gorm.DB, hub *ws.Hub) *Outbox {
+	return &Outbox{
+		repo:  repo,
+		db:    db,
+		wsHub: hub,
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Enqueue writes an event to the outbox using the caller's transaction.
+// requiredPrivilege may be empty (see ws.Hub.PublishWithPrivilege).
+func (o *Outbox) Enqueue(tx *gorm.DB, event *events.Envelope, requiredPrivilege string, topics ...string) error {
+	msg, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return o.repo.Enqueue(tx, &model.OutboxEvent{
+		Topics:            strings.Join(topics, ","),
+		RequiredPrivilege: requiredPrivilege,
+		Payload:           string(msg),
+	})
+}
+
+// Wake triggers a relay right away instead of waiting for the next poll.
+// Call it after the transaction that enqueued events has committed.
+func (o *Outbox) Wake() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays committed events to the hub until the process exits
+func (o *Outbox) Run() {
+	poll := time.NewTicker(outboxPollInterval)
+	cleanup := time.NewTicker(time.Hour)
+	defer poll.Stop()
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-o.wake:
+		case <-poll.C:
+		case <-cleanup.C:
+			if _, err := o.repo.DeleteDispatchedBefore(time.Now().Add(-outboxRetention)); err != nil {
+				log.Printf("Outbox cleanup failed: %v", err)
+			}
+			continue
+		}
+
+		// Drain everything pending, batch by batch
+		for {
+			relayed, err := o.relay()
+			if err != nil {
+				log.Printf("Outbox relay failed: %v", err)
+				break
+			}
+			if relayed < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// relay hands one batch of pending events to the hub and marks the ones it
+// accepted dispatched. An event the hub refuses (e.g. its event log is down)
+// stays pending, with every event after it, and is retried on the next relay.
+func (o *Outbox) relay() (int, error) {
+	relayed := 0
+	var sendErr error
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		events, err := o.repo.ClaimPending(tx, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0, len(events))
+		for _, event := range events {
+			sendErr = o.wsHub.Send(&ws.Message{
+				Topics:            ws.SplitList(event.Topics),
+				RequiredPrivilege: event.RequiredPrivilege,
+				UserIDs:           ws.SplitList(event.UserIDs),
+				Payload:           []byte(event.Payload),
+			})
+			if sendErr != nil {
+				break
+			}
+			ids = append(ids, event.ID)
+		}
+		relayed = len(ids)
+
+		return o.repo.MarkDispatched(tx, ids)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return relayed, sendErr
+}
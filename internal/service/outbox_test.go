@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/ws"
+)
+
+func enqueueTestEvents(t *testing.T, env *testEnv, messages ...string) {
+	t.Helper()
+	for _, message := range messages {
+		event := events.New(events.ProductDeleted{Message: message}, nil)
+		if err := env.outbox.Enqueue(env.db, event, "", ws.TopicProducts); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOutboxRelay(t *testing.T) {
+	env := newTestEnv(t)
+	enqueueTestEvents(t, env, "a", "b")
+
+	relayed, err := env.outbox.relay()
+	if err != nil || relayed != 2 {
+		t.Fatalf("relay() = %d, %v, want 2, nil", relayed, err)
+	}
+	if pending := env.store.pendingEventTypes(t); len(pending) != 0 {
+		t.Errorf("%d events still pending", len(pending))
+	}
+	logged := env.eventLog.logged()
+	if len(logged) != 2 || logged[0].Payload != env.store.outbox[0].Payload || logged[1].Payload != env.store.outbox[1].Payload {
+		t.Errorf("event log = %+v, want both events in outbox order", logged)
+	}
+	if logged[0].Topics != ws.TopicProducts {
+		t.Errorf("event logged with topics %q, want %q", logged[0].Topics, ws.TopicProducts)
+	}
+
+	// Nothing left to relay
+	if relayed, err := env.outbox.relay(); err != nil || relayed != 0 {
+		t.Errorf("second relay() = %d, %v, want 0, nil", relayed, err)
+	}
+}
+
+func TestOutboxRelayKeepsEventsTheHubRefused(t *testing.T) {
+	env := newTestEnv(t)
+	enqueueTestEvents(t, env, "a", "b", "c")
+
+	// The event log takes the first event, then goes down
+	env.eventLog.setFailAfter(1)
+	relayed, err := env.outbox.relay()
+	if !errors.Is(err, errEventLogDown) || relayed != 1 {
+		t.Fatalf("relay() = %d, %v, want 1, %v", relayed, err, errEventLogDown)
+	}
+	if env.store.outbox[0].DispatchedAt == nil {
+		t.Error("the event the hub logged was not marked dispatched")
+	}
+	if pending := env.store.pendingEventTypes(t); len(pending) != 2 {
+		t.Fatalf("%d events pending, want 2 (refused and the one after it)", len(pending))
+	}
+
+	// Still down: nothing is marked, nothing is lost
+	relayed, err = env.outbox.relay()
+	if err == nil || relayed != 0 {
+		t.Fatalf("relay() while down = %d, %v, want 0 and an error", relayed, err)
+	}
+	if pending := env.store.pendingEventTypes(t); len(pending) != 2 {
+		t.Fatalf("%d events pending, want 2", len(pending))
+	}
+
+	// Back up: the retry delivers the rest, in order
+	env.eventLog.setFailAfter(-1)
+	relayed, err = env.outbox.relay()
+	if err != nil || relayed != 2 {
+		t.Fatalf("relay() after recovery = %d, %v, want 2, nil", relayed, err)
+	}
+	logged := env.eventLog.logged()
+	if len(logged) != 3 {
+		t.Fatalf("event log has %d events, want 3", len(logged))
+	}
+	for i, event := range logged {
+		if event.Payload != env.store.outbox[i].Payload || event.Seq != int64(i+1) {
+			t.Errorf("logged event %d (seq %d) is not outbox event %d", i, event.Seq, i+1)
+		}
+	}
+}
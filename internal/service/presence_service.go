@@ -0,0 +1,224 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/internal/ws"
+
+	"github.com/google/uuid"
+)
+
+// PresenceStatus of a user
+type PresenceStatus string
+
+const (
+	PresenceOnline  PresenceStatus = "online"
+	PresenceAway    PresenceStatus = "away"
+	PresenceOffline PresenceStatus = "offline"
+)
+
+const (
+	// Without a WebSocket connection, a user stays online this long after a heartbeat...
+	presenceOnlineWindow = 2 * time.Minute
+	// ...and away until the session inactivity timeout (see authService.ValidateToken)
+	presenceAwayWindow = 5 * time.Minute
+
+	presenceSweepInterval = 30 * time.Second
+	// A WebSocket on another instance counts while that instance keeps refreshing
+	// socket_seen_at (every sweep), so one missed sweep does not flap the status
+	presenceSocketWindow = 2 * presenceSweepInterval
+)
+
+type PresenceService interface {
+	Touch(userID uuid.UUID)
+	GetPresence() ([]PresenceEntry, error)
+	Run()
+}
+
+// PresenceEntry is one row of GET /presence
+type PresenceEntry struct {
+	UserID      string         `json:"user_id"`
+	FullName    string         `json:"full_name"`
+	Status      PresenceStatus `json:"status"`
+	LastSeenAt  *time.Time     `json:"last_seen_at,omitempty"`
+	Connections int            `json:"connections"` // On the instance answering the request
+}
+
+type presenceService struct {
+	userRepo repository.UserRepository
+	wsHub    *ws.Hub
+
+	mutex      sync.Mutex
+	statuses   map[string]PresenceStatus // Last status published per user
+	lastSeen   map[string]time.Time      // Latest heartbeat known per user
+	socketSeen map[string]time.Time      // Latest WebSocket refresh known per user (any instance)
+}
+
+// NewPresenceService combines connection state and heartbeat timestamps and
+// publishes user_status_update only when a user's status actually changes.
+// Connection state is shared through users.socket_seen_at, so every instance
+// computes the same status whichever one holds the socket.
+func NewPresenceService(userRepo repository.UserRepository, hub *ws.Hub) PresenceService {
+	s := &presenceService{
+		userRepo:   userRepo,
+		wsHub:      hub,
+		statuses:   make(map[string]PresenceStatus),
+		lastSeen:   make(map[string]time.Time),
+		socketSeen: make(map[string]time.Time),
+	}
+	hub.OnUserConnectionChange(func(userID string, online bool) {
+		if online {
+			s.touchSockets([]string{userID})
+		}
+		s.evaluate(userID)
+	})
+	return s
+}
+
+// Touch records a heartbeat (LastSeenAt is already updated in the DB by the caller)
+func (s *presenceService) Touch(userID uuid.UUID) {
+	id := userID.String()
+	s.mutex.Lock()
+	s.lastSeen[id] = time.Now()
+	s.mutex.Unlock()
+
+	s.evaluate(id)
+}
+
+// Run periodically re-evaluates every user, so online decays to away and offline
+func (s *presenceService) Run() {
+	ticker := time.NewTicker(presenceSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sweep(); err != nil {
+			log.Printf("Presence sweep failed: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+func (s *presenceService) GetPresence() ([]PresenceEntry, error) {
+	users, err := s.userRepo.FindLastSeen()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]PresenceEntry, len(users))
+	for i, user := range users {
+		id := user.ID.String()
+		lastSeen := s.mergeSeen(s.lastSeen, id, user.LastSeenAt)
+		socketSeen := s.mergeSeen(s.socketSeen, id, user.SocketSeenAt)
+		entries[i] = PresenceEntry{
+			UserID:      id,
+			FullName:    user.FullName,
+			Status:      s.statusOf(id, lastSeen, socketSeen),
+			LastSeenAt:  lastSeen,
+			Connections: s.wsHub.GetUserConnectionCount(id),
+		}
+	}
+	return entries, nil
+}
+
+// sweep refreshes this instance's sockets, loads heartbeats and socket refreshes
+// from the DB (shared across instances) and applies transitions
+func (s *presenceService) sweep() error {
+	s.touchSockets(s.wsHub.ConnectedUserIDs())
+
+	users, err := s.userRepo.FindLastSeen()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		id := user.ID.String()
+		lastSeen := s.mergeSeen(s.lastSeen, id, user.LastSeenAt)
+		socketSeen := s.mergeSeen(s.socketSeen, id, user.SocketSeenAt)
+		s.transition(id, s.statusOf(id, lastSeen, socketSeen), lastSeen)
+	}
+	return nil
+}
+
+// touchSockets records that the users have a WebSocket open on this instance
+func (s *presenceService) touchSockets(userIDs []string) {
+	if err := s.userRepo.TouchSocketSeen(userIDs); err != nil {
+		log.Printf("Presence socket refresh failed: %v", err)
+		return
+	}
+	now := time.Now()
+	s.mutex.Lock()
+	for _, id := range userIDs {
+		s.socketSeen[id] = now
+	}
+	s.mutex.Unlock()
+}
+
+// evaluate re-computes a single user's status from in-memory state
+func (s *presenceService) evaluate(userID string) {
+	lastSeen := s.mergeSeen(s.lastSeen, userID, nil)
+	socketSeen := s.mergeSeen(s.socketSeen, userID, nil)
+	s.transition(userID, s.statusOf(userID, lastSeen, socketSeen), lastSeen)
+}
+
+// mergeSeen keeps the most recent of the in-memory and DB timestamp
+func (s *presenceService) mergeSeen(seen map[string]time.Time, userID string, fromDB *time.Time) *time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	known, ok := seen[userID]
+	if fromDB != nil && (!ok || fromDB.After(known)) {
+		known = *fromDB
+		ok = true
+		seen[userID] = known
+	}
+	if !ok {
+		return nil
+	}
+	return &known
+}
+
+// statusOf is online while a socket is open on this instance or was recently
+// refreshed by another one, otherwise it follows the heartbeats
+func (s *presenceService) statusOf(userID string, lastSeen, socketSeen *time.Time) PresenceStatus {
+	if s.wsHub.IsUserOnline(userID) {
+		return PresenceOnline
+	}
+	if socketSeen != nil && time.Since(*socketSeen) <= presenceSocketWindow {
+		return PresenceOnline
+	}
+	if lastSeen == nil {
+		return PresenceOffline
+	}
+	idle := time.Since(*lastSeen)
+	switch {
+	case idle <= presenceOnlineWindow:
+		return PresenceOnline
+	case idle <= presenceAwayWindow:
+		return PresenceAway
+	default:
+		return PresenceOffline
+	}
+}
+
+// transition publishes the status if it differs from the last one published.
+// Users seen for the first time as offline are recorded silently.
+func (s *presenceService) transition(userID string, status PresenceStatus, lastSeen *time.Time) {
+	s.mutex.Lock()
+	previous, known := s.statuses[userID]
+	s.statuses[userID] = status
+	s.mutex.Unlock()
+
+	if previous == status || (!known && status == PresenceOffline) {
+		return
+	}
+
+	event := events.New(events.UserStatusUpdate{
+		UserID:     userID,
+		Status:     string(status),
+		LastSeenAt: lastSeen,
+	}, &events.Actor{ID: userID})
+	s.wsHub.Publish(event, ws.TopicPresence)
+}
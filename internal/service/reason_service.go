@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/pkg/validator"
+)
+
+var (
+	ErrReasonNotFound = errors.New("reason code not found")
+	ErrReasonMissing  = errors.New("ADJUSTMENT and RETURN transactions need a reason code")
+)
+
+type ReasonService interface {
+	GetReasonCodes(txType model.TransactionType, includeInactive bool) ([]model.ReasonCode, error)
+	CreateReasonCode(req *model.ReasonCode, userID string) error
+	UpdateReasonCode(code string, req *UpdateReasonCodeRequest, userID string) (*model.ReasonCode, error)
+	// ResolveReason checks the reason code of a transaction and returns its catalog code
+	ResolveReason(txType, direction model.TransactionType, code string) (string, error)
+}
+
+type UpdateReasonCodeRequest struct {
+	Name      string                `json:"name" validate:"required"`
+	Direction model.TransactionType `json:"direction" validate:"omitempty,oneof=IN OUT"` // Empty = both
+	IsActive  *bool                 `json:"is_active"`                                   // Optional
+}
+
+type reasonService struct {
+	reasonRepo repository.ReasonRepository
+}
+
+func NewReasonService(reasonRepo repository.ReasonRepository) ReasonService {
+	return &reasonService{reasonRepo: reasonRepo}
+}
+
+func (s *reasonService) GetReasonCodes(txType model.TransactionType, includeInactive bool) ([]model.ReasonCode, error) {
+	return s.reasonRepo.FindAll(txType, includeInactive)
+}
+
+func (s *reasonService) CreateReasonCode(req *model.ReasonCode, userID string) error {
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+	if existing, err := s.reasonRepo.FindByCode(req.Code); err == nil && existing != nil {
+		return errors.New("reason code already exists")
+	}
+
+	req.IsActive = true
+	req.CreatedBy = userID
+	req.UpdatedBy = userID
+	return s.reasonRepo.Create(req)
+}
+
+// UpdateReasonCode renames, redirects or (de)activates a reason. Code and type are
+// immutable, transactions refer to them.
+func (s *reasonService) UpdateReasonCode(code string, req *UpdateReasonCodeRequest, userID string) (*model.ReasonCode, error) {
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return nil, fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+
+	reason, err := s.reasonRepo.FindByCode(code)
+	if err != nil {
+		return nil, ErrReasonNotFound
+	}
+	reason.Name = req.Name
+	reason.Direction = req.Direction
+	if req.IsActive != nil {
+		reason.IsActive = *req.IsActive
+	}
+	reason.UpdatedBy = userID
+	if err := s.reasonRepo.Update(reason); err != nil {
+		return nil, err
+	}
+	return reason, nil
+}
+
+func (s *reasonService) ResolveReason(txType, direction model.TransactionType, code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if txType != model.TxAdjustment && txType != model.TxReturn {
+		if code != "" {
+			return "", errors.New("reason codes only apply to ADJUSTMENT and RETURN transactions")
+		}
+		return "", nil
+	}
+	if code == "" {
+		return "", ErrReasonMissing
+	}
+
+	reason, err := s.reasonRepo.FindByCode(code)
+	if err != nil {
+		return "", ErrReasonNotFound
+	}
+	if !reason.IsActive {
+		return "", fmt.Errorf("reason code %s is inactive", reason.Code)
+	}
+	if !reason.Allows(txType, direction) {
+		return "", fmt.Errorf("reason code %s does not apply to %s %s", reason.Code, txType, direction)
+	}
+	return reason.Code, nil
+}
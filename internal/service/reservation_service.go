@@ -0,0 +1,350 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/internal/ws"
+	"go-inventory-ws/pkg/decimal"
+	"go-inventory-ws/pkg/validator"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReservationNotFound  = errors.New("reservation not found")
+	ErrReservationNotActive = errors.New("reservation is no longer active")
+)
+
+const (
+	// Reservations created without an expiry hold stock this long
+	defaultReservationTTL = 24 * time.Hour
+
+	reservationExpiryInterval = time.Minute
+	reservationExpiryBatch    = 100
+)
+
+type ReservationService interface {
+	CreateReservation(req *CreateReservationRequest, userID, userName, userEmail string) (*model.Reservation, error)
+	ReleaseReservation(id uuid.UUID, userID, userName, userEmail string) (*model.Reservation, error)
+	GetReservations(filter repository.ReservationFilter) ([]model.Reservation, int64, error)
+	GetReservation(id uuid.UUID) (*model.Reservation, error)
+	// Run releases expired reservations until the process exits
+	Run()
+}
+
+type CreateReservationRequest struct {
+	ProductID  uuid.UUID       `json:"product_id" validate:"uuid_required"`
+	LocationID *uuid.UUID      `json:"location_id"` // Empty = default location
+	Quantity   decimal.Decimal `json:"quantity" validate:"required,gt=0"`
+	Unit       string          `json:"unit"`      // Empty = the product's base unit
+	Reference  string          `json:"reference"` // Order number
+	Note       string          `json:"note"`
+	ExpiresAt  *time.Time      `json:"expires_at"` // Empty = in 24 hours
+}
+
+type reservationService struct {
+	reservationRepo repository.ReservationRepository
+	productRepo     repository.ProductRepository
+	stockRepo       repository.StockRepository
+	locations       LocationService
+	units           UnitService
+	db              *gorm.DB
+	outbox          *Outbox
+}
+
+func NewReservationService(reservationRepo repository.ReservationRepository, productRepo repository.ProductRepository, stockRepo repository.StockRepository, locations LocationService, units UnitService, db *gorm.DB, outbox *Outbox) ReservationService {
+	return &reservationService{
+		reservationRepo: reservationRepo,
+		productRepo:     productRepo,
+		stockRepo:       stockRepo,
+		locations:       locations,
+		units:           units,
+		db:              db,
+		outbox:          outbox,
+	}
+}
+
+// CreateReservation holds stock for an order. It fails when the quantity is more
+// than what is available (on-hand minus other reservations) at the location.
+func (s *reservationService) CreateReservation(req *CreateReservationRequest, userID, userName, userEmail string) (*model.Reservation, error) {
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return nil, fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+	expiresAt := time.Now().Add(defaultReservationTTL)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return nil, errors.New("expires_at must be in the future")
+		}
+		expiresAt = *req.ExpiresAt
+	}
+	location, err := s.locations.ResolveLocation(req.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation := &model.Reservation{
+		ProductID:  req.ProductID,
+		LocationID: location.ID,
+		Reference:  req.Reference,
+		Note:       req.Note,
+		Status:     model.ReservationActive,
+		ExpiresAt:  expiresAt,
+	}
+	reservation.CreatedBy = userID
+	reservation.UpdatedBy = userID
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock order: product, then its balance (same as RecordTransaction)
+		product, err := s.productRepo.FindByIDForUpdate(tx, req.ProductID, false)
+		if err != nil {
+			return ErrProductNotFound
+		}
+		if product.IsArchived() {
+			return ErrProductArchived
+		}
+		_, factor, err := s.units.ResolveUnit(product, req.Unit)
+		if err != nil {
+			return err
+		}
+		if reservation.Quantity, err = req.Quantity.MulInt(factor); err != nil {
+			return err
+		}
+
+		balance, err := s.stockRepo.LockBalance(tx, product.ID, location.ID)
+		if err != nil {
+			return err
+		}
+		reserved, err := s.reservationRepo.ReservedQuantity(tx, product.ID, location.ID, nil)
+		if err != nil {
+			return err
+		}
+		if available := balance.Quantity - reserved; available < reservation.Quantity {
+			return fmt.Errorf("insufficient stock available for '%s' at %s: %s on hand, %s reserved", product.Name, location.Name, balance.Quantity, reserved)
+		}
+		if err := s.reservationRepo.Create(tx, reservation); err != nil {
+			return err
+		}
+		reservation.Product, reservation.Location = product, location
+
+		event := events.New(events.ReservationCreated{
+			Reservation: reservationSummary(reservation),
+			Available:   balance.Quantity - reserved - reservation.Quantity,
+			Message:     fmt.Sprintf("%s reserved %s of '%s' at %s", userName, reservation.Quantity, product.Name, location.Name),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", reservationTopics(reservation)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return reservation, nil
+}
+
+// ReleaseReservation gives the held stock back (order cancelled)
+func (s *reservationService) ReleaseReservation(id uuid.UUID, userID, userName, userEmail string) (*model.Reservation, error) {
+	var released *model.Reservation
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		reservation, err := s.reservationRepo.FindByIDForUpdate(tx, id)
+		if err != nil {
+			return ErrReservationNotFound
+		}
+		if reservation.Status != model.ReservationActive {
+			return ErrReservationNotActive
+		}
+
+		now := time.Now()
+		reservation.Status = model.ReservationReleased
+		reservation.ReleasedAt = &now
+		reservation.ReleasedBy = userID
+		reservation.UpdatedBy = userID
+		if err := s.reservationRepo.Save(tx, reservation); err != nil {
+			return err
+		}
+		released = reservation
+
+		event := events.New(events.ReservationReleased{
+			Reservation: reservationSummary(reservation),
+			Message:     fmt.Sprintf("%s released reservation %s", userName, reservationLabel(reservation)),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", reservationTopics(reservation)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return s.GetReservation(released.ID)
+}
+
+func (s *reservationService) GetReservations(filter repository.ReservationFilter) ([]model.Reservation, int64, error) {
+	return s.reservationRepo.FindAll(filter)
+}
+
+func (s *reservationService) GetReservation(id uuid.UUID) (*model.Reservation, error) {
+	reservation, err := s.reservationRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrReservationNotFound
+	}
+	return reservation, nil
+}
+
+// Run marks expired reservations EXPIRED and tells clients. Expired reservations
+// already stopped counting as reserved; this only records it. Safe to run on every
+// instance, each batch skips rows another instance is expiring.
+func (s *reservationService) Run() {
+	ticker := time.NewTicker(reservationExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			expired, err := s.expireBatch()
+			if err != nil {
+				log.Printf("Reservation expiry failed: %v", err)
+				break
+			}
+			if expired < reservationExpiryBatch {
+				break
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// expireBatch expires one batch of reservations
+func (s *reservationService) expireBatch() (int, error) {
+	expired := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		reservations, err := s.reservationRepo.ExpireDue(tx, time.Now(), reservationExpiryBatch)
+		if err != nil {
+			return err
+		}
+		expired = len(reservations)
+
+		for i := range reservations {
+			reservation := &reservations[i]
+			event := events.New(events.ReservationReleased{
+				Reservation: reservationSummary(reservation),
+				Message:     fmt.Sprintf("Reservation %s expired", reservationLabel(reservation)),
+			}, nil)
+			if err := s.outbox.Enqueue(tx, event, "", reservationTopics(reservation)...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if expired > 0 {
+		s.outbox.Wake()
+	}
+	return expired, nil
+}
+
+// claimReservation applies an OUT transaction to the reservation it picks, if any, and
+// returns the quantity that stays reserved at the location afterwards (by the other
+// reservations and what is left of this one). Must run after the balance is locked.
+func claimReservation(tx *gorm.DB, repo repository.ReservationRepository, req *model.Transaction, location *model.Location, userID string) (decimal.Decimal, error) {
+	if req.ReservationID == nil {
+		return repo.ReservedQuantity(tx, req.ProductID, location.ID, nil)
+	}
+	if req.Type != model.TxOut {
+		return 0, errors.New("only OUT transactions can pick a reservation")
+	}
+
+	reservation, err := repo.FindByIDForUpdate(tx, *req.ReservationID)
+	if err != nil {
+		return 0, ErrReservationNotFound
+	}
+	now := time.Now()
+	if !reservation.IsHolding(now) {
+		return 0, ErrReservationNotActive
+	}
+	if reservation.ProductID != req.ProductID || reservation.LocationID != location.ID {
+		return 0, errors.New("reservation is for another product or location")
+	}
+	others, err := repo.ReservedQuantity(tx, req.ProductID, location.ID, &reservation.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Picking more than reserved is fine, the extra comes out of available stock
+	take := req.Quantity
+	if take > reservation.Outstanding() {
+		take = reservation.Outstanding()
+	}
+	reservation.FulfilledQty += take
+	req.ReservedQty = take
+	if reservation.Outstanding() == 0 {
+		reservation.Status = model.ReservationFulfilled
+		reservation.ReleasedAt = &now
+		reservation.ReleasedBy = userID
+	}
+	reservation.UpdatedBy = userID
+	if err := repo.Save(tx, reservation); err != nil {
+		return 0, err
+	}
+	return others + reservation.Outstanding(), nil
+}
+
+// restoreReservation undoes what a voided OUT picked from its reservation. Reservations
+// released or expired since then stay closed, the stock comes back as free stock.
+// Must run after the balance is locked.
+func restoreReservation(tx *gorm.DB, repo repository.ReservationRepository, t *model.Transaction, userID string) error {
+	if t.ReservationID == nil || t.ReservedQty == 0 {
+		return nil
+	}
+	reservation, err := repo.FindByIDForUpdate(tx, *t.ReservationID)
+	if err != nil {
+		return ErrReservationNotFound
+	}
+	if reservation.Status != model.ReservationActive && reservation.Status != model.ReservationFulfilled {
+		return nil
+	}
+
+	reservation.FulfilledQty -= t.ReservedQty
+	if reservation.FulfilledQty < 0 {
+		reservation.FulfilledQty = decimal.Zero
+	}
+	reservation.Status = model.ReservationActive
+	reservation.ReleasedAt = nil
+	reservation.ReleasedBy = ""
+	reservation.UpdatedBy = userID
+	return repo.Save(tx, reservation)
+}
+
+// reservationTopics notifies the product and location subscribers, availability changed
+func reservationTopics(r *model.Reservation) []string {
+	return []string{ws.TopicProducts, ws.ProductTopic(r.ProductID), ws.LocationTopic(r.LocationID)}
+}
+
+func reservationLabel(r *model.Reservation) string {
+	if r.Reference != "" {
+		return r.Reference
+	}
+	return r.ID.String()
+}
+
+func reservationSummary(r *model.Reservation) events.ReservationSummary {
+	return events.ReservationSummary{
+		ID:           r.ID,
+		ProductID:    r.ProductID,
+		LocationID:   r.LocationID,
+		Quantity:     r.Quantity,
+		FulfilledQty: r.FulfilledQty,
+		Reference:    r.Reference,
+		Status:       string(r.Status),
+		ExpiresAt:    r.ExpiresAt,
+	}
+}
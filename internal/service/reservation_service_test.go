@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+)
+
+func TestCreateReservationChecksAvailableStock(t *testing.T) {
+	env := newTestEnv(t)
+	reservations := env.reservationService()
+	product := env.addProduct("Soap", 2500)
+	env.setBalance(product, env.warehouse, qty(10), 0)
+	env.addReservation(product, env.warehouse, qty(6), time.Now().Add(time.Hour))
+	// Past its expiry, no longer holds stock even before the expiry job marks it
+	env.addReservation(product, env.warehouse, qty(3), time.Now().Add(-time.Minute))
+
+	_, err := reservations.CreateReservation(&CreateReservationRequest{ProductID: product.ID, Quantity: qty(5)}, "u1", "Ana", "ana@example.com")
+	if err == nil || !strings.Contains(err.Error(), "insufficient stock available") {
+		t.Fatalf("reserving 5 of 4 available: %v, want an insufficient stock error", err)
+	}
+
+	reservation, err := reservations.CreateReservation(&CreateReservationRequest{ProductID: product.ID, Quantity: qty(4), Reference: "SO-1"}, "u1", "Ana", "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reservation.Status != model.ReservationActive || reservation.LocationID != env.warehouse.ID || reservation.Quantity != qty(4) {
+		t.Errorf("reservation = %s %s at %s, want ACTIVE 4 at the default location", reservation.Status, reservation.Quantity, reservation.LocationID)
+	}
+	if ttl := time.Until(reservation.ExpiresAt); ttl < defaultReservationTTL-time.Minute || ttl > defaultReservationTTL {
+		t.Errorf("expires in %s, want %s", ttl, defaultReservationTTL)
+	}
+	if pending := env.store.pendingEventTypes(t); len(pending) != 1 || pending[0] != events.TypeReservationCreated {
+		t.Errorf("events = %v, want %s", pending, events.TypeReservationCreated)
+	}
+
+	// Nothing left to reserve, reservations don't move stock
+	if _, err := reservations.CreateReservation(&CreateReservationRequest{ProductID: product.ID, Quantity: qty(1)}, "u1", "Ana", "ana@example.com"); err == nil {
+		t.Error("reserved stock that is already held")
+	}
+	if b := env.balance(product, env.warehouse); b.Quantity != qty(10) {
+		t.Errorf("balance = %s, want 10", b.Quantity)
+	}
+}
+
+func TestSaleCannotTakeReservedStock(t *testing.T) {
+	env := newTestEnv(t)
+	inventory := env.inventoryService()
+	product := env.addProduct("Soap", 2500)
+	env.setBalance(product, env.warehouse, qty(10), 0)
+	env.addReservation(product, env.warehouse, qty(8), time.Now().Add(time.Hour))
+
+	sale := &model.Transaction{ProductID: product.ID, Type: model.TxOut, Quantity: qty(3)}
+	err := inventory.RecordTransaction(sale, "u1", "Ana", "ana@example.com")
+	if err == nil || !strings.Contains(err.Error(), "insufficient stock available") {
+		t.Fatalf("selling 3 of 2 available: %v, want an insufficient stock error", err)
+	}
+	if b := env.balance(product, env.warehouse); b.Quantity != qty(10) {
+		t.Errorf("balance = %s, want 10", b.Quantity)
+	}
+
+	sale = &model.Transaction{ProductID: product.ID, Type: model.TxOut, Quantity: qty(2)}
+	if err := inventory.RecordTransaction(sale, "u1", "Ana", "ana@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if b := env.balance(product, env.warehouse); b.Quantity != qty(8) {
+		t.Errorf("balance = %s, want 8", b.Quantity)
+	}
+
+	// Adjustments record stock that is physically gone, reserved or not
+	damage := &model.Transaction{ProductID: product.ID, Type: model.TxAdjustment, Direction: model.TxOut, ReasonCode: "DAMAGED", Quantity: qty(1)}
+	if err := inventory.RecordTransaction(damage, "u1", "Ana", "ana@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if b := env.balance(product, env.warehouse); b.Quantity != qty(7) {
+		t.Errorf("balance = %s, want 7", b.Quantity)
+	}
+}
+
+func TestExpireBatch(t *testing.T) {
+	env := newTestEnv(t)
+	reservations := env.reservationService()
+	product := env.addProduct("Soap", 2500)
+	due := env.addReservation(product, env.warehouse, qty(2), time.Now().Add(-time.Minute))
+	holding := env.addReservation(product, env.warehouse, qty(3), time.Now().Add(time.Hour))
+
+	expired, err := reservations.expireBatch()
+	if err != nil || expired != 1 {
+		t.Fatalf("expireBatch() = %d, %v, want 1, nil", expired, err)
+	}
+	if r := env.store.reservations[due.ID]; r.Status != model.ReservationExpired || r.ReleasedAt == nil {
+		t.Errorf("due reservation = %s, want EXPIRED", r.Status)
+	}
+	if r := env.store.reservations[holding.ID]; r.Status != model.ReservationActive {
+		t.Errorf("unexpired reservation = %s, want ACTIVE", r.Status)
+	}
+	if pending := env.store.pendingEventTypes(t); len(pending) != 1 || pending[0] != events.TypeReservationReleased {
+		t.Errorf("events = %v, want one %s", pending, events.TypeReservationReleased)
+	}
+
+	if expired, err := reservations.expireBatch(); err != nil || expired != 0 {
+		t.Errorf("second expireBatch() = %d, %v, want 0, nil", expired, err)
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSerialNotFound      = errors.New("serial number not found")
+	ErrSerialsNotTracked   = errors.New("product is not serialized")
+	ErrSerialsCountInvalid = errors.New("serialized products need exactly one serial number per unit")
+)
+
+type SerialService interface {
+	GetProductSerials(productID uuid.UUID, status model.SerialStatus, locationID *uuid.UUID) ([]model.SerialNumber, error)
+	// LookupSerial returns every unit with this serial number with its transaction history
+	LookupSerial(serial string) ([]SerialHistory, error)
+}
+
+// SerialHistory is a unit with the transactions that moved it, oldest first
+type SerialHistory struct {
+	model.SerialNumber
+	Transactions []model.Transaction `json:"transactions"`
+}
+
+type serialService struct {
+	serialRepo  repository.SerialRepository
+	productRepo repository.ProductRepository
+}
+
+func NewSerialService(serialRepo repository.SerialRepository, productRepo repository.ProductRepository) SerialService {
+	return &serialService{serialRepo: serialRepo, productRepo: productRepo}
+}
+
+func (s *serialService) GetProductSerials(productID uuid.UUID, status model.SerialStatus, locationID *uuid.UUID) ([]model.SerialNumber, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+	return s.serialRepo.FindByProduct(productID, status, locationID)
+}
+
+func (s *serialService) LookupSerial(serial string) ([]SerialHistory, error) {
+	units, err := s.serialRepo.FindBySerial(strings.TrimSpace(serial))
+	if err != nil {
+		return nil, err
+	}
+	if len(units) == 0 {
+		return nil, ErrSerialNotFound
+	}
+
+	history := make([]SerialHistory, len(units))
+	for i, unit := range units {
+		transactions, err := s.serialRepo.FindTransactions(unit.ID)
+		if err != nil {
+			return nil, err
+		}
+		history[i] = SerialHistory{SerialNumber: unit, Transactions: transactions}
+	}
+	return history, nil
+}
+
+// normalizeSerials trims the serial numbers of a request and checks there is exactly
+// one distinct serial per unit
+func normalizeSerials(serials []string, quantity decimal.Decimal) ([]string, error) {
+	if !quantity.IsWhole() {
+		return nil, errors.New("serialized products are moved in whole units")
+	}
+	if len(serials) != quantity.Int() {
+		return nil, ErrSerialsCountInvalid
+	}
+	normalized := make([]string, len(serials))
+	seen := make(map[string]bool, len(serials))
+	for i, serial := range serials {
+		serial = strings.TrimSpace(serial)
+		if serial == "" {
+			return nil, errors.New("serial numbers must not be empty")
+		}
+		if seen[serial] {
+			return nil, fmt.Errorf("serial %s appears more than once", serial)
+		}
+		seen[serial] = true
+		normalized[i] = serial
+	}
+	return normalized, nil
+}
+
+// registerSerials puts units into stock at a location on IN. New serials are created;
+// units that left the inventory (sold and returned, or found again) come back in stock.
+// Must run in the caller's transaction, after the product and balance are locked.
+func registerSerials(tx *gorm.DB, repo repository.SerialRepository, productID, locationID uuid.UUID, serials []string) ([]model.SerialNumber, error) {
+	existing, err := repo.LockSerials(tx, productID, serials)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]model.SerialNumber, len(existing))
+	var returning []uuid.UUID
+	for _, unit := range existing {
+		if unit.Status == model.SerialInStock || unit.Status == model.SerialInTransit {
+			return nil, fmt.Errorf("serial %s is already in stock", unit.Serial)
+		}
+		known[unit.Serial] = unit
+		returning = append(returning, unit.ID)
+	}
+	if err := repo.SetStatus(tx, returning, model.SerialInStock, locationID); err != nil {
+		return nil, err
+	}
+
+	var created []model.SerialNumber
+	for _, serial := range serials {
+		if _, ok := known[serial]; !ok {
+			created = append(created, model.SerialNumber{
+				ProductID:  productID,
+				Serial:     serial,
+				Status:     model.SerialInStock,
+				LocationID: &locationID,
+			})
+		}
+	}
+	if err := repo.Create(tx, created); err != nil {
+		return nil, err
+	}
+
+	units := make([]model.SerialNumber, 0, len(serials))
+	for _, unit := range existing {
+		unit.Status = model.SerialInStock
+		unit.LocationID = &locationID
+		units = append(units, unit)
+	}
+	return append(units, created...), nil
+}
+
+// moveSerials moves units that must all be in status `from` at location fromID to
+// status `to` at location toID (OUT, transfer dispatch/receipt/discrepancy).
+// Must run in the caller's transaction, after the product and balances are locked.
+func moveSerials(tx *gorm.DB, repo repository.SerialRepository, productID uuid.UUID, serials []string, from model.SerialStatus, fromID uuid.UUID, to model.SerialStatus, toID uuid.UUID) ([]model.SerialNumber, error) {
+	units, err := repo.LockSerials(tx, productID, serials)
+	if err != nil {
+		return nil, err
+	}
+	if len(units) != len(serials) {
+		found := make(map[string]bool, len(units))
+		for _, unit := range units {
+			found[unit.Serial] = true
+		}
+		for _, serial := range serials {
+			if !found[serial] {
+				return nil, fmt.Errorf("serial %s not found", serial)
+			}
+		}
+	}
+
+	ids := make([]uuid.UUID, len(units))
+	for i := range units {
+		unit := &units[i]
+		if unit.Status != from || unit.LocationID == nil || *unit.LocationID != fromID {
+			return nil, fmt.Errorf("serial %s is not %s at this location", unit.Serial, from)
+		}
+		ids[i] = unit.ID
+		unit.Status = to
+		unit.LocationID = &toID
+	}
+	if err := repo.SetStatus(tx, ids, to, toID); err != nil {
+		return nil, err
+	}
+	return units, nil
+}
+
+// serialNumbers lists the serial numbers of units, for events
+func serialNumbers(units []model.SerialNumber) []string {
+	if len(units) == 0 {
+		return nil
+	}
+	serials := make([]string, len(units))
+	for i, unit := range units {
+		serials[i] = unit.Serial
+	}
+	return serials
+}
@@ -1,7 +1,6 @@
 package service
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
@@ -9,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"go-inventory-ws/internal/events"
 	"go-inventory-ws/internal/model"
 	"go-inventory-ws/internal/repository"
 	"go-inventory-ws/internal/ws"
@@ -364,7 +364,7 @@ func (s *shiftService) DeleteShift(shiftID uuid.UUID, deleterID string) error {
 	}
 
 	// 3. Notify affected user
-	go s.notifyShiftDeleted(shift)
+	go s.notifyShiftDeleted(shift, deleterID)
 
 	return nil
 }
@@ -496,80 +496,70 @@ func formatConflictDetails(shifts []model.Shift) string {
 
 // WebSocket notification methods
 
+// shiftActor is the user who last changed the shift
+func shiftActor(shift *model.Shift) *events.Actor {
+	return &events.Actor{ID: shift.UpdatedBy}
+}
+
 func (s *shiftService) notifyShiftCreated(shift *model.Shift, user *model.User) {
-	payload := map[string]interface{}{
-		"type":   "shift_notification",
-		"action": "shift_created",
-		"message": fmt.Sprintf("You have been assigned a new shift: %s - %s, from %s to %s",
+	event := events.New(events.ShiftCreated{
+		Shift: shift.ToResponse(),
+		Message: fmt.Sprintf("You have been assigned a new shift: %s - %s, from %s to %s",
 			shift.StartTime, shift.EndTime,
 			shift.StartDate.Format("2006-01-02"),
 			shift.EndDate.Format("2006-01-02")),
-		"shift": shift.ToResponse(),
-	}
-	msg, _ := json.Marshal(payload)
+	}, shiftActor(shift))
 
 	// Send only to the assigned user
-	s.wsHub.SendToUsers([]string{user.ID.String()}, msg)
+	s.wsHub.SendToUsers([]string{user.ID.String()}, event, ws.TopicShifts)
 }
 
 func (s *shiftService) notifyShiftUpdated(shift *model.Shift, originalUserID uuid.UUID, originalUser *model.User) {
 	// Check if user was changed (shift reassigned)
 	if shift.UserID != originalUserID {
 		// Notify OLD user: "Your shift has been reassigned"
-		oldPayload := map[string]interface{}{
-			"type":   "shift_notification",
-			"action": "shift_reassigned_from",
-			"message": fmt.Sprintf("Your shift (%s - %s, %s to %s) has been reassigned to %s",
+		oldEvent := events.New(events.ShiftReassignedFrom{
+			NewAssignee: shift.User.ToResponse(),
+			Message: fmt.Sprintf("Your shift (%s - %s, %s to %s) has been reassigned to %s",
 				shift.StartTime, shift.EndTime,
 				shift.StartDate.Format("2006-01-02"),
 				shift.EndDate.Format("2006-01-02"),
 				shift.User.FullName),
-			"new_assignee": shift.User.ToResponse(),
-		}
-		oldMsg, _ := json.Marshal(oldPayload)
-		s.wsHub.SendToUsers([]string{originalUserID.String()}, oldMsg)
+		}, shiftActor(shift))
+		s.wsHub.SendToUsers([]string{originalUserID.String()}, oldEvent, ws.TopicShifts)
 
 		// Notify NEW user: "You are replacing X's shift"
-		newPayload := map[string]interface{}{
-			"type":   "shift_notification",
-			"action": "shift_reassigned_to",
-			"message": fmt.Sprintf("You are replacing %s's shift: %s - %s, from %s to %s",
+		newEvent := events.New(events.ShiftReassignedTo{
+			PreviousAssignee: originalUser.ToResponse(),
+			Shift:            shift.ToResponse(),
+			Message: fmt.Sprintf("You are replacing %s's shift: %s - %s, from %s to %s",
 				originalUser.FullName,
 				shift.StartTime, shift.EndTime,
 				shift.StartDate.Format("2006-01-02"),
 				shift.EndDate.Format("2006-01-02")),
-			"previous_assignee": originalUser.ToResponse(),
-			"shift":             shift.ToResponse(),
-		}
-		newMsg, _ := json.Marshal(newPayload)
-		s.wsHub.SendToUsers([]string{shift.UserID.String()}, newMsg)
+		}, shiftActor(shift))
+		s.wsHub.SendToUsers([]string{shift.UserID.String()}, newEvent, ws.TopicShifts)
 	} else {
 		// Same user, just notify about the update
-		payload := map[string]interface{}{
-			"type":   "shift_notification",
-			"action": "shift_updated",
-			"message": fmt.Sprintf("Your shift has been updated: %s - %s, from %s to %s",
+		event := events.New(events.ShiftUpdated{
+			Shift: shift.ToResponse(),
+			Message: fmt.Sprintf("Your shift has been updated: %s - %s, from %s to %s",
 				shift.StartTime, shift.EndTime,
 				shift.StartDate.Format("2006-01-02"),
 				shift.EndDate.Format("2006-01-02")),
-			"shift": shift.ToResponse(),
-		}
-		msg, _ := json.Marshal(payload)
-		s.wsHub.SendToUsers([]string{shift.UserID.String()}, msg)
+		}, shiftActor(shift))
+		s.wsHub.SendToUsers([]string{shift.UserID.String()}, event, ws.TopicShifts)
 	}
 }
 
-func (s *shiftService) notifyShiftDeleted(shift *model.Shift) {
-	payload := map[string]interface{}{
-		"type":   "shift_notification",
-		"action": "shift_cancelled",
-		"message": fmt.Sprintf("Your shift has been cancelled: %s - %s, from %s to %s",
+func (s *shiftService) notifyShiftDeleted(shift *model.Shift, deleterID string) {
+	event := events.New(events.ShiftCancelled{
+		Shift: shift.ToResponse(),
+		Message: fmt.Sprintf("Your shift has been cancelled: %s - %s, from %s to %s",
 			shift.StartTime, shift.EndTime,
 			shift.StartDate.Format("2006-01-02"),
 			shift.EndDate.Format("2006-01-02")),
-		"shift": shift.ToResponse(),
-	}
-	msg, _ := json.Marshal(payload)
+	}, &events.Actor{ID: deleterID})
 
-	s.wsHub.SendToUsers([]string{shift.UserID.String()}, msg)
+	s.wsHub.SendToUsers([]string{shift.UserID.String()}, event, ws.TopicShifts)
 }
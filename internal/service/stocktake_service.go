@@ -0,0 +1,510 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/internal/ws"
+	"go-inventory-ws/pkg/decimal"
+	"go-inventory-ws/pkg/validator"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrStocktakeNotFound    = errors.New("stocktake not found")
+	ErrStocktakeNotOpen     = errors.New("stocktake is no longer open")
+	ErrStocktakeIncomplete  = errors.New("every line must be counted before the stocktake can be approved")
+	ErrStocktakeEmpty       = errors.New("nothing to count: no products match the stocktake")
+	ErrStocktakeUncountable = errors.New("lot-tracked and serialized products can't be counted in a stocktake")
+)
+
+type StocktakeService interface {
+	CreateStocktake(req *CreateStocktakeRequest, userID, userName, userEmail string) (*model.Stocktake, error)
+	// RecordCounts saves counted quantities. Several users can count the same stocktake at once.
+	RecordCounts(id uuid.UUID, req *RecordCountsRequest, userID, userName, userEmail string) ([]model.StocktakeLine, error)
+	ApproveStocktake(id uuid.UUID, userID, userName, userEmail string) (*model.Stocktake, error)
+	CancelStocktake(id uuid.UUID, userID, userName, userEmail string) (*model.Stocktake, error)
+	GetStocktakes(filter repository.StocktakeFilter) ([]model.Stocktake, int64, error)
+	GetStocktake(id uuid.UUID) (*model.Stocktake, error)
+}
+
+// CreateStocktakeRequest opens a stocktake for the given products, or without
+// products for everything stocked at the location
+type CreateStocktakeRequest struct {
+	LocationID uuid.UUID   `json:"location_id" validate:"uuid_required"`
+	ProductIDs []uuid.UUID `json:"product_ids"`
+	Note       string      `json:"note"`
+}
+
+type RecordCountsRequest struct {
+	Counts []StocktakeCountRequest `json:"counts" validate:"required,min=1,dive"`
+}
+
+// StocktakeCountRequest is the count of one product. Mode "set" (default) replaces the
+// counted quantity, "add" adds to it (the product is on several shelves).
+type StocktakeCountRequest struct {
+	ProductID uuid.UUID       `json:"product_id" validate:"uuid_required"`
+	Quantity  decimal.Decimal `json:"quantity" validate:"gte=0"`
+	Unit      string          `json:"unit"` // Empty = the product's base unit
+	Mode      string          `json:"mode" validate:"omitempty,oneof=set add"`
+}
+
+type stocktakeService struct {
+	stocktakeRepo repository.StocktakeRepository
+	productRepo   repository.ProductRepository
+	stockRepo     repository.StockRepository
+	locations     LocationService
+	units         UnitService
+	reasons       ReasonService
+	db            *gorm.DB
+	outbox        *Outbox
+}
+
+func NewStocktakeService(stocktakeRepo repository.StocktakeRepository, productRepo repository.ProductRepository, stockRepo repository.StockRepository, locations LocationService, units UnitService, reasons ReasonService, db *gorm.DB, outbox *Outbox) StocktakeService {
+	return &stocktakeService{
+		stocktakeRepo: stocktakeRepo,
+		productRepo:   productRepo,
+		stockRepo:     stockRepo,
+		locations:     locations,
+		units:         units,
+		reasons:       reasons,
+		db:            db,
+		outbox:        outbox,
+	}
+}
+
+// CreateStocktake snapshots the expected quantities. Stock keeps moving while the
+// shelves are counted; approval posts the variances against the snapshot.
+// A location-wide stocktake leaves out lot-tracked and serialized products and
+// lists them in Excluded.
+func (s *stocktakeService) CreateStocktake(req *CreateStocktakeRequest, userID, userName, userEmail string) (*model.Stocktake, error) {
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return nil, fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+	location, err := s.locations.ResolveLocation(&req.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	if open, err := s.stocktakeRepo.FindOpenAt(location.ID); err == nil {
+		return nil, fmt.Errorf("stocktake %s is still open at %s", open.Number, location.Name)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(req.ProductIDs))
+	for _, productID := range req.ProductIDs {
+		if seen[productID] {
+			return nil, errors.New("each product can only appear once per stocktake")
+		}
+		seen[productID] = true
+
+		product, err := s.productRepo.FindByID(productID)
+		if err != nil {
+			return nil, ErrProductNotFound
+		}
+		if product.IsArchived() {
+			return nil, ErrProductArchived
+		}
+		if product.TrackLots || product.Serialized {
+			return nil, ErrStocktakeUncountable
+		}
+	}
+
+	stocktake := &model.Stocktake{
+		Number:     documentNumber("STK"),
+		LocationID: location.ID,
+		Status:     model.StocktakeOpen,
+		Note:       req.Note,
+	}
+	stocktake.CreatedBy = userID
+	stocktake.UpdatedBy = userID
+
+	var excluded []model.StocktakeExclusion
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		lines, err := s.stocktakeRepo.SnapshotLines(tx, location.ID, req.ProductIDs)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return ErrStocktakeEmpty
+		}
+		if len(req.ProductIDs) == 0 {
+			if excluded, err = s.stocktakeRepo.ExcludedAt(tx, location.ID); err != nil {
+				return err
+			}
+		}
+		stocktake.Lines = lines
+		if err := s.stocktakeRepo.Create(tx, stocktake); err != nil {
+			return err
+		}
+		stocktake.Location = location
+
+		message := fmt.Sprintf("%s started stocktake %s at %s (%d products)", userName, stocktake.Number, location.Name, len(lines))
+		if len(excluded) > 0 {
+			message += fmt.Sprintf(", %d lot-tracked or serialized products left out", len(excluded))
+		}
+		event := events.New(events.StocktakeCreated{
+			Stocktake: stocktakeSummary(stocktake, model.StocktakeProgress{Total: len(lines)}),
+			Message:   message,
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", stocktakeTopics(stocktake)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	created, err := s.GetStocktake(stocktake.ID)
+	if err != nil {
+		return nil, err
+	}
+	created.Excluded = excluded
+	return created, nil
+}
+
+func (s *stocktakeService) RecordCounts(id uuid.UUID, req *RecordCountsRequest, userID, userName, userEmail string) ([]model.StocktakeLine, error) {
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return nil, fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+
+	// Counts come in the unit counted (boxes, packs), lines are in base units
+	counts := make([]StocktakeCountRequest, len(req.Counts))
+	seen := make(map[uuid.UUID]bool, len(req.Counts))
+	for i, count := range req.Counts {
+		if seen[count.ProductID] {
+			return nil, errors.New("each product can only appear once per batch of counts")
+		}
+		seen[count.ProductID] = true
+
+		if count.Unit != "" {
+			product, err := s.productRepo.FindByID(count.ProductID)
+			if err != nil {
+				return nil, ErrProductNotFound
+			}
+			_, factor, err := s.units.ResolveUnit(product, count.Unit)
+			if err != nil {
+				return nil, err
+			}
+			if count.Quantity, err = count.Quantity.MulInt(factor); err != nil {
+				return nil, err
+			}
+		}
+		counts[i] = count
+	}
+	// Same line order in every batch, so concurrent counters can't deadlock
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].ProductID.String() < counts[j].ProductID.String()
+	})
+
+	var updated []model.StocktakeLine
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Shared lock: other counts go ahead, approval and cancellation wait
+		stocktake, err := s.stocktakeRepo.FindByIDForShare(tx, id)
+		if err != nil {
+			return ErrStocktakeNotFound
+		}
+		if stocktake.Status != model.StocktakeOpen {
+			return ErrStocktakeNotOpen
+		}
+		if stocktake.Location, err = s.locations.GetLocation(stocktake.LocationID); err != nil {
+			return err
+		}
+
+		lineCounts := make([]events.StocktakeLineCount, 0, len(counts))
+		for _, count := range counts {
+			line, err := s.stocktakeRepo.RecordCount(tx, stocktake.ID, count.ProductID, count.Quantity, count.Mode == "add", userID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("product %s is not part of stocktake %s", count.ProductID, stocktake.Number)
+			}
+			if err != nil {
+				return err
+			}
+			updated = append(updated, *line)
+			lineCounts = append(lineCounts, events.StocktakeLineCount{
+				ProductID:   line.ProductID,
+				ExpectedQty: line.ExpectedQty,
+				CountedQty:  *line.CountedQty,
+				Variance:    *line.Variance,
+			})
+		}
+
+		progress, err := s.stocktakeRepo.Progress(tx, stocktake.ID)
+		if err != nil {
+			return err
+		}
+		event := events.New(events.StocktakeCountUpdated{
+			Stocktake: stocktakeSummary(stocktake, progress),
+			Counts:    lineCounts,
+			Message:   fmt.Sprintf("%s counted %d products of stocktake %s (%d/%d)", userName, len(lineCounts), stocktake.Number, progress.Counted, progress.Total),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", stocktakeTopics(stocktake)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return updated, nil
+}
+
+// ApproveStocktake posts one ADJUSTMENT per line whose count differs from the snapshot,
+// all or nothing. The variance is applied to the current balance, so movements
+// recorded while counting are kept: the resulting balance (ResultingQty) is the
+// counted quantity plus those movements, and equals the count when nothing moved.
+func (s *stocktakeService) ApproveStocktake(id uuid.UUID, userID, userName, userEmail string) (*model.Stocktake, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock order: stocktake, then products (lines are sorted by product ID), then balances
+		stocktake, err := s.stocktakeRepo.FindByIDForUpdate(tx, id)
+		if err != nil {
+			return ErrStocktakeNotFound
+		}
+		if stocktake.Status != model.StocktakeOpen {
+			return ErrStocktakeNotOpen
+		}
+		location, err := s.locations.ResolveLocation(&stocktake.LocationID)
+		if err != nil {
+			return err
+		}
+		stocktake.Location = location
+		for _, line := range stocktake.Lines {
+			if line.CountedQty == nil {
+				return ErrStocktakeIncomplete
+			}
+		}
+
+		actor := &events.Actor{ID: userID, Name: userName, Email: userEmail}
+		var adjustments []events.StocktakeAdjustment
+		var applied []*appliedTransaction
+		for i := range stocktake.Lines {
+			line := &stocktake.Lines[i]
+			adjustment, a, resulting, err := s.postVariance(tx, stocktake, line, userID)
+			if err != nil {
+				return err
+			}
+			line.ResultingQty = &resulting
+			if adjustment != nil {
+				line.AdjustmentID = &adjustment.ID
+			}
+			if err := s.stocktakeRepo.SaveLine(tx, line); err != nil {
+				return err
+			}
+			if adjustment == nil {
+				continue
+			}
+			applied = append(applied, a)
+			adjustments = append(adjustments, events.StocktakeAdjustment{
+				TransactionID: adjustment.ID,
+				ProductID:     a.product.ID,
+				ProductName:   a.product.Name,
+				ProductSKU:    a.product.SKU,
+				Direction:     string(adjustment.Direction),
+				Quantity:      adjustment.Quantity,
+				ExpectedQty:   line.ExpectedQty,
+				CountedQty:    *line.CountedQty,
+				NewStock:      a.newStock,
+				LocationStock: a.newLocationStock,
+			})
+		}
+
+		now := time.Now()
+		stocktake.Status = model.StocktakeApproved
+		stocktake.ApprovedAt = &now
+		stocktake.ApprovedBy = userID
+		stocktake.UpdatedBy = userID
+		if err := s.stocktakeRepo.Save(tx, stocktake); err != nil {
+			return err
+		}
+
+		topics := stocktakeTopics(stocktake)
+		if len(applied) > 0 {
+			topics = append(topics, ws.TopicProducts)
+			for _, a := range applied {
+				topics = append(topics, ws.ProductTopic(a.product.ID))
+			}
+		}
+		progress := model.StocktakeProgress{Counted: len(stocktake.Lines), Total: len(stocktake.Lines)}
+		event := events.New(events.StocktakeApproved{
+			Stocktake:   stocktakeSummary(stocktake, progress),
+			Adjustments: adjustments,
+			Message:     fmt.Sprintf("%s approved stocktake %s at %s: %d adjustments", userName, stocktake.Number, location.Name, len(adjustments)),
+		}, actor)
+		if err := s.outbox.Enqueue(tx, event, "", topics...); err != nil {
+			return err
+		}
+
+		for _, a := range applied {
+			if err := enqueueLowStockAlerts(tx, s.outbox, a, location, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return s.GetStocktake(id)
+}
+
+// postVariance records the ADJUSTMENT correcting one counted line and returns the
+// resulting balance at the location. Lines without variance get no adjustment.
+func (s *stocktakeService) postVariance(tx *gorm.DB, stocktake *model.Stocktake, line *model.StocktakeLine, userID string) (*model.Transaction, *appliedTransaction, decimal.Decimal, error) {
+	product, err := s.productRepo.FindByIDForUpdate(tx, line.ProductID, false)
+	if err != nil {
+		return nil, nil, 0, ErrProductNotFound
+	}
+	if *line.Variance == 0 {
+		balance, err := s.stockRepo.LockBalance(tx, product.ID, stocktake.LocationID)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		return nil, nil, balance.Quantity, nil
+	}
+	if product.IsArchived() {
+		return nil, nil, 0, fmt.Errorf("'%s' was archived during the stocktake, restore it or recount without it", product.Name)
+	}
+	if product.TrackLots || product.Serialized {
+		return nil, nil, 0, ErrStocktakeUncountable
+	}
+
+	direction, quantity := model.TxIn, *line.Variance
+	if quantity < 0 {
+		direction, quantity = model.TxOut, -quantity
+	}
+	reason, err := s.reasons.ResolveReason(model.TxAdjustment, direction, model.StocktakeReasonCode)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	balance, err := s.stockRepo.LockBalance(tx, product.ID, stocktake.LocationID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	newLocationStock := balance.Quantity + *line.Variance
+	if newLocationStock < 0 {
+		return nil, nil, 0, fmt.Errorf("insufficient stock remaining for '%s' at %s to post a variance of %s", product.Name, stocktake.Location.Name, *line.Variance)
+	}
+	if err := s.stockRepo.SetQuantity(tx, product.ID, stocktake.LocationID, newLocationStock); err != nil {
+		return nil, nil, 0, err
+	}
+	newStock, err := s.stockRepo.SyncProductStock(tx, product.ID, userID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	applied := newAppliedTransaction(product, balance, direction, newStock, newLocationStock)
+	totalAmount, err := quantity.MulAmount(product.Price)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	adjustment := &model.Transaction{
+		ProductID:    product.ID,
+		LocationID:   &stocktake.LocationID,
+		Type:         model.TxAdjustment,
+		Direction:    direction,
+		ReasonCode:   reason,
+		Quantity:     quantity,
+		TotalAmount:  totalAmount,
+		Unit:         product.Unit,
+		UnitQuantity: quantity,
+		UnitFactor:   1,
+		Note:         fmt.Sprintf("Stocktake %s", stocktake.Number),
+		StocktakeID:  &stocktake.ID,
+	}
+	adjustment.CreatedBy = userID
+	adjustment.UpdatedBy = userID
+	adjustment.CreatedByUserID = &userID
+	if err := tx.Create(adjustment).Error; err != nil {
+		return nil, nil, 0, err
+	}
+	return adjustment, applied, newLocationStock, nil
+}
+
+// CancelStocktake closes an open stocktake without touching stock
+func (s *stocktakeService) CancelStocktake(id uuid.UUID, userID, userName, userEmail string) (*model.Stocktake, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		stocktake, err := s.stocktakeRepo.FindByIDForUpdate(tx, id)
+		if err != nil {
+			return ErrStocktakeNotFound
+		}
+		if stocktake.Status != model.StocktakeOpen {
+			return ErrStocktakeNotOpen
+		}
+		if stocktake.Location, err = s.locations.GetLocation(stocktake.LocationID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		stocktake.Status = model.StocktakeCancelled
+		stocktake.CancelledAt = &now
+		stocktake.UpdatedBy = userID
+		if err := s.stocktakeRepo.Save(tx, stocktake); err != nil {
+			return err
+		}
+
+		event := events.New(events.StocktakeCancelled{
+			Stocktake: stocktakeSummary(stocktake, lineProgress(stocktake.Lines)),
+			Message:   fmt.Sprintf("%s cancelled stocktake %s", userName, stocktake.Number),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", stocktakeTopics(stocktake)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return s.GetStocktake(id)
+}
+
+func (s *stocktakeService) GetStocktakes(filter repository.StocktakeFilter) ([]model.Stocktake, int64, error) {
+	return s.stocktakeRepo.FindAll(filter)
+}
+
+func (s *stocktakeService) GetStocktake(id uuid.UUID) (*model.Stocktake, error) {
+	stocktake, err := s.stocktakeRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrStocktakeNotFound
+	}
+	progress := lineProgress(stocktake.Lines)
+	stocktake.Progress = &progress
+	return stocktake, nil
+}
+
+func lineProgress(lines []model.StocktakeLine) model.StocktakeProgress {
+	progress := model.StocktakeProgress{Total: len(lines)}
+	for _, line := range lines {
+		if line.CountedQty != nil {
+			progress.Counted++
+		}
+	}
+	return progress
+}
+
+// stocktakeTopics notifies stocktake screens and the location's subscribers
+func stocktakeTopics(st *model.Stocktake) []string {
+	return []string{ws.TopicStocktakes, ws.LocationTopic(st.LocationID)}
+}
+
+func stocktakeSummary(st *model.Stocktake, progress model.StocktakeProgress) events.StocktakeSummary {
+	summary := events.StocktakeSummary{
+		ID:         st.ID,
+		Number:     st.Number,
+		Status:     string(st.Status),
+		LocationID: st.LocationID,
+		Counted:    progress.Counted,
+		Total:      progress.Total,
+	}
+	if st.Location != nil {
+		summary.LocationName = st.Location.Name
+	}
+	return summary
+}
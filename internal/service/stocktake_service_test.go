@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+)
+
+type stocktakeCount struct {
+	product  *model.Product
+	expected decimal.Decimal
+	counted  *decimal.Decimal
+}
+
+func addOpenStocktake(env *testEnv, location *model.Location, counts ...stocktakeCount) *model.Stocktake {
+	stocktake := &model.Stocktake{Number: "STK-TEST", LocationID: location.ID, Status: model.StocktakeOpen}
+	stocktake.ID = uuid.New()
+	for _, c := range counts {
+		stocktake.Lines = append(stocktake.Lines, model.StocktakeLine{
+			ID:          uuid.New(),
+			StocktakeID: stocktake.ID,
+			ProductID:   c.product.ID,
+			ExpectedQty: c.expected,
+			CountedQty:  c.counted,
+		})
+	}
+	env.store.stocktakes[stocktake.ID] = stocktake
+	return stocktake
+}
+
+func counted(n int) *decimal.Decimal {
+	q := qty(n)
+	return &q
+}
+
+func TestApproveStocktakePostsVariances(t *testing.T) {
+	env := newTestEnv(t)
+	stocktakes := NewStocktakeService(&fakeStocktakeRepo{store: env.store}, env.products, env.stock, env.locations, env.units, env.reasons, env.db, env.outbox)
+	short := env.addProduct("Short", 1000)
+	exact := env.addProduct("Exact", 1000)
+	over := env.addProduct("Over", 1000)
+
+	// Short received 2 more after the snapshot, Exact sold 1: the variance applies
+	// on top of the current balance
+	env.setBalance(short, env.warehouse, qty(12), 0)
+	env.setBalance(exact, env.warehouse, qty(4), 0)
+	env.setBalance(over, env.warehouse, qty(2), 0)
+	stocktake := addOpenStocktake(env, env.warehouse,
+		stocktakeCount{short, qty(10), counted(8)},
+		stocktakeCount{exact, qty(5), counted(5)},
+		stocktakeCount{over, qty(2), counted(4)},
+	)
+
+	approved, err := stocktakes.ApproveStocktake(stocktake.ID, "u1", "Ana", "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status != model.StocktakeApproved || approved.ApprovedBy != "u1" {
+		t.Errorf("status = %s by %q, want APPROVED by u1", approved.Status, approved.ApprovedBy)
+	}
+
+	want := map[uuid.UUID]struct {
+		direction model.TransactionType
+		quantity  decimal.Decimal
+		resulting decimal.Decimal
+	}{
+		short.ID: {model.TxOut, qty(2), qty(10)},
+		exact.ID: {"", 0, qty(4)},
+		over.ID:  {model.TxIn, qty(2), qty(4)},
+	}
+	adjustments := make(map[uuid.UUID]*model.Transaction)
+	for _, adjustment := range env.store.created {
+		adjustments[adjustment.ProductID] = adjustment
+	}
+	if len(env.store.created) != 2 {
+		t.Errorf("%d adjustments posted, want 2 (none for the line without variance)", len(env.store.created))
+	}
+
+	if len(approved.Lines) != 3 {
+		t.Fatalf("%d lines, want 3", len(approved.Lines))
+	}
+	for _, line := range approved.Lines {
+		w := want[line.ProductID]
+		if line.ResultingQty == nil || *line.ResultingQty != w.resulting {
+			t.Errorf("product %s resulting qty = %v, want %s", line.ProductID, line.ResultingQty, w.resulting)
+		}
+		if b := env.store.balances[balanceKey{line.ProductID, env.warehouse.ID}]; b.Quantity != w.resulting {
+			t.Errorf("product %s balance = %s, want %s", line.ProductID, b.Quantity, w.resulting)
+		}
+
+		adjustment := adjustments[line.ProductID]
+		if w.direction == "" {
+			if adjustment != nil || line.AdjustmentID != nil {
+				t.Errorf("product %s got an adjustment without variance", line.ProductID)
+			}
+			continue
+		}
+		if adjustment == nil {
+			t.Errorf("product %s has no adjustment", line.ProductID)
+			continue
+		}
+		if adjustment.Type != model.TxAdjustment || adjustment.Direction != w.direction || adjustment.Quantity != w.quantity {
+			t.Errorf("product %s adjustment = %s %s %s, want ADJUSTMENT %s %s", line.ProductID, adjustment.Type, adjustment.Direction, adjustment.Quantity, w.direction, w.quantity)
+		}
+		if adjustment.ReasonCode != model.StocktakeReasonCode || adjustment.StocktakeID == nil || *adjustment.StocktakeID != stocktake.ID {
+			t.Errorf("product %s adjustment is not a %s of the stocktake", line.ProductID, model.StocktakeReasonCode)
+		}
+		if line.AdjustmentID == nil || *line.AdjustmentID != adjustment.ID {
+			t.Errorf("product %s line does not point to its adjustment", line.ProductID)
+		}
+	}
+
+	if pending := env.store.pendingEventTypes(t); len(pending) == 0 || pending[0] != events.TypeStocktakeApproved {
+		t.Errorf("events = %v, want %s first", pending, events.TypeStocktakeApproved)
+	}
+
+	if _, err := stocktakes.ApproveStocktake(stocktake.ID, "u1", "Ana", "ana@example.com"); !errors.Is(err, ErrStocktakeNotOpen) {
+		t.Errorf("second approval: %v, want %v", err, ErrStocktakeNotOpen)
+	}
+}
+
+func TestApproveStocktakeNeedsEveryCount(t *testing.T) {
+	env := newTestEnv(t)
+	stocktakes := NewStocktakeService(&fakeStocktakeRepo{store: env.store}, env.products, env.stock, env.locations, env.units, env.reasons, env.db, env.outbox)
+	done := env.addProduct("Done", 1000)
+	missing := env.addProduct("Missing", 1000)
+	env.setBalance(done, env.warehouse, qty(3), 0)
+	env.setBalance(missing, env.warehouse, qty(3), 0)
+	stocktake := addOpenStocktake(env, env.warehouse,
+		stocktakeCount{done, qty(3), counted(1)},
+		stocktakeCount{missing, qty(3), nil},
+	)
+
+	if _, err := stocktakes.ApproveStocktake(stocktake.ID, "u1", "Ana", "ana@example.com"); !errors.Is(err, ErrStocktakeIncomplete) {
+		t.Fatalf("approval: %v, want %v", err, ErrStocktakeIncomplete)
+	}
+	if len(env.store.created) != 0 || env.store.stocktakes[stocktake.ID].Status != model.StocktakeOpen {
+		t.Error("an incomplete stocktake posted adjustments or left OPEN")
+	}
+	if b := env.balance(done, env.warehouse); b.Quantity != qty(3) {
+		t.Errorf("balance = %s, want 3", b.Quantity)
+	}
+}
@@ -0,0 +1,769 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/internal/ws"
+	"go-inventory-ws/pkg/decimal"
+	"go-inventory-ws/pkg/validator"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTransferNotFound     = errors.New("transfer not found")
+	ErrTransferInvalidState = errors.New("transfer is not in a state allowing this action")
+)
+
+type TransferService interface {
+	CreateTransfer(req *CreateTransferRequest, userID, userName, userEmail string) (*model.StockTransfer, error)
+	DispatchTransfer(id uuid.UUID, userID, userName, userEmail string) (*model.StockTransfer, error)
+	ReceiveTransfer(id uuid.UUID, req *ReceiveTransferRequest, userID, userName, userEmail string) (*model.StockTransfer, error)
+	CancelTransfer(id uuid.UUID, userID, userName, userEmail string) (*model.StockTransfer, error)
+	GetTransfers(filter repository.TransferFilter) ([]model.StockTransfer, int64, error)
+	GetTransfer(id uuid.UUID) (*model.StockTransfer, error)
+}
+
+type CreateTransferRequest struct {
+	FromLocationID uuid.UUID             `json:"from_location_id" validate:"uuid_required"`
+	ToLocationID   uuid.UUID             `json:"to_location_id" validate:"uuid_required"`
+	Note           string                `json:"note"`
+	Lines          []TransferLineRequest `json:"lines" validate:"required,min=1,dive"`
+}
+
+type TransferLineRequest struct {
+	ProductID uuid.UUID       `json:"product_id" validate:"uuid_required"`
+	Quantity  decimal.Decimal `json:"quantity" validate:"required,gt=0"`
+	Serials   []string        `json:"serials"` // Serialized products: the units to ship, one per unit
+}
+
+// ReceiveTransferRequest records a (partial) receipt. With Close, whatever is
+// still outstanding afterwards is recorded as a discrepancy and the transfer is closed.
+type ReceiveTransferRequest struct {
+	Lines []ReceiveLineRequest `json:"lines" validate:"dive"`
+	Close bool                 `json:"close"`
+	Note  string               `json:"note"` // Default discrepancy note
+}
+
+type ReceiveLineRequest struct {
+	LineID          uuid.UUID       `json:"line_id" validate:"uuid_required"`
+	Quantity        decimal.Decimal `json:"quantity" validate:"gte=0"`
+	DiscrepancyNote string          `json:"discrepancy_note"`
+	// Serialized products: the units received. May be left out when receiving everything outstanding.
+	Serials []string `json:"serials"`
+}
+
+type transferService struct {
+	transferRepo repository.TransferRepository
+	productRepo  repository.ProductRepository
+	stockRepo    repository.StockRepository
+	lotRepo      repository.LotRepository
+	serialRepo   repository.SerialRepository
+	reservations repository.ReservationRepository
+	locations    LocationService
+	reasons      ReasonService
+	db           *gorm.DB
+	outbox       *Outbox
+}
+
+func NewTransferService(transferRepo repository.TransferRepository, productRepo repository.ProductRepository, stockRepo repository.StockRepository, lotRepo repository.LotRepository, serialRepo repository.SerialRepository, reservations repository.ReservationRepository, locations LocationService, reasons ReasonService, db *gorm.DB, outbox *Outbox) TransferService {
+	return &transferService{
+		transferRepo: transferRepo,
+		productRepo:  productRepo,
+		stockRepo:    stockRepo,
+		lotRepo:      lotRepo,
+		serialRepo:   serialRepo,
+		reservations: reservations,
+		locations:    locations,
+		reasons:      reasons,
+		db:           db,
+		outbox:       outbox,
+	}
+}
+
+func (s *transferService) CreateTransfer(req *CreateTransferRequest, userID, userName, userEmail string) (*model.StockTransfer, error) {
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return nil, fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+	if req.FromLocationID == req.ToLocationID {
+		return nil, errors.New("source and destination locations must differ")
+	}
+	from, err := s.locations.ResolveLocation(&req.FromLocationID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.locations.ResolveLocation(&req.ToLocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := &model.StockTransfer{
+		Number:         documentNumber("TRF"),
+		FromLocationID: from.ID,
+		ToLocationID:   to.ID,
+		Status:         model.TransferDraft,
+		Note:           req.Note,
+	}
+	transfer.CreatedBy = userID
+	transfer.UpdatedBy = userID
+
+	seen := make(map[uuid.UUID]bool, len(req.Lines))
+	serials := make([][]string, len(req.Lines)) // Per line, serialized products only
+	for i, line := range req.Lines {
+		if seen[line.ProductID] {
+			return nil, errors.New("each product can only appear once per transfer")
+		}
+		seen[line.ProductID] = true
+
+		product, err := s.productRepo.FindByID(line.ProductID)
+		if err != nil {
+			return nil, ErrProductNotFound
+		}
+		if product.IsArchived() {
+			return nil, ErrProductArchived
+		}
+		if product.Serialized {
+			if serials[i], err = normalizeSerials(line.Serials, line.Quantity); err != nil {
+				return nil, err
+			}
+		} else if len(line.Serials) > 0 {
+			return nil, ErrSerialsNotTracked
+		}
+		transfer.Lines = append(transfer.Lines, model.StockTransferLine{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+		})
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.transferRepo.Create(tx, transfer); err != nil {
+			return err
+		}
+		for i := range transfer.Lines {
+			if serials[i] != nil {
+				if err := s.assignLineSerials(tx, transfer, &transfer.Lines[i], serials[i]); err != nil {
+					return err
+				}
+			}
+		}
+		transfer.FromLocation, transfer.ToLocation = from, to
+
+		event := events.New(events.StockTransferCreated{
+			Transfer: transferSummary(transfer),
+			Message:  fmt.Sprintf("%s created transfer %s from %s to %s", userName, transfer.Number, from.Name, to.Name),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", transferTopics(transfer, false)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return transfer, nil
+}
+
+// DispatchTransfer takes the goods out of the source location. They stay counted
+// in the product total as in-transit stock of the destination until received.
+func (s *transferService) DispatchTransfer(id uuid.UUID, userID, userName, userEmail string) (*model.StockTransfer, error) {
+	var dispatched *model.StockTransfer
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		transfer, err := s.lockTransfer(tx, id)
+		if err != nil {
+			return err
+		}
+		if transfer.Status != model.TransferDraft {
+			return ErrTransferInvalidState
+		}
+		if _, err := s.locations.ResolveLocation(&transfer.FromLocationID); err != nil {
+			return err
+		}
+		if _, err := s.locations.ResolveLocation(&transfer.ToLocationID); err != nil {
+			return err
+		}
+
+		// Pessimistic locking, same order as RecordTransaction: products (sorted by ID,
+		// so concurrent multi-line documents can't deadlock), then their balances
+		lines := sortedLines(transfer.Lines)
+		products := make(map[uuid.UUID]*model.Product, len(lines))
+		for _, line := range lines {
+			product, err := s.productRepo.FindByIDForUpdate(tx, line.ProductID, false)
+			if err != nil {
+				return ErrProductNotFound
+			}
+			if product.IsArchived() {
+				return ErrProductArchived
+			}
+			products[product.ID] = product
+		}
+
+		for _, line := range lines {
+			product := products[line.ProductID]
+			source, dest, err := s.lockBalancePair(tx, line.ProductID, transfer.FromLocationID, transfer.ToLocationID)
+			if err != nil {
+				return err
+			}
+			if source.Quantity < line.Quantity {
+				return fmt.Errorf("insufficient stock remaining at %s for product %s", transfer.FromLocation.Name, line.ProductID)
+			}
+			// Stock held for orders stays at the source
+			reserved, err := s.reservations.ReservedQuantity(tx, line.ProductID, transfer.FromLocationID, nil)
+			if err != nil {
+				return err
+			}
+			if source.Quantity-reserved < line.Quantity {
+				return fmt.Errorf("insufficient stock available at %s for product %s: %s on hand, %s reserved", transfer.FromLocation.Name, line.ProductID, source.Quantity, reserved)
+			}
+			if err := s.stockRepo.SetQuantity(tx, line.ProductID, transfer.FromLocationID, source.Quantity-line.Quantity); err != nil {
+				return err
+			}
+			if err := s.stockRepo.SetInTransit(tx, line.ProductID, transfer.ToLocationID, dest.InTransit+line.Quantity); err != nil {
+				return err
+			}
+
+			// Lot-tracked: ship non-expired lots FEFO, the destination receives the same lots
+			var picks []lotPick
+			if product.TrackLots {
+				picks, err = pickLots(tx, s.lotRepo, line.ProductID, transfer.FromLocationID, line.Quantity, "", false)
+				if err != nil {
+					return err
+				}
+				line.Lots = make([]model.TransferLineLot, len(picks))
+				for i, pick := range picks {
+					line.Lots[i] = model.TransferLineLot{
+						TransferLineID: line.ID,
+						LotNumber:      pick.lot.LotNumber,
+						ExpiryDate:     pick.lot.ExpiryDate,
+						Quantity:       pick.quantity,
+					}
+				}
+				if err := s.lotRepo.CreateTransferLineLots(tx, line.Lots); err != nil {
+					return err
+				}
+			}
+
+			// Serialized: the units chosen at creation must still be in stock at the source
+			var units []model.SerialNumber
+			if product.Serialized {
+				if len(line.Serials) != line.Quantity.Int() {
+					return fmt.Errorf("transfer line for product %s has no serial numbers, recreate the transfer", line.ProductID)
+				}
+				units, err = moveSerials(tx, s.serialRepo, line.ProductID, lineSerialNumbers(line.Serials, false),
+					model.SerialInStock, transfer.FromLocationID, model.SerialInTransit, transfer.ToLocationID)
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := s.recordMovement(tx, transfer, product, transfer.FromLocationID, model.TxOut, line.Quantity, picks, units, userID); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		transfer.Status = model.TransferInTransit
+		transfer.DispatchedAt = &now
+		transfer.DispatchedBy = userID
+		transfer.UpdatedBy = userID
+		if err := s.transferRepo.Save(tx, transfer); err != nil {
+			return err
+		}
+		dispatched = transfer
+
+		event := events.New(events.StockTransferDispatched{
+			Transfer: transferSummary(transfer),
+			Message:  fmt.Sprintf("%s dispatched transfer %s from %s to %s", userName, transfer.Number, transfer.FromLocation.Name, transfer.ToLocation.Name),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", transferTopics(transfer, true)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return dispatched, nil
+}
+
+// ReceiveTransfer moves received quantities from in-transit to on-hand at the destination
+func (s *transferService) ReceiveTransfer(id uuid.UUID, req *ReceiveTransferRequest, userID, userName, userEmail string) (*model.StockTransfer, error) {
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return nil, fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+	if len(req.Lines) == 0 && !req.Close {
+		return nil, errors.New("nothing to receive")
+	}
+
+	var received *model.StockTransfer
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		transfer, err := s.lockTransfer(tx, id)
+		if err != nil {
+			return err
+		}
+		if transfer.Status != model.TransferInTransit && transfer.Status != model.TransferPartiallyReceived {
+			return ErrTransferInvalidState
+		}
+
+		receipts := make(map[uuid.UUID]ReceiveLineRequest, len(req.Lines))
+		for _, r := range req.Lines {
+			if _, dup := receipts[r.LineID]; dup {
+				return errors.New("each line can only appear once per receipt")
+			}
+			receipts[r.LineID] = r
+		}
+		for lineID, r := range receipts {
+			line := findLine(transfer.Lines, lineID)
+			if line == nil {
+				return fmt.Errorf("line %s does not belong to transfer %s", lineID, transfer.Number)
+			}
+			if r.Quantity > line.Outstanding() {
+				return fmt.Errorf("received quantity for product %s exceeds the outstanding %s", line.ProductID, line.Outstanding())
+			}
+		}
+
+		// Lock products (sorted), then the destination balances
+		lines := sortedLines(transfer.Lines)
+		products := make(map[uuid.UUID]*model.Product, len(lines))
+		serialized := make(map[uuid.UUID]bool, len(lines))
+		for _, line := range lines {
+			product, err := s.productRepo.FindByIDForUpdate(tx, line.ProductID, true)
+			if err != nil {
+				return ErrProductNotFound
+			}
+			products[product.ID] = product
+			serialized[product.ID] = len(line.Serials) > 0
+			if len(receipts[line.ID].Serials) > 0 && !serialized[product.ID] {
+				return ErrSerialsNotTracked
+			}
+		}
+
+		var thisReceipt []events.TransferLineSummary
+		for _, line := range lines {
+			r := receipts[line.ID]
+			discrepancy := decimal.Zero
+			if req.Close {
+				discrepancy = line.Outstanding() - r.Quantity
+			}
+			if r.Quantity == 0 && discrepancy == 0 {
+				continue
+			}
+
+			dest, err := s.stockRepo.LockBalance(tx, line.ProductID, transfer.ToLocationID)
+			if err != nil {
+				return err
+			}
+			if err := s.stockRepo.SetQuantity(tx, line.ProductID, transfer.ToLocationID, dest.Quantity+r.Quantity); err != nil {
+				return err
+			}
+			if err := s.stockRepo.SetInTransit(tx, line.ProductID, transfer.ToLocationID, dest.InTransit-r.Quantity-discrepancy); err != nil {
+				return err
+			}
+			picks, err := s.receiveLineLots(tx, transfer, line, r.Quantity)
+			if err != nil {
+				return err
+			}
+			var units []model.SerialNumber
+			if serialized[line.ProductID] {
+				if units, err = s.receiveLineSerials(tx, transfer, line, r, discrepancy); err != nil {
+					return err
+				}
+			}
+			if r.Quantity > 0 {
+				if err := s.recordMovement(tx, transfer, products[line.ProductID], transfer.ToLocationID, model.TxIn, r.Quantity, picks, units, userID); err != nil {
+					return err
+				}
+			}
+
+			line.ReceivedQty += r.Quantity
+			if discrepancy > 0 {
+				// Shipped but never arrived: leaves the product total as a transit loss
+				line.DiscrepancyQty += discrepancy
+				line.DiscrepancyNote = r.DiscrepancyNote
+				if line.DiscrepancyNote == "" {
+					line.DiscrepancyNote = req.Note
+				}
+				transfer.HasDiscrepancy = true
+				if _, err := s.stockRepo.SyncProductStock(tx, line.ProductID, userID); err != nil {
+					return err
+				}
+				if err := s.recordTransitLoss(tx, transfer, products[line.ProductID], discrepancy, line.DiscrepancyNote, userID); err != nil {
+					return err
+				}
+			}
+			if err := s.transferRepo.SaveLine(tx, line); err != nil {
+				return err
+			}
+
+			thisReceipt = append(thisReceipt, events.TransferLineSummary{
+				ProductID:      line.ProductID,
+				Quantity:       line.Quantity,
+				ReceivedQty:    r.Quantity,
+				DiscrepancyQty: discrepancy,
+			})
+		}
+
+		transfer.Status = model.TransferReceived
+		for _, line := range transfer.Lines {
+			if line.Outstanding() > 0 {
+				transfer.Status = model.TransferPartiallyReceived
+				break
+			}
+		}
+		if transfer.Status == model.TransferReceived {
+			now := time.Now()
+			transfer.ReceivedAt = &now
+			transfer.ReceivedBy = userID
+		}
+		transfer.UpdatedBy = userID
+		if err := s.transferRepo.Save(tx, transfer); err != nil {
+			return err
+		}
+		received = transfer
+
+		verb := "partially received"
+		if transfer.Status == model.TransferReceived {
+			verb = "received"
+		}
+		event := events.New(events.StockTransferReceived{
+			Transfer: transferSummary(transfer),
+			Received: thisReceipt,
+			Message:  fmt.Sprintf("%s %s transfer %s at %s", userName, verb, transfer.Number, transfer.ToLocation.Name),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", transferTopics(transfer, true)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return received, nil
+}
+
+// CancelTransfer cancels a draft. Transfers already in transit have to be received
+// (with close, recording what never arrived as discrepancy).
+func (s *transferService) CancelTransfer(id uuid.UUID, userID, userName, userEmail string) (*model.StockTransfer, error) {
+	var cancelled *model.StockTransfer
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		transfer, err := s.lockTransfer(tx, id)
+		if err != nil {
+			return err
+		}
+		if transfer.Status != model.TransferDraft {
+			return ErrTransferInvalidState
+		}
+
+		transfer.Status = model.TransferCancelled
+		transfer.UpdatedBy = userID
+		if err := s.transferRepo.Save(tx, transfer); err != nil {
+			return err
+		}
+		cancelled = transfer
+
+		event := events.New(events.StockTransferCancelled{
+			Transfer: transferSummary(transfer),
+			Message:  fmt.Sprintf("%s cancelled transfer %s", userName, transfer.Number),
+		}, &events.Actor{ID: userID, Name: userName, Email: userEmail})
+		return s.outbox.Enqueue(tx, event, "", transferTopics(transfer, false)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Wake()
+	return cancelled, nil
+}
+
+func (s *transferService) GetTransfers(filter repository.TransferFilter) ([]model.StockTransfer, int64, error) {
+	return s.transferRepo.FindAll(filter)
+}
+
+func (s *transferService) GetTransfer(id uuid.UUID) (*model.StockTransfer, error) {
+	transfer, err := s.transferRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrTransferNotFound
+	}
+	return transfer, nil
+}
+
+// lockTransfer locks the transfer row and loads its lines and locations
+func (s *transferService) lockTransfer(tx *gorm.DB, id uuid.UUID) (*model.StockTransfer, error) {
+	transfer, err := s.transferRepo.FindByIDForUpdate(tx, id)
+	if err != nil {
+		return nil, ErrTransferNotFound
+	}
+	if transfer.FromLocation, err = s.locations.GetLocation(transfer.FromLocationID); err != nil {
+		return nil, err
+	}
+	if transfer.ToLocation, err = s.locations.GetLocation(transfer.ToLocationID); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// assignLineSerials records the units a draft transfer line will ship. They must
+// be in stock at the source; dispatch checks again under lock.
+func (s *transferService) assignLineSerials(tx *gorm.DB, transfer *model.StockTransfer, line *model.StockTransferLine, serials []string) error {
+	units, err := s.serialRepo.LockSerials(tx, line.ProductID, serials)
+	if err != nil {
+		return err
+	}
+	if len(units) != len(serials) {
+		return fmt.Errorf("some serial numbers of product %s were not found", line.ProductID)
+	}
+
+	line.Serials = make([]model.TransferLineSerial, len(units))
+	for i, unit := range units {
+		if unit.Status != model.SerialInStock || unit.LocationID == nil || *unit.LocationID != transfer.FromLocationID {
+			return fmt.Errorf("serial %s is not in stock at the source location", unit.Serial)
+		}
+		line.Serials[i] = model.TransferLineSerial{TransferLineID: line.ID, SerialID: unit.ID}
+	}
+	return s.serialRepo.CreateTransferLineSerials(tx, line.Serials)
+}
+
+// receiveLineSerials moves the received units of a serialized line into stock at the
+// destination, and the ones never received (discrepancy) to MISSING
+func (s *transferService) receiveLineSerials(tx *gorm.DB, transfer *model.StockTransfer, line *model.StockTransferLine, r ReceiveLineRequest, discrepancy decimal.Decimal) ([]model.SerialNumber, error) {
+	pending := make(map[string]*model.TransferLineSerial, len(line.Serials))
+	for i := range line.Serials {
+		if line.Serials[i].ReceivedAt == nil && line.Serials[i].Serial != nil && line.Serials[i].Serial.Status == model.SerialInTransit {
+			pending[line.Serials[i].Serial.Serial] = &line.Serials[i]
+		}
+	}
+
+	received := r.Serials
+	if len(received) == 0 && r.Quantity > 0 {
+		// Everything outstanding arrived, no need to list the units
+		if r.Quantity != decimal.FromInt(len(pending)) {
+			return nil, fmt.Errorf("serial numbers are required to receive part of the units of product %s", line.ProductID)
+		}
+		received = lineSerialNumbers(line.Serials, true)
+	}
+	received, err := normalizeSerials(received, r.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, serial := range received {
+		lineSerial, ok := pending[serial]
+		if !ok {
+			return nil, fmt.Errorf("serial %s is not outstanding on this transfer", serial)
+		}
+		lineSerial.ReceivedAt = &now
+		if err := s.serialRepo.SaveTransferLineSerial(tx, lineSerial); err != nil {
+			return nil, err
+		}
+		delete(pending, serial)
+	}
+	units, err := moveSerials(tx, s.serialRepo, line.ProductID, received, model.SerialInTransit, transfer.ToLocationID, model.SerialInStock, transfer.ToLocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if discrepancy > 0 {
+		missing := make([]string, 0, len(pending))
+		for serial := range pending {
+			missing = append(missing, serial)
+		}
+		if _, err := moveSerials(tx, s.serialRepo, line.ProductID, missing, model.SerialInTransit, transfer.ToLocationID, model.SerialMissing, transfer.ToLocationID); err != nil {
+			return nil, err
+		}
+	}
+	return units, nil
+}
+
+// lineSerialNumbers lists the serial numbers of a line (only the unreceived ones with pendingOnly)
+func lineSerialNumbers(lineSerials []model.TransferLineSerial, pendingOnly bool) []string {
+	serials := make([]string, 0, len(lineSerials))
+	for _, ls := range lineSerials {
+		if ls.Serial == nil || (pendingOnly && ls.ReceivedAt != nil) {
+			continue
+		}
+		serials = append(serials, ls.Serial.Serial)
+	}
+	return serials
+}
+
+// receiveLineLots puts a received quantity into the destination lots, following the
+// lots the line was dispatched with (in FEFO order). Lines without lots are a no-op.
+func (s *transferService) receiveLineLots(tx *gorm.DB, transfer *model.StockTransfer, line *model.StockTransferLine, quantity decimal.Decimal) ([]lotPick, error) {
+	var picks []lotPick
+	for i := range line.Lots {
+		if quantity == 0 {
+			break
+		}
+		lineLot := &line.Lots[i]
+		take := lineLot.Quantity - lineLot.ReceivedQty
+		if take > quantity {
+			take = quantity
+		}
+		if take <= 0 {
+			continue
+		}
+		lot, err := receiveLot(tx, s.lotRepo, line.ProductID, transfer.ToLocationID, lineLot.LotNumber, lineLot.ExpiryDate, take)
+		if err != nil {
+			return nil, err
+		}
+		lineLot.ReceivedQty += take
+		if err := s.lotRepo.SaveTransferLineLot(tx, lineLot); err != nil {
+			return nil, err
+		}
+		picks = append(picks, lotPick{lot: lot, quantity: take})
+		quantity -= take
+	}
+	return picks, nil
+}
+
+// recordMovement logs one side of a transfer line as a TRANSFER transaction (OUT at
+// the source on dispatch, IN at the destination on receipt), so the stock ledger of
+// each location includes transfers
+func (s *transferService) recordMovement(tx *gorm.DB, transfer *model.StockTransfer, product *model.Product, locationID uuid.UUID, direction model.TransactionType, quantity decimal.Decimal, picks []lotPick, units []model.SerialNumber, userID string) error {
+	movement := &model.Transaction{
+		ProductID:    product.ID,
+		LocationID:   &locationID,
+		Type:         model.TxTransfer,
+		Direction:    direction,
+		Quantity:     quantity,
+		Unit:         product.Unit,
+		UnitQuantity: quantity,
+		UnitFactor:   1,
+		Note:         fmt.Sprintf("Transfer %s", transfer.Number),
+		TransferID:   &transfer.ID,
+	}
+	movement.CreatedBy = userID
+	movement.UpdatedBy = userID
+	movement.CreatedByUserID = &userID
+	if err := tx.Create(movement).Error; err != nil {
+		return err
+	}
+	return linkLotsAndSerials(tx, s.lotRepo, s.serialRepo, movement, picks, units)
+}
+
+// recordTransitLoss logs what never arrived as an ADJUSTMENT OUT at the destination,
+// linked to the transfer, so the ledger and the shrinkage report include it. The
+// quantity comes out of in-transit stock, not the destination's on-hand balance.
+func (s *transferService) recordTransitLoss(tx *gorm.DB, transfer *model.StockTransfer, product *model.Product, quantity decimal.Decimal, note string, userID string) error {
+	reason, err := s.reasons.ResolveReason(model.TxAdjustment, model.TxOut, model.TransitLossReasonCode)
+	if err != nil {
+		return err
+	}
+	totalAmount, err := quantity.MulAmount(product.Price)
+	if err != nil {
+		return err
+	}
+	if note == "" {
+		note = fmt.Sprintf("Lost in transit, transfer %s", transfer.Number)
+	}
+
+	loss := &model.Transaction{
+		ProductID:    product.ID,
+		LocationID:   &transfer.ToLocationID,
+		Type:         model.TxAdjustment,
+		Direction:    model.TxOut,
+		ReasonCode:   reason,
+		Quantity:     quantity,
+		TotalAmount:  totalAmount,
+		Unit:         product.Unit,
+		UnitQuantity: quantity,
+		UnitFactor:   1,
+		Note:         note,
+		TransferID:   &transfer.ID,
+	}
+	loss.CreatedBy = userID
+	loss.UpdatedBy = userID
+	loss.CreatedByUserID = &userID
+	return tx.Create(loss).Error
+}
+
+// lockBalancePair locks the balances of a product at two locations, in location ID order
+func (s *transferService) lockBalancePair(tx *gorm.DB, productID, fromID, toID uuid.UUID) (from, to *model.StockBalance, err error) {
+	if fromID.String() < toID.String() {
+		if from, err = s.stockRepo.LockBalance(tx, productID, fromID); err != nil {
+			return nil, nil, err
+		}
+		to, err = s.stockRepo.LockBalance(tx, productID, toID)
+		return from, to, err
+	}
+	if to, err = s.stockRepo.LockBalance(tx, productID, toID); err != nil {
+		return nil, nil, err
+	}
+	from, err = s.stockRepo.LockBalance(tx, productID, fromID)
+	return from, to, err
+}
+
+// sortedLines returns pointers to the lines ordered by product ID (the lock order)
+func sortedLines(lines []model.StockTransferLine) []*model.StockTransferLine {
+	sorted := make([]*model.StockTransferLine, len(lines))
+	for i := range lines {
+		sorted[i] = &lines[i]
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ProductID.String() < sorted[j].ProductID.String()
+	})
+	return sorted
+}
+
+func findLine(lines []model.StockTransferLine, id uuid.UUID) *model.StockTransferLine {
+	for i := range lines {
+		if lines[i].ID == id {
+			return &lines[i]
+		}
+	}
+	return nil
+}
+
+// documentNumber generates a human readable document number, e.g. TRF-20240131-3F9A1C
+func documentNumber(prefix string) string {
+	return fmt.Sprintf("%s-%s-%s", prefix, time.Now().Format("20060102"), strings.ToUpper(uuid.NewString()[:6]))
+}
+
+// transferTopics notifies both locations; stock changes also reach product subscribers
+func transferTopics(t *model.StockTransfer, stockMoved bool) []string {
+	topics := []string{ws.TopicTransfers, ws.LocationTopic(t.FromLocationID), ws.LocationTopic(t.ToLocationID)}
+	if stockMoved {
+		topics = append(topics, ws.TopicProducts)
+		for _, line := range t.Lines {
+			topics = append(topics, ws.ProductTopic(line.ProductID))
+		}
+	}
+	return topics
+}
+
+func transferSummary(t *model.StockTransfer) events.TransferSummary {
+	summary := events.TransferSummary{
+		ID:             t.ID,
+		Number:         t.Number,
+		Status:         string(t.Status),
+		FromLocationID: t.FromLocationID,
+		ToLocationID:   t.ToLocationID,
+		HasDiscrepancy: t.HasDiscrepancy,
+		Lines:          make([]events.TransferLineSummary, len(t.Lines)),
+	}
+	if t.FromLocation != nil {
+		summary.FromLocationName = t.FromLocation.Name
+	}
+	if t.ToLocation != nil {
+		summary.ToLocationName = t.ToLocation.Name
+	}
+	for i, line := range t.Lines {
+		summary.Lines[i] = events.TransferLineSummary{
+			ProductID:      line.ProductID,
+			Quantity:       line.Quantity,
+			ReceivedQty:    line.ReceivedQty,
+			DiscrepancyQty: line.DiscrepancyQty,
+		}
+	}
+	return summary
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/pkg/decimal"
+
+	"github.com/google/uuid"
+)
+
+// addTransferInTransit seeds a dispatched transfer of one line: the quantity already
+// left the source and is held in transit at the destination
+func addTransferInTransit(env *testEnv, product *model.Product, from, to *model.Location, quantity decimal.Decimal) *model.StockTransfer {
+	transfer := &model.StockTransfer{
+		Number:         "TRF-TEST",
+		FromLocationID: from.ID,
+		ToLocationID:   to.ID,
+		Status:         model.TransferInTransit,
+	}
+	transfer.ID = uuid.New()
+	transfer.Lines = []model.StockTransferLine{{
+		ID:         uuid.New(),
+		TransferID: transfer.ID,
+		ProductID:  product.ID,
+		Quantity:   quantity,
+	}}
+	env.store.transfers[transfer.ID] = transfer
+	env.setBalance(product, to, decimal.Zero, quantity)
+	return transfer
+}
+
+func TestReceiveTransferWithDiscrepancy(t *testing.T) {
+	env := newTestEnv(t)
+	transfers := NewTransferService(&fakeTransferRepo{store: env.store}, env.products, env.stock, nil, nil, env.reservations, env.locations, env.reasons, env.db, env.outbox)
+	shop := env.addLocation("Shop", false)
+	product := env.addProduct("Soap", 2500)
+	transfer := addTransferInTransit(env, product, env.warehouse, shop, qty(10))
+	lineID := transfer.Lines[0].ID
+
+	// Partial receipt: the rest stays in transit, nothing is lost yet
+	received, err := transfers.ReceiveTransfer(transfer.ID, &ReceiveTransferRequest{
+		Lines: []ReceiveLineRequest{{LineID: lineID, Quantity: qty(4)}},
+	}, "u1", "Ana", "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.Status != model.TransferPartiallyReceived {
+		t.Errorf("status = %s, want %s", received.Status, model.TransferPartiallyReceived)
+	}
+	if b := env.balance(product, shop); b.Quantity != qty(4) || b.InTransit != qty(6) {
+		t.Errorf("shop balance = %s on hand, %s in transit, want 4 and 6", b.Quantity, b.InTransit)
+	}
+	if len(env.store.created) != 1 {
+		t.Fatalf("%d transactions recorded, want the TRANSFER IN only", len(env.store.created))
+	}
+
+	// Closing with 3 more received: the last 3 never arrived
+	received, err = transfers.ReceiveTransfer(transfer.ID, &ReceiveTransferRequest{
+		Lines: []ReceiveLineRequest{{LineID: lineID, Quantity: qty(3), DiscrepancyNote: "Box crushed"}},
+		Close: true,
+	}, "u1", "Ana", "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.Status != model.TransferReceived || !received.HasDiscrepancy {
+		t.Errorf("status = %s, has discrepancy %v, want RECEIVED with a discrepancy", received.Status, received.HasDiscrepancy)
+	}
+	line := env.store.transfers[transfer.ID].Lines[0]
+	if line.ReceivedQty != qty(7) || line.DiscrepancyQty != qty(3) || line.DiscrepancyNote != "Box crushed" {
+		t.Errorf("line received %s, discrepancy %s (%q), want 7 and 3", line.ReceivedQty, line.DiscrepancyQty, line.DiscrepancyNote)
+	}
+	if b := env.balance(product, shop); b.Quantity != qty(7) || b.InTransit != 0 {
+		t.Errorf("shop balance = %s on hand, %s in transit, want 7 and 0", b.Quantity, b.InTransit)
+	}
+	if stock := env.store.products[product.ID].Stock; stock != qty(7) {
+		t.Errorf("product stock = %s, want 7", stock)
+	}
+
+	if len(env.store.created) != 3 {
+		t.Fatalf("%d transactions recorded, want 2 TRANSFER IN and the transit loss", len(env.store.created))
+	}
+	movement, loss := env.store.created[1], env.store.created[2]
+	if movement.Type != model.TxTransfer || movement.Direction != model.TxIn || movement.Quantity != qty(3) {
+		t.Errorf("movement = %s %s %s, want TRANSFER IN 3", movement.Type, movement.Direction, movement.Quantity)
+	}
+	if loss.Type != model.TxAdjustment || loss.Direction != model.TxOut || loss.ReasonCode != model.TransitLossReasonCode {
+		t.Errorf("loss = %s %s %s, want ADJUSTMENT OUT %s", loss.Type, loss.Direction, loss.ReasonCode, model.TransitLossReasonCode)
+	}
+	if loss.Quantity != qty(3) || loss.TotalAmount != 7500 || loss.Note != "Box crushed" {
+		t.Errorf("loss = %s worth %d (%q), want 3 worth 7500", loss.Quantity, loss.TotalAmount, loss.Note)
+	}
+	if loss.TransferID == nil || *loss.TransferID != transfer.ID || loss.LocationID == nil || *loss.LocationID != shop.ID {
+		t.Error("loss is not linked to the transfer and its destination")
+	}
+
+	pending := env.store.pendingEventTypes(t)
+	if len(pending) != 2 || pending[1] != events.TypeStockTransferReceived {
+		t.Errorf("events = %v, want two %s", pending, events.TypeStockTransferReceived)
+	}
+
+	// The loss belongs to the transfer, it can't be voided on its own
+	inventory := env.inventoryService()
+	if _, err := inventory.VoidTransaction(loss.ID, "found it", "u1", "Ana", "ana@example.com"); !errors.Is(err, ErrTransferNotVoidable) {
+		t.Errorf("void of the transit loss: %v, want %v", err, ErrTransferNotVoidable)
+	}
+}
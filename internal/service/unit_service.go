@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-inventory-ws/internal/model"
+	"go-inventory-ws/internal/repository"
+	"go-inventory-ws/pkg/validator"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnitNotFound      = errors.New("unit not found in the unit catalog")
+	ErrUnitNotConfigured = errors.New("unit is not configured for this product")
+)
+
+type UnitService interface {
+	GetUnits(includeInactive bool) ([]model.UnitOfMeasure, error)
+	CreateUnit(req *model.UnitOfMeasure, userID string) error
+	UpdateUnit(code string, req *UpdateUnitRequest, userID string) (*model.UnitOfMeasure, error)
+	GetProductUnits(productID uuid.UUID) (*model.Product, []model.ProductUnit, error)
+	SetProductUnit(productID uuid.UUID, code string, factor int, userID string) (*model.ProductUnit, error)
+	RemoveProductUnit(productID uuid.UUID, code string) error
+	// CatalogUnit returns the catalog code of an active unit (matched case-insensitively)
+	CatalogUnit(code string) (string, error)
+	// ResolveUnit returns the unit a quantity was entered in and the number of base units
+	// in one of it. An empty unit is the product's base unit.
+	ResolveUnit(product *model.Product, unit string) (string, int, error)
+}
+
+type UpdateUnitRequest struct {
+	Name     string `json:"name" validate:"required"`
+	IsActive *bool  `json:"is_active"` // Optional
+}
+
+type unitService struct {
+	unitRepo    repository.UnitRepository
+	productRepo repository.ProductRepository
+}
+
+func NewUnitService(unitRepo repository.UnitRepository, productRepo repository.ProductRepository) UnitService {
+	return &unitService{unitRepo: unitRepo, productRepo: productRepo}
+}
+
+func (s *unitService) GetUnits(includeInactive bool) ([]model.UnitOfMeasure, error) {
+	return s.unitRepo.FindAll(includeInactive)
+}
+
+func (s *unitService) CreateUnit(req *model.UnitOfMeasure, userID string) error {
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+	if existing, err := s.unitRepo.FindByCode(req.Code); err == nil && existing != nil {
+		return errors.New("unit code already exists")
+	}
+
+	req.IsActive = true
+	req.CreatedBy = userID
+	req.UpdatedBy = userID
+	return s.unitRepo.Create(req)
+}
+
+// UpdateUnit renames or (de)activates a unit. Codes are immutable, products and
+// transactions refer to them.
+func (s *unitService) UpdateUnit(code string, req *UpdateUnitRequest, userID string) (*model.UnitOfMeasure, error) {
+	if errs := validator.ValidateStruct(req); len(errs) > 0 {
+		firstErr := errs[0]
+		return nil, fmt.Errorf("Validation failed: Field '%s' failed on tag '%s'", firstErr.FailedField, firstErr.Tag)
+	}
+
+	unit, err := s.unitRepo.FindByCode(code)
+	if err != nil {
+		return nil, ErrUnitNotFound
+	}
+	unit.Name = req.Name
+	if req.IsActive != nil {
+		unit.IsActive = *req.IsActive
+	}
+	unit.UpdatedBy = userID
+	if err := s.unitRepo.Update(unit); err != nil {
+		return nil, err
+	}
+	return unit, nil
+}
+
+// GetProductUnits returns the product (with its base unit) and its alternative units
+func (s *unitService) GetProductUnits(productID uuid.UUID) (*model.Product, []model.ProductUnit, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, nil, ErrProductNotFound
+	}
+	units, err := s.unitRepo.FindProductUnits(productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return product, units, nil
+}
+
+// SetProductUnit adds or changes an alternative unit of a product. The base unit is
+// the smallest one, so a factor is at least 2.
+func (s *unitService) SetProductUnit(productID uuid.UUID, code string, factor int, userID string) (*model.ProductUnit, error) {
+	if factor < 2 {
+		return nil, errors.New("factor must be at least 2 (base units in one of this unit)")
+	}
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	if product.Unit == "" {
+		return nil, errors.New("set the product's base unit first")
+	}
+	code, err = s.CatalogUnit(code)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(code, product.Unit) {
+		return nil, errors.New("the base unit needs no conversion")
+	}
+
+	unit := &model.ProductUnit{
+		ProductID: productID,
+		UnitCode:  code,
+		Factor:    factor,
+		UpdatedBy: userID,
+	}
+	if err := s.unitRepo.SaveProductUnit(unit); err != nil {
+		return nil, err
+	}
+	return unit, nil
+}
+
+func (s *unitService) RemoveProductUnit(productID uuid.UUID, code string) error {
+	removed, err := s.unitRepo.DeleteProductUnit(productID, code)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrUnitNotConfigured
+	}
+	return nil
+}
+
+func (s *unitService) CatalogUnit(code string) (string, error) {
+	unit, err := s.unitRepo.FindByCode(strings.TrimSpace(code))
+	if err != nil {
+		return "", ErrUnitNotFound
+	}
+	if !unit.IsActive {
+		return "", fmt.Errorf("unit %s is inactive", unit.Code)
+	}
+	return unit.Code, nil
+}
+
+func (s *unitService) ResolveUnit(product *model.Product, unit string) (string, int, error) {
+	unit = strings.TrimSpace(unit)
+	if unit == "" || strings.EqualFold(unit, product.Unit) {
+		return product.Unit, 1, nil
+	}
+	productUnit, err := s.unitRepo.FindProductUnit(product.ID, unit)
+	if err != nil {
+		return "", 0, fmt.Errorf("unit %s is not configured for product '%s'", unit, product.Name)
+	}
+	return productUnit.UnitCode, productUnit.Factor, nil
+}
@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"log"
+	"time"
+)
+
+// Backplane relays hub traffic between instances running behind a load balancer,
+// so broadcasts and targeted messages reach sockets connected to any replica
+type Backplane interface {
+	// Publish sends an envelope to every instance (including, possibly, this one)
+	Publish(env *Envelope) error
+
+	// Listen hands envelopes from the backplane to handle. It blocks and only
+	// returns when the underlying connection fails.
+	Listen(handle func(*Envelope)) error
+}
+
+// Envelope is the unit exchanged over the backplane
+type Envelope struct {
+	Origin string `json:"origin"` // Instance that produced it, own envelopes are skipped
+
+	// Exactly one of the following is set
+	Message            *Message       `json:"message,omitempty"`
+	CloseStaleSessions *StaleSessions `json:"close_stale_sessions,omitempty"`
+}
+
+// StaleSessions asks every instance to close a user's sockets from older sessions
+type StaleSessions struct {
+	UserID              string `json:"user_id"`
+	CurrentTokenVersion string `json:"current_token_version"`
+}
+
+// UseBackplane connects the hub to other instances. Messages published locally are
+// forwarded, and messages from other instances are delivered to local clients.
+func (h *Hub) UseBackplane(backplane Backplane) {
+	h.backplane = backplane
+	go h.listenBackplane()
+}
+
+// listenBackplane keeps the backplane subscription alive, reconnecting on failure
+func (h *Hub) listenBackplane() {
+	for {
+		err := h.backplane.Listen(h.handleEnvelope)
+		log.Printf("WS backplane disconnected: %v (reconnecting in 5s)", err)
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func (h *Hub) handleEnvelope(env *Envelope) {
+	if env.Origin == h.instanceID {
+		return
+	}
+
+	switch {
+	case env.Message != nil:
+		// Already sequenced and persisted by the origin instance
+		h.Broadcast <- env.Message
+	case env.CloseStaleSessions != nil:
+		h.closeStaleSessionsLocal(env.CloseStaleSessions.UserID, env.CloseStaleSessions.CurrentTokenVersion)
+	}
+}
+
+// forward publishes an envelope on the backplane, if one is configured
+func (h *Hub) forward(env *Envelope) {
+	if h.backplane == nil {
+		return
+	}
+	env.Origin = h.instanceID
+	if err := h.backplane.Publish(env); err != nil {
+		log.Printf("WS backplane publish failed: %v", err)
+	}
+}
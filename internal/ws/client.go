@@ -0,0 +1,295 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+const (
+	// Time allowed to write a message to the peer
+	writeWait = 10 * time.Second
+
+	// Time allowed to read the next pong message from the peer
+	pongWait = 60 * time.Second
+
+	// Send pings with this period. Must be less than pongWait
+	pingPeriod = (pongWait * 9) / 10
+
+	// Maximum message size allowed from the peer
+	maxMessageSize = 4096
+
+	// Outbound messages buffered per client before it is considered a slow consumer
+	sendBufferSize = 256
+)
+
+// Client wraps a single WebSocket connection with its own bounded send queue.
+// Only the client's writePump goroutine ever writes to the connection,
+// so a slow client can never block the hub.
+type Client struct {
+	hub  *Hub
+	conn *websocket.Conn
+
+	UserID       string
+	TokenVersion string // Session the connection authenticated with (see Hub.CloseStaleSessions)
+
+	// Privilege codes from the JWT claims, snapshotted at connect time
+	privileges map[string]bool
+
+	// Buffered channel of outbound messages, closed by the hub on unregister
+	send chan []byte
+
+	// Close frame sent when the hub drops the client (set before send is closed)
+	closeCode int
+	closeText string
+
+	// Topic subscriptions. A client that never subscribed (filtering == false)
+	// keeps receiving every event, as before topics existed.
+	subMutex      sync.RWMutex
+	filtering     bool
+	subscriptions map[string]bool
+
+	// Last sequence seen before reconnecting, replayed right after registration
+	resumeFrom *int64
+}
+
+// clientFrame is a control message sent by the client, e.g.
+// {"action":"subscribe","topics":["products","product:<id>"]}
+// {"action":"resume","last_seq":1234}
+type clientFrame struct {
+	Action  string   `json:"action"`
+	Topics  []string `json:"topics"`
+	LastSeq *int64   `json:"last_seq"`
+}
+
+func NewClient(hub *Hub, conn *websocket.Conn, userID, tokenVersion string, privileges []string) *Client {
+	privilegeSet := make(map[string]bool, len(privileges))
+	for _, p := range privileges {
+		privilegeSet[p] = true
+	}
+
+	return &Client{
+		hub:           hub,
+		conn:          conn,
+		UserID:        userID,
+		TokenVersion:  tokenVersion,
+		privileges:    privilegeSet,
+		send:          make(chan []byte, sendBufferSize),
+		closeCode:     websocket.CloseNormalClosure,
+		subscriptions: make(map[string]bool),
+	}
+}
+
+// HasPrivilege checks the client's privilege codes. An empty code is always allowed.
+func (c *Client) HasPrivilege(code string) bool {
+	return code == "" || c.privileges[code]
+}
+
+// Subscribe adds topics to the client's subscriptions and switches it to filtered
+// delivery once it holds at least one valid topic
+func (c *Client) Subscribe(topics ...string) {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+	for _, t := range topics {
+		if IsValidTopic(t) {
+			c.subscriptions[t] = true
+		}
+	}
+	if len(c.subscriptions) > 0 {
+		c.filtering = true
+	}
+}
+
+// Unsubscribe removes topics from the client's subscriptions. It never switches
+// delivery mode: a client that never subscribed keeps receiving every event.
+func (c *Client) Unsubscribe(topics ...string) {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+	for _, t := range topics {
+		delete(c.subscriptions, t)
+	}
+}
+
+// Topics returns the current subscriptions (sorted)
+func (c *Client) Topics() []string {
+	c.subMutex.RLock()
+	defer c.subMutex.RUnlock()
+	topics := make([]string, 0, len(c.subscriptions))
+	for t := range c.subscriptions {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// accepts reports whether a hub message may be delivered to this client
+// (recipient, privilege and topic checks)
+func (c *Client) accepts(msg *Message) bool {
+	if len(msg.UserIDs) > 0 {
+		found := false
+		for _, id := range msg.UserIDs {
+			if id == c.UserID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return c.HasPrivilege(msg.RequiredPrivilege) && c.wants(msg.Topics)
+}
+
+// wants reports whether an event published on the given topics should reach this client.
+// Untagged events (no topics) go to everyone.
+func (c *Client) wants(topics []string) bool {
+	if len(topics) == 0 {
+		return true
+	}
+	c.subMutex.RLock()
+	defer c.subMutex.RUnlock()
+	if !c.filtering {
+		return true
+	}
+	for _, t := range topics {
+		if c.subscriptions[t] {
+			return true
+		}
+	}
+	return false
+}
+
+// ResumeFrom asks for every event after lastSeq to be replayed once the client is registered
+func (c *Client) ResumeFrom(lastSeq int64) {
+	c.resumeFrom = &lastSeq
+}
+
+// Serve registers the client and pumps messages until the connection closes.
+// It blocks, as the fiber websocket handler must not return while the conn is in use.
+func (c *Client) Serve() {
+	c.hub.register(c)
+	go c.writePump()
+	if c.resumeFrom != nil {
+		c.hub.Replay(c, *c.resumeFrom)
+	}
+	c.readPump()
+}
+
+// enqueue adds a message to the send queue without blocking.
+// Returns false when the buffer is full (slow consumer).
+func (c *Client) enqueue(message []byte) bool {
+	select {
+	case c.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// readPump keeps the read deadline alive via pong frames and detects dead peers
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.Unregister <- c
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("WS read error for user %s: %v", c.UserID, err)
+			}
+			break
+		}
+		c.handleFrame(data)
+	}
+}
+
+// handleFrame processes subscribe/unsubscribe requests and acknowledges
+// with the resulting subscription list
+func (c *Client) handleFrame(data []byte) {
+	var frame clientFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		c.reply(map[string]interface{}{"type": "error", "message": "invalid JSON frame"})
+		return
+	}
+
+	var invalid []string
+	for _, t := range frame.Topics {
+		if !IsValidTopic(t) {
+			invalid = append(invalid, t)
+		}
+	}
+
+	switch frame.Action {
+	case "resume":
+		if frame.LastSeq == nil {
+			c.reply(map[string]interface{}{"type": "error", "message": "last_seq is required"})
+			return
+		}
+		c.hub.Replay(c, *frame.LastSeq)
+		return
+	case "subscribe":
+		c.Subscribe(frame.Topics...)
+	case "unsubscribe":
+		c.Unsubscribe(frame.Topics...)
+	default:
+		c.reply(map[string]interface{}{"type": "error", "message": "unknown action: " + frame.Action})
+		return
+	}
+
+	ack := map[string]interface{}{
+		"type":   "subscriptions",
+		"topics": c.Topics(),
+	}
+	if len(invalid) > 0 {
+		ack["invalid_topics"] = invalid
+	}
+	c.reply(ack)
+}
+
+// reply sends a control response to this client only
+func (c *Client) reply(payload map[string]interface{}) {
+	msg, _ := json.Marshal(payload)
+	c.hub.SendToClient(c, msg)
+}
+
+// writePump drains the send queue and sends periodic pings
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The hub closed the channel
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeText))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+)
+
+// Maximum number of events replayed to a reconnecting client. Kept well below
+// sendBufferSize so a replay can never get the client evicted as a slow consumer.
+const replayLimit = sendBufferSize / 2
+
+// EventLog persists hub events so reconnecting clients can catch up
+// (implemented by repository.HubEventRepository)
+type EventLog interface {
+	Append(event *model.HubEvent) error
+	FindAfter(seq int64, limit int) ([]model.HubEvent, error)
+	OldestSeq() (int64, error)
+	DeleteOlderThan(cutoff time.Time) (int64, error)
+}
+
+// sequence assigns the next sequence number to a message, persisting it when
+// an event log is configured (a single INSERT … RETURNING seq), and sets the
+// envelope's seq in the payload. A message the log can't persist is not
+// delivered (clients could not resume from it) and the error is returned.
+// Must be called with h.seqMutex held.
+func (h *Hub) sequence(msg *Message) error {
+	if h.eventLog == nil {
+		h.lastSeq++
+		msg.Seq = h.lastSeq
+	} else {
+		event := toHubEvent(msg)
+		if err := h.eventLog.Append(event); err != nil {
+			return fmt.Errorf("event log append failed: %w", err)
+		}
+		msg.Seq = event.Seq
+		h.lastSeq = event.Seq
+	}
+	msg.Payload = payloadWithSeq(msg)
+	return nil
+}
+
+// Replay sends a reconnecting client every event after lastSeq it is allowed to see,
+// or a resync_required message when the gap can't be filled from the log.
+// The client is already registered, so live events may interleave with replayed
+// ones; clients should de-duplicate by seq.
+func (h *Hub) Replay(client *Client, lastSeq int64) {
+	if h.eventLog == nil {
+		h.sendResync(client, "event log disabled")
+		return
+	}
+
+	oldest, err := h.eventLog.OldestSeq()
+	if err != nil {
+		h.sendResync(client, "event log unavailable")
+		return
+	}
+	if oldest > lastSeq+1 {
+		h.sendResync(client, "events since last_seq are no longer retained")
+		return
+	}
+
+	events, err := h.eventLog.FindAfter(lastSeq, replayLimit+1)
+	if err != nil {
+		h.sendResync(client, "event log unavailable")
+		return
+	}
+	if len(events) > replayLimit {
+		h.sendResync(client, "too many missed events")
+		return
+	}
+
+	count := 0
+	toSeq := lastSeq
+	for _, event := range events {
+		msg := fromHubEvent(event)
+		toSeq = msg.Seq
+		if !client.accepts(msg) {
+			continue
+		}
+		h.SendToClient(client, payloadWithSeq(msg))
+		count++
+	}
+
+	h.sendControl(client, map[string]interface{}{
+		"type":     "replay_complete",
+		"from_seq": lastSeq,
+		"to_seq":   toSeq,
+		"count":    count,
+	})
+}
+
+// RunRetention periodically prunes events older than the retention period
+func (h *Hub) RunRetention(retention time.Duration) {
+	if h.eventLog == nil {
+		return
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := h.eventLog.DeleteOlderThan(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("WS event log retention failed: %v", err)
+		} else if deleted > 0 {
+			log.Printf("WS event log pruned %d events", deleted)
+		}
+		<-ticker.C
+	}
+}
+
+func (h *Hub) sendResync(client *Client, reason string) {
+	h.sendControl(client, map[string]interface{}{
+		"type":   "resync_required",
+		"reason": reason,
+	})
+}
+
+func (h *Hub) sendControl(client *Client, payload map[string]interface{}) {
+	msg, _ := json.Marshal(payload)
+	h.SendToClient(client, msg)
+}
+
+// payloadWithSeq returns the message's envelope with Seq set. The log stores
+// payloads without it, their seq is only known once inserted.
+func payloadWithSeq(msg *Message) []byte {
+	payload, err := events.WithSeq(msg.Payload, msg.Seq)
+	if err != nil {
+		log.Printf("WS failed to set seq %d on payload: %v", msg.Seq, err)
+		return msg.Payload
+	}
+	return payload
+}
+
+func toHubEvent(msg *Message) *model.HubEvent {
+	return &model.HubEvent{
+		Topics:            strings.Join(msg.Topics, ","),
+		RequiredPrivilege: msg.RequiredPrivilege,
+		UserIDs:           strings.Join(msg.UserIDs, ","),
+		Payload:           string(msg.Payload),
+	}
+}
+
+func fromHubEvent(event model.HubEvent) *Message {
+	return &Message{
+		Seq:               event.Seq,
+		Topics:            SplitList(event.Topics),
+		RequiredPrivilege: event.RequiredPrivilege,
+		UserIDs:           SplitList(event.UserIDs),
+		Payload:           []byte(event.Payload),
+	}
+}
+
+// SplitList splits a comma separated column (topics, user IDs) as stored in the
+// event log and the outbox (empty string = no values)
+func SplitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,282 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-inventory-ws/internal/events"
+	"go-inventory-ws/internal/model"
+)
+
+// memoryEventLog is an in-memory EventLog. Append fails with failWith while it is set.
+type memoryEventLog struct {
+	mu       sync.Mutex
+	events   []model.HubEvent
+	nextSeq  int64
+	failWith error
+}
+
+func (l *memoryEventLog) Append(event *model.HubEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.failWith != nil {
+		return l.failWith
+	}
+	l.nextSeq++
+	event.Seq = l.nextSeq
+	event.CreatedAt = time.Now()
+	l.events = append(l.events, *event)
+	return nil
+}
+
+func (l *memoryEventLog) FindAfter(seq int64, limit int) ([]model.HubEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var found []model.HubEvent
+	for _, event := range l.events {
+		if event.Seq > seq && len(found) < limit {
+			found = append(found, event)
+		}
+	}
+	return found, nil
+}
+
+func (l *memoryEventLog) OldestSeq() (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.events) == 0 {
+		return l.nextSeq + 1, nil
+	}
+	return l.events[0].Seq, nil
+}
+
+func (l *memoryEventLog) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	kept := l.events[:0]
+	for _, event := range l.events {
+		if !event.CreatedAt.Before(cutoff) {
+			kept = append(kept, event)
+		}
+	}
+	deleted := int64(len(l.events) - len(kept))
+	l.events = kept
+	return deleted, nil
+}
+
+func (l *memoryEventLog) setFailure(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failWith = err
+}
+
+// received is the part of a payload the tests look at, envelopes and control messages alike
+type received struct {
+	ID     string `json:"id"`
+	Seq    int64  `json:"seq"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+	ToSeq  int64  `json:"to_seq"`
+	Count  int    `json:"count"`
+}
+
+func newTestClient(hub *Hub, userID string, privileges ...string) *Client {
+	client := NewClient(hub, nil, userID, "v1", privileges)
+	hub.register(client)
+	return client
+}
+
+func testEnvelope(t *testing.T, name string) (*events.Envelope, []byte) {
+	t.Helper()
+	env := events.New(events.ProductDeleted{Message: name}, nil)
+	payload, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env, payload
+}
+
+func receive(t *testing.T, client *Client) received {
+	t.Helper()
+	select {
+	case raw, ok := <-client.send:
+		if !ok {
+			t.Fatal("client send channel closed")
+		}
+		var msg received
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.Fatalf("decode %s: %v", raw, err)
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message delivered")
+	}
+	return received{}
+}
+
+func expectNothing(t *testing.T, client *Client) {
+	t.Helper()
+	select {
+	case raw := <-client.send:
+		t.Fatalf("unexpected message %s", raw)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSendSequencesInOrder(t *testing.T) {
+	eventLog := &memoryEventLog{}
+	hub := NewHub(eventLog)
+	go hub.Run()
+	client := newTestClient(hub, "u1")
+
+	var ids []string
+	for _, name := range []string{"a", "b", "c"} {
+		env, payload := testEnvelope(t, name)
+		ids = append(ids, env.ID.String())
+		if err := hub.Send(&Message{Topics: []string{TopicProducts}, Payload: payload}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	for i, id := range ids {
+		msg := receive(t, client)
+		if msg.ID != id || msg.Seq != int64(i+1) {
+			t.Errorf("message %d = id %s seq %d, want id %s seq %d", i, msg.ID, msg.Seq, id, i+1)
+		}
+	}
+
+	// The log keeps the payload without seq, it is set again on replay
+	for _, event := range eventLog.events {
+		var stored received
+		if err := json.Unmarshal([]byte(event.Payload), &stored); err != nil {
+			t.Fatal(err)
+		}
+		if stored.Seq != 0 {
+			t.Errorf("stored event %d has seq %d in its payload", event.Seq, stored.Seq)
+		}
+	}
+}
+
+func TestSendReturnsAppendError(t *testing.T) {
+	eventLog := &memoryEventLog{}
+	hub := NewHub(eventLog)
+	go hub.Run()
+	client := newTestClient(hub, "u1")
+
+	appendErr := errors.New("connection refused")
+	eventLog.setFailure(appendErr)
+	_, payload := testEnvelope(t, "lost")
+	if err := hub.Send(&Message{Payload: payload}); !errors.Is(err, appendErr) {
+		t.Fatalf("Send error = %v, want %v", err, appendErr)
+	}
+	expectNothing(t, client)
+
+	// Retrying once the log is back delivers it with the next seq
+	eventLog.setFailure(nil)
+	env, payload := testEnvelope(t, "retried")
+	if err := hub.Send(&Message{Payload: payload}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	msg := receive(t, client)
+	if msg.ID != env.ID.String() || msg.Seq != 1 {
+		t.Errorf("got id %s seq %d, want id %s seq 1", msg.ID, msg.Seq, env.ID)
+	}
+}
+
+// appendEvents stores messages in the log as the hub would have, without delivering them
+func appendEvents(t *testing.T, eventLog *memoryEventLog, messages ...*Message) []string {
+	t.Helper()
+	var ids []string
+	for _, msg := range messages {
+		env, payload := testEnvelope(t, "replayed")
+		ids = append(ids, env.ID.String())
+		msg.Payload = payload
+		if err := eventLog.Append(toHubEvent(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
+func TestReplay(t *testing.T) {
+	eventLog := &memoryEventLog{}
+	hub := NewHub(eventLog)
+
+	// 1: products, 2: finance (needs transaction:view), 3: another user, 4: transfers, 5: products
+	ids := appendEvents(t, eventLog,
+		&Message{Topics: []string{TopicProducts}},
+		&Message{Topics: []string{TopicFinance}, RequiredPrivilege: "transaction:view"},
+		&Message{UserIDs: []string{"u2"}},
+		&Message{Topics: []string{TopicTransfers}},
+		&Message{Topics: []string{TopicProducts}},
+	)
+
+	client := newTestClient(hub, "u1")
+	client.Subscribe(TopicProducts, TopicFinance)
+	hub.Replay(client, 0)
+
+	for _, want := range []int{0, 4} {
+		msg := receive(t, client)
+		if msg.ID != ids[want] || msg.Seq != int64(want+1) {
+			t.Errorf("replayed id %s seq %d, want id %s seq %d", msg.ID, msg.Seq, ids[want], want+1)
+		}
+	}
+	done := receive(t, client)
+	if done.Type != "replay_complete" || done.ToSeq != 5 || done.Count != 2 {
+		t.Errorf("got %+v, want replay_complete to_seq 5 count 2", done)
+	}
+
+	hub.Replay(client, 5)
+	if done := receive(t, client); done.Type != "replay_complete" || done.Count != 0 {
+		t.Errorf("got %+v, want an empty replay_complete", done)
+	}
+}
+
+func TestReplayResync(t *testing.T) {
+	t.Run("gap no longer retained", func(t *testing.T) {
+		eventLog := &memoryEventLog{}
+		hub := NewHub(eventLog)
+		ids := appendEvents(t, eventLog, &Message{}, &Message{}, &Message{})
+		eventLog.events = eventLog.events[2:] // 1 and 2 pruned by retention
+
+		client := newTestClient(hub, "u1")
+		hub.Replay(client, 1)
+		if msg := receive(t, client); msg.Type != "resync_required" {
+			t.Errorf("got %+v, want resync_required", msg)
+		}
+		hub.Replay(client, 2)
+		if msg := receive(t, client); msg.ID != ids[2] || msg.Seq != 3 {
+			t.Errorf("got %+v, want event 3", msg)
+		}
+		if msg := receive(t, client); msg.Type != "replay_complete" || msg.Count != 1 {
+			t.Errorf("got %+v, want replay_complete count 1", msg)
+		}
+	})
+
+	t.Run("too many missed events", func(t *testing.T) {
+		eventLog := &memoryEventLog{}
+		hub := NewHub(eventLog)
+		for i := 0; i <= replayLimit; i++ {
+			appendEvents(t, eventLog, &Message{})
+		}
+
+		client := newTestClient(hub, "u1")
+		hub.Replay(client, 0)
+		if msg := receive(t, client); msg.Type != "resync_required" {
+			t.Errorf("got %+v, want resync_required", msg)
+		}
+		expectNothing(t, client)
+	})
+
+	t.Run("no event log", func(t *testing.T) {
+		hub := NewHub(nil)
+		client := newTestClient(hub, "u1")
+		hub.Replay(client, 0)
+		if msg := receive(t, client); msg.Type != "resync_required" {
+			t.Errorf("got %+v, want resync_required", msg)
+		}
+	})
+}
@@ -1,101 +1,102 @@
 package ws
 
 import (
+	"encoding/json"
 	"log"
 	"sync"
 
+	"go-inventory-ws/internal/events"
+
 	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
 )
 
-// TargetedMessage represents a message to be sent to specific users
-type TargetedMessage struct {
-	UserIDs []string
-	Message []byte
+// Message is an event published to every client subscribed to one of its topics.
+// When RequiredPrivilege is set, only clients holding that privilege receive it;
+// when UserIDs is set, only those users' connections receive it.
+type Message struct {
+	Seq               int64           `json:"seq"` // Hub-wide sequence number, also set as the Payload envelope's seq
+	Topics            []string        `json:"topics,omitempty"`
+	RequiredPrivilege string          `json:"required_privilege,omitempty"`
+	UserIDs           []string        `json:"user_ids,omitempty"`
+	Payload           json.RawMessage `json:"payload"`
 }
 
+// Sequenced messages queued for delivery before dispatch blocks (backpressure)
+const outgoingBufferSize = 1024
+
 type Hub struct {
 	// All clients (for broadcast to all)
-	Clients map[*websocket.Conn]bool
+	Clients map[*Client]bool
 
-	// Map user ID to their connections for targeted messaging
+	// Map user ID to their clients for targeted messaging
 	// A user might have multiple connections (multiple tabs/devices)
-	UserClients map[string][]*websocket.Conn
+	UserClients map[string]map[*Client]bool
 
-	// Map connection to user ID for cleanup
-	ConnToUser map[*websocket.Conn]string
+	Unregister chan *Client
 
-	Register   chan *websocket.Conn
-	Unregister chan *websocket.Conn
-	Broadcast  chan []byte
+	// Sequenced broadcast and targeted messages
+	Broadcast chan *Message
 
-	// Targeted message channel
-	TargetedSend chan *TargetedMessage
+	// Sequenced messages waiting for fanOut, in sequence order
+	outgoing chan *Message
 
+	// Guards the client maps for readers outside Run (IsUserOnline etc.)
 	mutex sync.Mutex
+
+	// Persisted event log for replay on reconnect (nil = in-memory sequence only).
+	// seqMutex only covers numbering and queueing on outgoing, which keeps
+	// sequence order and delivery order identical.
+	eventLog EventLog
+	seqMutex sync.Mutex
+	lastSeq  int64
+
+	// Optional fan-out to other instances (see UseBackplane)
+	instanceID string
+	backplane  Backplane
+
+	// Called when a user's first connection opens or last one closes (see OnUserConnectionChange)
+	connectionHook func(userID string, online bool)
 }
 
-func NewHub() *Hub {
+func NewHub(eventLog EventLog) *Hub {
 	return &Hub{
-		Clients:      make(map[*websocket.Conn]bool),
-		UserClients:  make(map[string][]*websocket.Conn),
-		ConnToUser:   make(map[*websocket.Conn]string),
-		Register:     make(chan *websocket.Conn),
-		Unregister:   make(chan *websocket.Conn),
-		Broadcast:    make(chan []byte),
-		TargetedSend: make(chan *TargetedMessage),
+		Clients:     make(map[*Client]bool),
+		UserClients: make(map[string]map[*Client]bool),
+		Unregister:  make(chan *Client),
+		Broadcast:   make(chan *Message),
+		outgoing:    make(chan *Message, outgoingBufferSize),
+		eventLog:    eventLog,
+		instanceID:  uuid.New().String(),
 	}
 }
 
+// Run processes unregistrations and message delivery. It never writes to a socket
+// itself: messages are queued on each client's buffered send channel and written by its writePump.
 func (h *Hub) Run() {
+	go h.fanOut()
+
 	for {
 		select {
-		case conn := <-h.Register:
+		case client := <-h.Unregister:
 			h.mutex.Lock()
-			h.Clients[conn] = true
-			h.mutex.Unlock()
-			log.Println("New WS Client Connected (anonymous)")
-
-		case conn := <-h.Unregister:
-			h.mutex.Lock()
-			if _, ok := h.Clients[conn]; ok {
-				delete(h.Clients, conn)
-
-				// Also remove from UserClients if registered
-				if userID, exists := h.ConnToUser[conn]; exists {
-					h.removeUserConnection(userID, conn)
-					delete(h.ConnToUser, conn)
-				}
-
-				conn.Close()
-			}
+			h.removeClient(client)
 			h.mutex.Unlock()
 
 		case message := <-h.Broadcast:
 			h.mutex.Lock()
-			for conn := range h.Clients {
-				if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
-					conn.Close()
-					delete(h.Clients, conn)
-					// Cleanup user mapping
-					if userID, exists := h.ConnToUser[conn]; exists {
-						h.removeUserConnection(userID, conn)
-						delete(h.ConnToUser, conn)
+			if len(message.UserIDs) > 0 {
+				for _, userID := range message.UserIDs {
+					for client := range h.UserClients[userID] {
+						if client.accepts(message) {
+							h.deliver(client, message.Payload)
+						}
 					}
 				}
-			}
-			h.mutex.Unlock()
-
-		case targeted := <-h.TargetedSend:
-			h.mutex.Lock()
-			for _, userID := range targeted.UserIDs {
-				if conns, exists := h.UserClients[userID]; exists {
-					for _, conn := range conns {
-						if err := conn.WriteMessage(websocket.TextMessage, targeted.Message); err != nil {
-							conn.Close()
-							delete(h.Clients, conn)
-							h.removeUserConnection(userID, conn)
-							delete(h.ConnToUser, conn)
-						}
+			} else {
+				for client := range h.Clients {
+					if client.accepts(message) {
+						h.deliver(client, message.Payload)
 					}
 				}
 			}
@@ -104,56 +105,194 @@ func (h *Hub) Run() {
 	}
 }
 
-// RegisterWithUser registers a connection with a specific user ID for targeted messaging
-func (h *Hub) RegisterWithUser(conn *websocket.Conn, userID string) {
+// register adds a client synchronously, so it is guaranteed to receive
+// anything sent after this returns (e.g. a replay)
+func (h *Hub) register(client *Client) {
 	h.mutex.Lock()
-	defer h.mutex.Unlock()
+	h.Clients[client] = true
+	if h.UserClients[client.UserID] == nil {
+		h.UserClients[client.UserID] = make(map[*Client]bool)
+	}
+	h.UserClients[client.UserID][client] = true
+	count := len(h.UserClients[client.UserID])
+	h.mutex.Unlock()
+	log.Printf("WS Client Connected for user: %s (total connections: %d)", client.UserID, count)
 
-	// Add to general clients
-	h.Clients[conn] = true
+	if count == 1 && h.connectionHook != nil {
+		go h.connectionHook(client.UserID, true)
+	}
+}
+
+// deliver queues a message for a client, evicting it if its buffer is full.
+// Must be called with h.mutex held.
+func (h *Hub) deliver(client *Client, message []byte) {
+	if client.enqueue(message) {
+		return
+	}
+	log.Printf("WS slow consumer evicted for user: %s", client.UserID)
+	client.closeCode = websocket.ClosePolicyViolation
+	client.closeText = "slow consumer"
+	h.removeClient(client)
+}
 
-	// Add to user-specific connections
-	h.UserClients[userID] = append(h.UserClients[userID], conn)
-	h.ConnToUser[conn] = userID
+// removeClient drops a client from all maps and closes its send channel.
+// Must be called with h.mutex held; safe to call more than once.
+func (h *Hub) removeClient(client *Client) {
+	if _, ok := h.Clients[client]; !ok {
+		return
+	}
+	delete(h.Clients, client)
+
+	if conns, exists := h.UserClients[client.UserID]; exists {
+		delete(conns, client)
+		// If no more connections for this user, remove the user entry
+		if len(conns) == 0 {
+			delete(h.UserClients, client.UserID)
+			if h.connectionHook != nil {
+				go h.connectionHook(client.UserID, false)
+			}
+		}
+	}
+
+	close(client.send)
+}
 
-	log.Printf("WS Client Connected for user: %s (total connections: %d)", userID, len(h.UserClients[userID]))
+// Publish sends an event to every client subscribed to at least one of the topics
+// (or to all clients when no topic is given)
+func (h *Hub) Publish(event *events.Envelope, topics ...string) {
+	h.PublishWithPrivilege("", event, topics...)
 }
 
-// SendToUsers sends a message only to specific users
-func (h *Hub) SendToUsers(userIDs []string, message []byte) {
-	h.TargetedSend <- &TargetedMessage{
+// PublishWithPrivilege is Publish restricted to clients holding the given privilege
+// (e.g. "transaction:view" for financial data)
+func (h *Hub) PublishWithPrivilege(privilege string, event *events.Envelope, topics ...string) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("WS failed to encode %s event: %v", event.Type, err)
+		return
+	}
+	err = h.dispatch(&Message{
+		Topics:            topics,
+		RequiredPrivilege: privilege,
+		Payload:           payload,
+	})
+	if err != nil {
+		log.Printf("WS failed to publish %s event %s: %v", event.Type, event.ID, err)
+	}
+}
+
+// SendToUsers sends an event only to specific users,
+// optionally restricted to their connections subscribed to one of the topics
+func (h *Hub) SendToUsers(userIDs []string, event *events.Envelope, topics ...string) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("WS failed to encode %s event: %v", event.Type, err)
+		return
+	}
+	err = h.dispatch(&Message{
+		Topics:  topics,
 		UserIDs: userIDs,
-		Message: message,
+		Payload: payload,
+	})
+	if err != nil {
+		log.Printf("WS failed to send %s event %s: %v", event.Type, event.ID, err)
 	}
 }
 
-// removeUserConnection removes a specific connection from a user's connection list
-func (h *Hub) removeUserConnection(userID string, conn *websocket.Conn) {
-	if conns, exists := h.UserClients[userID]; exists {
-		for i, c := range conns {
-			if c == conn {
-				// Remove this connection from the slice
-				h.UserClients[userID] = append(conns[:i], conns[i+1:]...)
-				break
-			}
-		}
-		// If no more connections for this user, remove the user entry
-		if len(h.UserClients[userID]) == 0 {
-			delete(h.UserClients, userID)
+// Send publishes a fully routed message (e.g. relayed from the outbox). Once it
+// returns nil the message is in the event log, so clients get it live or on replay;
+// on error nothing was delivered and the caller may retry.
+func (h *Hub) Send(msg *Message) error {
+	return h.dispatch(msg)
+}
+
+// dispatch sequences (and persists) a message and queues it for fanOut. Only the
+// numbering and the queueing happen under seqMutex, delivery and NOTIFY don't.
+func (h *Hub) dispatch(msg *Message) error {
+	h.seqMutex.Lock()
+	defer h.seqMutex.Unlock()
+	if err := h.sequence(msg); err != nil {
+		return err
+	}
+	h.outgoing <- msg
+	return nil
+}
+
+// fanOut hands sequenced messages to Run and forwards them to the other instances,
+// one at a time so they go out in sequence order
+func (h *Hub) fanOut() {
+	for msg := range h.outgoing {
+		h.Broadcast <- msg
+		h.forward(&Envelope{Message: msg})
+	}
+}
+
+// SendToClient queues a message for a single connection (e.g. a control reply)
+func (h *Hub) SendToClient(client *Client, message []byte) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.Clients[client] {
+		h.deliver(client, message)
+	}
+}
+
+// CloseStaleSessions closes every connection of a user that was opened with a
+// TokenVersion other than the current one (e.g. after a login on another device),
+// on this instance and, through the backplane, on every other one.
+func (h *Hub) CloseStaleSessions(userID, currentTokenVersion string) {
+	h.closeStaleSessionsLocal(userID, currentTokenVersion)
+	h.forward(&Envelope{CloseStaleSessions: &StaleSessions{
+		UserID:              userID,
+		CurrentTokenVersion: currentTokenVersion,
+	}})
+}
+
+func (h *Hub) closeStaleSessionsLocal(userID, currentTokenVersion string) {
+	h.mutex.Lock()
+	var stale []*Client
+	for client := range h.UserClients[userID] {
+		if client.TokenVersion != currentTokenVersion {
+			client.closeCode = websocket.ClosePolicyViolation
+			client.closeText = "session expired (logged in on another device)"
+			stale = append(stale, client)
 		}
 	}
+	h.mutex.Unlock()
+
+	// Unregister outside the lock, Run needs it to process the request
+	for _, client := range stale {
+		h.Unregister <- client
+		log.Printf("WS session closed for user: %s (token version changed)", userID)
+	}
+}
+
+// OnUserConnectionChange registers a hook called (in its own goroutine) when a user's
+// first connection opens or their last connection closes. Set it before the server starts accepting connections.
+func (h *Hub) OnUserConnectionChange(hook func(userID string, online bool)) {
+	h.connectionHook = hook
 }
 
-// GetUserConnectionCount returns the number of active connections for a user
+// GetUserConnectionCount returns the number of active connections for a user (on this instance)
 func (h *Hub) GetUserConnectionCount(userID string) int {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 	return len(h.UserClients[userID])
 }
 
-// IsUserOnline checks if a user has any active WebSocket connections
+// IsUserOnline checks if a user has any active WebSocket connections (on this instance)
 func (h *Hub) IsUserOnline(userID string) bool {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 	return len(h.UserClients[userID]) > 0
 }
+
+// ConnectedUserIDs lists the users with at least one connection (on this instance)
+func (h *Hub) ConnectedUserIDs() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	ids := make([]string, 0, len(h.UserClients))
+	for userID := range h.UserClients {
+		ids = append(ids, userID)
+	}
+	return ids
+}
@@ -0,0 +1,57 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+func TestSlowConsumerEvicted(t *testing.T) {
+	hub := NewHub(nil)
+	go hub.Run()
+	slow := newTestClient(hub, "slow")
+	fast := newTestClient(hub, "fast")
+
+	total := sendBufferSize + 1
+	fastReceived := make(chan int)
+	go func() {
+		count := 0
+		for count < total {
+			<-fast.send
+			count++
+		}
+		fastReceived <- count
+	}()
+
+	for i := 0; i < total; i++ {
+		_, payload := testEnvelope(t, "burst")
+		if err := hub.Send(&Message{Payload: payload}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case count := <-fastReceived:
+		if count != total {
+			t.Errorf("fast client got %d messages, want %d", count, total)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fast client was held up by the slow one")
+	}
+
+	// The slow client keeps what was buffered, then its queue is closed
+	buffered := 0
+	for range slow.send {
+		buffered++
+	}
+	if buffered != sendBufferSize {
+		t.Errorf("slow client had %d buffered messages, want %d", buffered, sendBufferSize)
+	}
+	if slow.closeCode != websocket.ClosePolicyViolation || slow.closeText != "slow consumer" {
+		t.Errorf("close frame = %d %q, want policy violation", slow.closeCode, slow.closeText)
+	}
+	if hub.IsUserOnline("slow") || !hub.IsUserOnline("fast") {
+		t.Error("only the slow client should have been removed")
+	}
+}
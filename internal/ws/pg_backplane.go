@@ -0,0 +1,105 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	pgBackplaneChannel = "ws_hub"
+
+	// NOTIFY payloads are limited to 8000 bytes
+	maxNotifyPayload = 7900
+)
+
+// PostgresBackplane fans hub messages out through Postgres LISTEN/NOTIFY.
+// Messages too large for a NOTIFY are sent as a reference to their seq and
+// loaded from the shared event log by the receiving instances.
+type PostgresBackplane struct {
+	dsn      string   // Dedicated LISTEN connection (must not go through a transaction-mode pooler)
+	db       *gorm.DB // Used for NOTIFY
+	eventLog EventLog
+}
+
+// pgEnvelope adds the by-reference form to Envelope
+type pgEnvelope struct {
+	*Envelope
+	RefSeq int64 `json:"ref_seq,omitempty"`
+}
+
+func NewPostgresBackplane(dsn string, db *gorm.DB, eventLog EventLog) *PostgresBackplane {
+	return &PostgresBackplane{
+		dsn:      dsn,
+		db:       db,
+		eventLog: eventLog,
+	}
+}
+
+func (b *PostgresBackplane) Publish(env *Envelope) error {
+	data, err := json.Marshal(pgEnvelope{Envelope: env})
+	if err != nil {
+		return err
+	}
+
+	if len(data) > maxNotifyPayload {
+		if env.Message == nil || env.Message.Seq == 0 || b.eventLog == nil {
+			return errors.New("backplane message too large and not in the event log")
+		}
+		data, err = json.Marshal(pgEnvelope{
+			Envelope: &Envelope{Origin: env.Origin},
+			RefSeq:   env.Message.Seq,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.db.Exec("SELECT pg_notify(?, ?)", pgBackplaneChannel, string(data)).Error
+}
+
+func (b *PostgresBackplane) Listen(handle func(*Envelope)) error {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgBackplaneChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var env pgEnvelope
+		if err := json.Unmarshal([]byte(notification.Payload), &env); err != nil || env.Envelope == nil {
+			continue
+		}
+		if env.RefSeq > 0 {
+			env.Message = b.loadMessage(env.RefSeq)
+			if env.Message == nil {
+				continue
+			}
+		}
+		handle(env.Envelope)
+	}
+}
+
+// loadMessage resolves a by-reference envelope from the event log
+func (b *PostgresBackplane) loadMessage(seq int64) *Message {
+	events, err := b.eventLog.FindAfter(seq-1, 1)
+	if err != nil || len(events) == 0 || events[0].Seq != seq {
+		return nil
+	}
+	msg := fromHubEvent(events[0])
+	msg.Payload = payloadWithSeq(msg)
+	return msg
+}
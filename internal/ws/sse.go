@@ -0,0 +1,70 @@
+package ws
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Comment lines keep proxies from closing an idle stream and detect gone clients
+const sseHeartbeatPeriod = 15 * time.Second
+
+// NewStreamClient creates a hub client without a WebSocket, for Server-Sent Events
+func NewStreamClient(hub *Hub, userID, tokenVersion string, privileges []string) *Client {
+	return NewClient(hub, nil, userID, tokenVersion, privileges)
+}
+
+// ServeSSE registers the client and streams its events as Server-Sent Events until
+// the peer disconnects or the hub drops the client. Each event's data is the same JSON
+// the WebSocket receives, with its seq as the SSE id so EventSource resumes via
+// Last-Event-ID. Events are unnamed, so they all arrive on EventSource.onmessage.
+func (c *Client) ServeSSE(w *bufio.Writer) {
+	c.hub.register(c)
+	defer func() { c.hub.Unregister <- c }()
+
+	// Reconnect hint for EventSource, also flushes the headers right away
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := w.Flush(); err != nil {
+		return
+	}
+
+	if c.resumeFrom != nil {
+		c.hub.Replay(c, *c.resumeFrom)
+	}
+
+	ticker := time.NewTicker(sseHeartbeatPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			if !ok {
+				// Dropped by the hub (slow consumer or stale session)
+				fmt.Fprintf(w, "event: close\ndata: %q\n\n", c.closeText)
+				w.Flush()
+				return
+			}
+			writeSSEEvent(w, message)
+
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeSSEEvent writes one message, using its seq (if any) as the event id.
+// json.Marshal never emits raw newlines, so the payload fits on one data line.
+func writeSSEEvent(w *bufio.Writer, message []byte) {
+	var meta struct {
+		Seq int64 `json:"seq"`
+	}
+	if json.Unmarshal(message, &meta) == nil && meta.Seq > 0 {
+		fmt.Fprintf(w, "id: %d\n", meta.Seq)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", message)
+}
@@ -0,0 +1,58 @@
+package ws
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Topics clients can subscribe to
+const (
+	TopicProducts   = "products"   // Every product / stock change
+	TopicFinance    = "finance"    // Financial stats changes
+	TopicPresence   = "presence"   // User online status
+	TopicShifts     = "shifts"     // Shift notifications (still only sent to the assigned users)
+	TopicTransfers  = "transfers"  // Stock transfers between locations
+	TopicStocktakes = "stocktakes" // Stocktake sessions and counting progress
+
+	productTopicPrefix  = "product:"
+	locationTopicPrefix = "location:"
+)
+
+// ProductTopic returns the topic for changes of a single product ("product:<id>")
+func ProductTopic(productID uuid.UUID) string {
+	return productTopicPrefix + productID.String()
+}
+
+// LocationTopic returns the topic for stock changes at a single location ("location:<id>")
+func LocationTopic(locationID uuid.UUID) string {
+	return locationTopicPrefix + locationID.String()
+}
+
+// IsValidTopic checks a topic name sent by a client
+func IsValidTopic(topic string) bool {
+	switch topic {
+	case TopicProducts, TopicFinance, TopicPresence, TopicShifts, TopicTransfers, TopicStocktakes:
+		return true
+	}
+	for _, prefix := range []string{productTopicPrefix, locationTopicPrefix} {
+		if strings.HasPrefix(topic, prefix) {
+			_, err := uuid.Parse(strings.TrimPrefix(topic, prefix))
+			return err == nil
+		}
+	}
+	return false
+}
+
+// ParseTopics splits a comma separated topic list (e.g. ?topics=products,finance),
+// dropping unknown topics
+func ParseTopics(raw string) []string {
+	var topics []string
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.TrimSpace(t)
+		if IsValidTopic(t) {
+			topics = append(topics, t)
+		}
+	}
+	return topics
+}
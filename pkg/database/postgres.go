@@ -11,7 +11,8 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-func ConnectDB() *gorm.DB {
+// DSN returns DATABASE_URL or builds a DSN from the DB_* variables
+func DSN() string {
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
 		dsn = fmt.Sprintf(
@@ -23,6 +24,11 @@ func ConnectDB() *gorm.DB {
 			os.Getenv("DB_PORT"),
 		)
 	}
+	return dsn
+}
+
+func ConnectDB() *gorm.DB {
+	dsn := DSN()
 
 	newLogger := logger.New(
 		log.New(os.Stdout, "\r\n", log.LstdFlags),
@@ -0,0 +1,229 @@
+// Package decimal provides the fixed-precision quantity type used for stock.
+//
+// A Decimal is an int64 count of thousandths, so adding and comparing quantities
+// is plain integer arithmetic and never rounds. It is stored as NUMERIC(18,3) and
+// written to JSON as an exact number literal (1.25, not 1.2499999).
+package decimal
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Places is the number of decimal places kept
+const Places = 3
+
+const unit = 1000 // 10^Places
+
+// maxWhole bounds the whole part: NUMERIC(18,3) holds 15 digits before the point
+const maxWhole = 1_000_000_000_000_000 // 10^15
+
+// Max is the largest quantity the column can store, 999999999999999.999
+const Max Decimal = maxWhole*unit - 1
+
+var (
+	ErrInvalid  = errors.New("invalid decimal number (at most 3 decimal places, below 10^15)")
+	ErrOverflow = errors.New("decimal result out of range")
+)
+
+// Decimal is a quantity with 3 decimal places
+type Decimal int64
+
+// Zero is the zero quantity
+const Zero Decimal = 0
+
+// FromInt converts a whole number
+func FromInt(n int) Decimal {
+	return Decimal(int64(n) * unit)
+}
+
+// Parse parses "12", "-1.5", "0.125". More than 3 decimal places is an error,
+// never a silent rounding.
+func Parse(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalid
+	}
+
+	neg := false
+	if s[0] == '-' || s[0] == '+' {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, ErrInvalid
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > Places {
+		return 0, ErrInvalid
+	}
+	for _, part := range []string{whole, frac} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return 0, ErrInvalid
+			}
+		}
+	}
+
+	// Bound the whole part before multiplying, so the result can neither wrap
+	// around nor exceed what the column stores
+	var w int64
+	if whole != "" {
+		var err error
+		if w, err = strconv.ParseInt(whole, 10, 64); err != nil || w >= maxWhole {
+			return 0, ErrInvalid
+		}
+	}
+	var f int64
+	if frac != "" {
+		f, _ = strconv.ParseInt(frac+strings.Repeat("0", Places-len(frac)), 10, 64)
+	}
+
+	d := Decimal(w*unit + f)
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// String formats without trailing zeros: 2, 1.25, -0.5
+func (d Decimal) String() string {
+	sign := ""
+	v := int64(d)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	whole, frac := v/unit, v%unit
+	if frac == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	fracStr := strings.TrimRight(fmt.Sprintf("%03d", frac), "0")
+	return fmt.Sprintf("%s%d.%s", sign, whole, fracStr)
+}
+
+// IsWhole reports whether the quantity has no fractional part
+func (d Decimal) IsWhole() bool {
+	return int64(d)%unit == 0
+}
+
+// Int returns the whole part (truncated towards zero)
+func (d Decimal) Int() int {
+	return int(int64(d) / unit)
+}
+
+// MulInt multiplies by a whole number (unit conversion factors). Results beyond
+// Max are an error.
+func (d Decimal) MulInt(n int) (Decimal, error) {
+	if n != 0 && abs(int64(d)) > int64(Max)/abs(int64(n)) {
+		return 0, ErrOverflow
+	}
+	return d * Decimal(n), nil
+}
+
+// MulAmount multiplies a money amount (whole currency units) by the quantity,
+// rounding half away from zero to whole currency units. Results that don't fit
+// an int64 are an error.
+func (d Decimal) MulAmount(amount int64) (int64, error) {
+	// Leave room for the rounding term added below
+	if d != 0 && abs(amount) > (1<<63-1-unit)/abs(int64(d)) {
+		return 0, ErrOverflow
+	}
+	p := amount * int64(d)
+	if p < 0 {
+		return -((-p + unit/2) / unit), nil
+	}
+	return (p + unit/2) / unit, nil
+}
+
+// abs of a value that is never math.MinInt64 (quantities are bounded by Max)
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// MarshalJSON writes an exact number literal
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON accepts a number (1.25) or a string ("1.25")
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	// Exponent notation is a float, not a quantity someone typed
+	if strings.ContainsAny(s, "eE") {
+		return ErrInvalid
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Value stores the decimal as a NUMERIC literal
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan reads NUMERIC (as text) or integer columns
+func (d *Decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = 0
+		return nil
+	case int64:
+		if v >= maxWhole || v <= -maxWhole {
+			return ErrInvalid
+		}
+		*d = Decimal(v * unit)
+		return nil
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	case float64:
+		// Aggregates may come back as floats, they never carry more than 3 places
+		return d.scanString(strconv.FormatFloat(v, 'f', Places, 64))
+	default:
+		return fmt.Errorf("decimal: cannot scan %T", src)
+	}
+}
+
+func (d *Decimal) scanString(s string) error {
+	// NUMERIC results of division or AVG can carry more places than a quantity
+	if whole, frac, ok := strings.Cut(s, "."); ok && len(frac) > Places {
+		s = whole + "." + frac[:Places]
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// GormDataType is the column type used by AutoMigrate
+func (Decimal) GormDataType() string {
+	return "numeric(18,3)"
+}
+
+// GormDBDataType is the column type used by AutoMigrate
+func (Decimal) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return "numeric(18,3)"
+}
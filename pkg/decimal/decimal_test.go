@@ -0,0 +1,156 @@
+package decimal
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Decimal
+		wantErr bool
+	}{
+		{in: "12", want: 12000},
+		{in: "-1.5", want: -1500},
+		{in: "+0.125", want: 125},
+		{in: ".5", want: 500},
+		{in: "2.", want: 2000},
+		{in: " 3.10 ", want: 3100},
+		{in: "1.2500", want: 1250}, // Trailing zeros don't count as places
+		{in: "999999999999999.999", want: Max},
+		{in: "-999999999999999.999", want: -Max},
+		{in: "1000000000000000", wantErr: true}, // Doesn't fit NUMERIC(18,3)
+		{in: "9223372036854775.999", wantErr: true},
+		{in: "-9223372036854775.999", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+		{in: "0.1234", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "1,5", wantErr: true},
+		{in: "--1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Decimal
+		want string
+	}{
+		{0, "0"},
+		{2000, "2"},
+		{1250, "1.25"},
+		{-500, "-0.5"},
+		{5, "0.005"},
+		{-1001, "-1.001"},
+		{Max, "999999999999999.999"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Decimal(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		in      interface{}
+		want    Decimal
+		wantErr bool
+	}{
+		{in: nil, want: 0},
+		{in: int64(7), want: 7000},
+		{in: int64(-7), want: -7000},
+		{in: []byte("1.500"), want: 1500},
+		{in: "2.25", want: 2250},
+		{in: "0.33333333", want: 333}, // AVG results are truncated to 3 places
+		{in: float64(1.25), want: 1250},
+		{in: int64(1000000000000000), wantErr: true},
+		{in: "1000000000000000.000", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Decimal
+		err := got.Scan(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Scan(%#v) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Scan(%#v) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestMulAmount(t *testing.T) {
+	tests := []struct {
+		qty     Decimal
+		amount  int64
+		want    int64
+		wantErr bool
+	}{
+		{qty: 2000, amount: 15000, want: 30000},
+		{qty: 1500, amount: 999, want: 1499}, // 1498.5 rounds half away from zero
+		{qty: 1, amount: 499, want: 0},       // 0.499
+		{qty: 1, amount: 500, want: 1},       // 0.5
+		{qty: -1500, amount: 999, want: -1499},
+		{qty: 0, amount: 1 << 62, want: 0},
+		{qty: Max, amount: 9, want: 9000000000000000},
+		{qty: Max, amount: 1 << 40, wantErr: true},
+		{qty: -Max, amount: 1 << 40, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.qty.MulAmount(tt.amount)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Decimal(%d).MulAmount(%d) = %d, want error", int64(tt.qty), tt.amount, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Decimal(%d).MulAmount(%d) = %d, %v, want %d", int64(tt.qty), tt.amount, got, err, tt.want)
+		}
+	}
+}
+
+func TestMulInt(t *testing.T) {
+	tests := []struct {
+		qty     Decimal
+		n       int
+		want    Decimal
+		wantErr bool
+	}{
+		{qty: 1500, n: 12, want: 18000},
+		{qty: -1500, n: 12, want: -18000},
+		{qty: Max, n: 1, want: Max},
+		{qty: Max, n: 0, want: 0},
+		{qty: Max, n: 2, wantErr: true},
+		{qty: 1000000000000000, n: 1000, wantErr: true}, // Would still fit an int64
+	}
+	for _, tt := range tests {
+		got, err := tt.qty.MulInt(tt.n)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Decimal(%d).MulInt(%d) = %d, want error", int64(tt.qty), tt.n, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Decimal(%d).MulInt(%d) = %d, %v, want %d", int64(tt.qty), tt.n, got, err, tt.want)
+		}
+	}
+}